@@ -26,6 +26,10 @@ type Device struct {
 
 // DeviceManager manages device connections with connection pooling
 //
+// For drivers that keep a connection per device, pkg/devices of the SDK runs
+// that lifecycle (connection, objects, events, reconnection, device states)
+// from a DeviceConnector implementation; see docs/devices.md.
+//
 // This pattern avoids creating new connections for every request,
 // improving performance and reducing load on devices.
 type DeviceManager struct {
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	counter := NewCounterVec("test_requests_total", "Requests.", "method", "code")
+	counter.Inc("GET", "2xx")
+	counter.Add(2, "GET", "5xx")
+	gauge := NewGaugeVec("test_sessions_active", "Open sessions.", "kind")
+	gauge.Inc("talkback")
+	gauge.Inc("talkback")
+	gauge.Dec("talkback")
+	hist := NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "action")
+	hist.Observe(0.05, "open")
+	hist.Observe(0.5, "open")
+	hist.Observe(3, "open")
+	NewGaugeFunc("test_queue_depth", "Queue depth.", func() float64 { return 4 })
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteText(&buf))
+	out := buf.String()
+
+	assert.Contains(t, out, "# HELP test_requests_total Requests.\n# TYPE test_requests_total counter\n")
+	assert.Contains(t, out, `test_requests_total{method="GET",code="2xx"} 1`+"\n")
+	assert.Contains(t, out, `test_requests_total{method="GET",code="5xx"} 2`+"\n")
+	assert.Contains(t, out, "# TYPE test_sessions_active gauge\n"+`test_sessions_active{kind="talkback"} 1`+"\n")
+	assert.Contains(t, out, "# TYPE test_duration_seconds histogram\n"+
+		`test_duration_seconds_bucket{action="open",le="0.1"} 1`+"\n"+
+		`test_duration_seconds_bucket{action="open",le="1"} 2`+"\n"+
+		`test_duration_seconds_bucket{action="open",le="+Inf"} 3`+"\n"+
+		`test_duration_seconds_sum{action="open"} 3.55`+"\n"+
+		`test_duration_seconds_count{action="open"} 3`+"\n")
+	assert.Contains(t, out, "test_queue_depth 4\n")
+	assert.Less(t, bytes.Index(buf.Bytes(), []byte("test_duration_seconds")), bytes.Index(buf.Bytes(), []byte("test_queue_depth")), "families are sorted by name")
+}
+
+func TestWriteText_escapesLabelValues(t *testing.T) {
+	counter := NewCounterVec("test_escape_total", "Escaping.", "key")
+	counter.Inc("a\"b\\c\nd")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteText(&buf))
+	assert.Contains(t, buf.String(), `test_escape_total{key="a\"b\\c\nd"} 1`)
+}
+
+func TestRegister_rejectsDuplicates(t *testing.T) {
+	NewCounterVec("test_duplicate_total", "First.")
+	assert.Panics(t, func() { NewCounterVec("test_duplicate_total", "Second.") })
+}
+
+func TestLabelValueCountMustMatch(t *testing.T) {
+	counter := NewCounterVec("test_labels_total", "Labels.", "method")
+	assert.Panics(t, func() { counter.Inc() })
+}
+
+func TestHandler(t *testing.T) {
+	NewCounterVec("test_handler_total", "Handler.").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "test_handler_total 1\n")
+}
@@ -0,0 +1,9 @@
+package metrics
+
+// Metrics updated by more than one package.
+var (
+	// WebsocketReconnects counts the reconnection attempts of the DriverHub
+	// websockets, labelled "objects" or "config".
+	WebsocketReconnects = NewCounterVec("driver_websocket_reconnects_total",
+		"Reconnection attempts of the DriverHub websockets, by socket.", "socket")
+)
@@ -0,0 +1,91 @@
+// Package recovery turns panics raised by driver code at the SDK dispatch
+// boundaries (action handlers, config handlers, event bus callbacks) into
+// errors, so one misbehaving device model cannot crash the whole driver.
+package recovery
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+)
+
+// Dispatch boundaries, used as the Boundary of a PanicError and as the key of
+// the recovered-panic counters.
+const (
+	BoundaryAction   = "action"
+	BoundaryConfig   = "config"
+	BoundaryEventBus = "eventbus"
+	BoundarySetup    = "setup"
+	BoundaryClose    = "close"
+	BoundaryDevice   = "device"
+)
+
+// PanicError is the error a recovered panic is turned into.
+type PanicError struct {
+	Boundary string
+	Value    any
+	Stack    []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s handler: %v", e.Boundary, e.Value)
+}
+
+var (
+	mu     sync.Mutex
+	counts = map[string]uint64{}
+)
+
+func init() {
+	metrics.NewCounterFunc("driver_recovered_panics_total",
+		"Panics recovered at the SDK dispatch boundaries, by boundary.", "boundary", func() map[string]float64 {
+			values := map[string]float64{}
+			for boundary, n := range Counts() {
+				values[boundary] = float64(n)
+			}
+			return values
+		})
+}
+
+// Error builds the PanicError for r, the value returned by recover(), logs it
+// with its stack trace and counts it. It must be called from the deferred
+// function that recovered, so the stack still shows where the panic happened:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			err = recovery.Error(recovery.BoundaryAction, r)
+//		}
+//	}()
+func Error(boundary string, r any) *PanicError {
+	err := &PanicError{Boundary: boundary, Value: r, Stack: debug.Stack()}
+	mu.Lock()
+	counts[boundary]++
+	mu.Unlock()
+	logger.Logger().Errorw("recovered panic", "boundary", boundary, "panic", fmt.Sprint(r), "stack", string(err.Stack))
+	return err
+}
+
+// Guard runs fn and recovers a panic it raises, returning it as an error.
+func Guard(boundary string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Error(boundary, r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// Counts returns how many panics were recovered so far, per boundary.
+func Counts() map[string]uint64 {
+	mu.Lock()
+	defer mu.Unlock()
+	snapshot := make(map[string]uint64, len(counts))
+	for boundary, n := range counts {
+		snapshot[boundary] = n
+	}
+	return snapshot
+}
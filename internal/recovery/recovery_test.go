@@ -0,0 +1,28 @@
+package recovery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard_turnsPanicIntoError(t *testing.T) {
+	before := Counts()["test"]
+
+	err := Guard("test", func() { panic("boom") })
+
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "test", panicErr.Boundary)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "recovery_test.go")
+	assert.Equal(t, before+1, Counts()["test"])
+}
+
+func TestGuard_passesThrough(t *testing.T) {
+	ran := false
+	assert.NoError(t, Guard("test", func() { ran = true }))
+	assert.True(t, ran)
+}
@@ -79,7 +79,7 @@ func (d *NetsocsDriverClient) SetDeviceState(deviceId int, state DeviceState) (D
 	}
 
 	if resp.IsError() {
-		return DeviceStateResponse{}, errors.New(resp.String())
+		return DeviceStateResponse{}, httpx.NewHubError(resp)
 	}
 
 	return DeviceStateResponse{}, nil
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+)
+
+// EventValidation decides what DispatchEvent does with an event whose type
+// was not registered with AddEventTypes, or whose properties do not match
+// the schema of its type.
+type EventValidation string
+
+const (
+	// EventValidationWarn logs the first invalid event of each type and
+	// sends it anyway. It is the default, as types registered by an earlier
+	// run of the driver are unknown to the client.
+	EventValidationWarn EventValidation = "warn"
+	// EventValidationStrict rejects invalid events with an error wrapping
+	// objects.ErrUnknownEventType or objects.ErrInvalidEvent.
+	EventValidationStrict EventValidation = "strict"
+	// EventValidationOff sends every event unchecked.
+	EventValidationOff EventValidation = "off"
+)
+
+// SetEventValidation sets how dispatched events are checked against the
+// registered event types.
+func (c *NetsocsDriverClient) SetEventValidation(mode EventValidation) {
+	c.eventValidation.Store(mode)
+}
+
+// SetEventSchema declares the properties of an event type. Events of that
+// type are then checked against it, as SetEventValidation decides:
+//
+//	client.SetEventSchema("door.event.forced", objects.EventSchema{
+//		Properties: map[string]objects.PropertySchema{
+//			"zone":  {Type: objects.PropertyInt, Required: true},
+//			"level": {Enum: []string{"low", "high"}},
+//		},
+//	})
+func (c *NetsocsDriverClient) SetEventSchema(eventType string, schema objects.EventSchema) error {
+	registry, err := c.eventRegistry()
+	if err != nil {
+		return err
+	}
+	registry.SetSchema(eventType, schema)
+	return nil
+}
+
+// EventTypes returns the event types registered with AddEventTypes, by
+// EventType.
+func (c *NetsocsDriverClient) EventTypes() []objects.EventType {
+	registry, err := c.eventRegistry()
+	if err != nil {
+		return nil
+	}
+	return registry.Types()
+}
+
+func (c *NetsocsDriverClient) eventRegistry() (*objects.EventRegistry, error) {
+	controller, ok := c.objectsRunner.GetController().(objects.EventRegistryController)
+	if !ok {
+		return nil, errors.New("the objects controller does not keep an event registry")
+	}
+	return controller.EventRegistry(), nil
+}
+
+// validateEvent checks an event before it is dispatched. It returns an error
+// only in EventValidationStrict mode.
+func (c *NetsocsDriverClient) validateEvent(eventType string, properties map[string]string) error {
+	mode, _ := c.eventValidation.Load().(EventValidation)
+	if mode == EventValidationOff {
+		return nil
+	}
+	registry, err := c.eventRegistry()
+	if err != nil {
+		return nil
+	}
+	err = registry.Validate(eventType, properties)
+	if err == nil || mode == EventValidationStrict {
+		return err
+	}
+	if _, warned := c.warnedEventTypes.LoadOrStore(eventType, true); !warned {
+		logger.Logger().Warnw("dispatching an invalid event", "event_type", eventType, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"log/slog"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SetLogger makes l the logger of the whole SDK: the client, the action
+// runner, the config websocket and every other package log through it. A nil
+// l restores the default zap production logger.
+//
+// The logger is process-wide, like the action dispatch: with several clients
+// in one process, the last call wins.
+func (n *NetsocsDriverClient) SetLogger(l *zap.Logger) {
+	logger.Set(l)
+}
+
+// SetLogHandler is SetLogger for drivers that log with log/slog: the SDK
+// entries, with their fields, are written to h.
+//
+//	client.SetLogHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+func (n *NetsocsDriverClient) SetLogHandler(h slog.Handler) {
+	logger.SetHandler(h)
+}
+
+// SetLogLevel sets the minimum level the SDK logs: "debug", "info", "warn"
+// or "error". New applies the log_level of driver.netsocs.json through it.
+// The level applies on top of the logger's own, so a handler that drops debug
+// entries keeps dropping them.
+func (n *NetsocsDriverClient) SetLogLevel(level string) error {
+	return logger.SetLevel(level)
+}
@@ -1,16 +1,23 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/tools"
 )
 
@@ -19,7 +26,7 @@ type NetsocsDriverClient struct {
 	driverHubHost                   string
 	isSSL                           bool
 	DriverName                      string
-	objectsRunner                   objects.ObjectRunner
+	objectsRunner                   objects.ManagedObjectRunner
 	siteID                          string
 	videoEngineID                   string
 	token                           string
@@ -29,6 +36,26 @@ type NetsocsDriverClient struct {
 	siteHost                        string
 	mediaMTXHost                    string
 	videoEngineAdditionalProperties config.VideoEngineAdditionalProperties
+	configStateListeners            []func(httpx.ConnectionStateChange)
+	configSession                   atomic.Pointer[config.Session]
+
+	// ctx scopes the websockets; cancel is called by Shutdown.
+	ctx        context.Context
+	cancel     context.CancelFunc
+	listenDone chan struct{}
+	configRuns sync.WaitGroup
+
+	// offline buffering, see EnableOutbox
+	outbox     atomic.Pointer[outbox.Outbox]
+	outboxDone chan struct{}
+
+	// metrics endpoint, see ServeMetrics
+	metricsServer atomic.Pointer[http.Server]
+	metricsAddr   atomic.Value
+
+	// checks of dispatched events, see SetEventValidation
+	eventValidation  atomic.Value // EventValidation
+	warnedEventTypes sync.Map     // event types already logged as invalid
 }
 
 func (n *NetsocsDriverClient) SetVideoEngineID(videoEngineID string) {
@@ -43,8 +70,11 @@ func (n *NetsocsDriverClient) SetToken(token string) {
 	n.token = token
 }
 
+// SetDriverID sets the driver ID, which is also added to every log entry as
+// driver_id.
 func (n *NetsocsDriverClient) SetDriverID(driverID string) {
 	n.driverID = driverID
+	logger.SetField("driver_id", driverID)
 }
 
 func (n *NetsocsDriverClient) SetSiteHost(siteHost string) {
@@ -76,8 +106,11 @@ func (n *NetsocsDriverClient) GetSiteHost() string {
 
 func NewNetsocsDriverClient(driverKey string, driverHubHost string, isSSL bool) *NetsocsDriverClient {
 	controller := objects.NewObjectController(driverHubHost, driverKey)
+	ctx, cancel := context.WithCancel(context.Background())
+	listenDone := make(chan struct{})
 	go func() {
-		err := controller.ListenActionRequests()
+		defer close(listenDone)
+		err := objects.WithContext(controller).ListenActionRequestsContext(ctx)
 		if err != nil {
 			panic(err)
 		}
@@ -88,6 +121,9 @@ func NewNetsocsDriverClient(driverKey string, driverHubHost string, isSSL bool)
 		driverHubHost: driverHubHost,
 		isSSL:         isSSL,
 		objectsRunner: runner,
+		ctx:           ctx,
+		cancel:        cancel,
+		listenDone:    listenDone,
 	}
 
 	// If the events.json file exists, add the handler for the actionListenEvents
@@ -112,6 +148,20 @@ func New() (*NetsocsDriverClient, error) {
 		return nil, err
 	}
 
+	tlsOptions, err := httpx.TLSOptionsFromEnv(httpx.TLSOptions{
+		CAFile:     fileData.TLSCAFile,
+		CertFile:   fileData.TLSCertFile,
+		KeyFile:    fileData.TLSKeyFile,
+		PinnedSPKI: fileData.TLSPinnedSPKI,
+		Strict:     fileData.TLSStrict,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := httpx.ConfigureTLS(tlsOptions); err != nil {
+		return nil, err
+	}
+
 	client := NewNetsocsDriverClient(fileData.DriverKey, fileData.DriverHubHost, false)
 	client.DriverName = fileData.Name
 
@@ -119,6 +169,11 @@ func New() (*NetsocsDriverClient, error) {
 	client.SetToken(fileData.Token)
 	client.SetDriverID(fileData.DriverID)
 	client.SetSiteHost(fileData.SiteHost)
+	if fileData.LogLevel != "" {
+		if err := client.SetLogLevel(fileData.LogLevel); err != nil {
+			logger.Logger().Warnw("ignoring log_level of driver.netsocs.json", "error", err)
+		}
+	}
 	return client, nil
 }
 
@@ -162,12 +217,93 @@ func (d *NetsocsDriverClient) UploadSnapshot(r io.Reader, filename string, custo
 	return tools.UploadSnapshot(d.driverHubHost, d.driverKey, r, filename, customName)
 }
 
+// ListenConfig serves the DriverHub config requests. It blocks until the
+// process is interrupted or Shutdown is called; while the hub is unreachable it
+// keeps retrying in the background instead of failing.
 func (d *NetsocsDriverClient) ListenConfig() error {
+	d.configRuns.Add(1)
+	defer d.configRuns.Done()
+	session := config.NewSession(config.SessionParams{
+		Host:                d.driverHubHost,
+		DriverKey:           d.driverKey,
+		SiteID:              d.siteID,
+		Token:               d.token,
+		DriverID:            d.driverID,
+		DriverVersion:       d.driverVersion,
+		DriverDocumentation: d.driverDocumentation,
+		SetVideoEngineID: func(videoEngineID string, videoEngineAdditionalProperties config.VideoEngineAdditionalProperties) {
+			d.SetVideoEngineID(videoEngineID)
+			d.SetVideoEngineAdditionalProperties(videoEngineAdditionalProperties)
+		},
+		OnConnectionStateChange: func(change httpx.ConnectionStateChange) {
+			for _, fn := range d.configStateListeners {
+				fn(change)
+			}
+		},
+	})
+	d.configSession.Store(session)
+	return session.Run(d.ctx)
+}
+
+// Shutdown stops the client and returns once everything has stopped:
+//
+//  1. action requests are no longer accepted and the executions in flight are
+//     drained; if ctx expires first they are cancelled (custom action handlers
+//     see CustomActionContext.Context done);
+//  2. active talkback and microphone sessions are closed;
+//  3. the config handlers still running finish and send their replies;
+//  4. pending batched state changes are sent (see EnableStateBatching);
+//  5. the objects and config websockets are closed with a close frame, and
+//     the outbox, if enabled, is closed;
+//  6. the metrics endpoint, if served, is stopped.
+//
+// ListenConfig returns nil once Shutdown completes. The client cannot be
+// reused afterwards.
+func (d *NetsocsDriverClient) Shutdown(ctx context.Context) error {
+	errs := []error{d.objectsRunner.Shutdown(ctx)}
+	if session := d.configSession.Load(); session != nil {
+		// Only this client's session: the pool may serve other clients.
+		errs = append(errs, session.StopWorkers(ctx))
+	}
+	if batcher, ok := d.objectsRunner.GetController().(objects.StateBatchController); ok {
+		errs = append(errs, batcher.FlushStateBatch(ctx))
+	}
+	d.cancel()
 
-	return config.ListenConfig(d.driverHubHost, d.driverKey, d.siteID, d.token, d.driverID, func(videoEngineID string, videoEngineAdditionalProperties config.VideoEngineAdditionalProperties) {
-		d.SetVideoEngineID(videoEngineID)
-		d.SetVideoEngineAdditionalProperties(videoEngineAdditionalProperties)
-	}, d.driverVersion, d.driverDocumentation)
+	stopped := make(chan struct{})
+	var outboxErr error
+	go func() {
+		<-d.listenDone
+		d.configRuns.Wait()
+		outboxErr = d.closeOutbox()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		errs = append(errs, outboxErr)
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+	errs = append(errs, d.closeMetrics(ctx))
+	return errors.Join(errs...)
+}
+
+// OnConfigConnectionStateChange registers fn to be called every time the config
+// websocket connects or drops. Register listeners before calling ListenConfig.
+// fn must not block.
+func (d *NetsocsDriverClient) OnConfigConnectionStateChange(fn func(httpx.ConnectionStateChange)) {
+	if fn != nil {
+		d.configStateListeners = append(d.configStateListeners, fn)
+	}
+}
+
+// ConfigConnectionState returns the current state of the config websocket.
+func (d *NetsocsDriverClient) ConfigConnectionState() httpx.ConnectionState {
+	session := d.configSession.Load()
+	if session == nil {
+		return httpx.ConnectionStateDisconnected
+	}
+	return session.State()
 }
 
 func (d *NetsocsDriverClient) SetDriverVersion(version string) {
@@ -246,15 +382,150 @@ func (c *NetsocsDriverClient) RegisterObject(obj objects.RegistrableObject) erro
 	return c.objectsRunner.RegisterObject(obj)
 }
 
+// UnregisterObject removes an object, e.g. a channel of a device that lost
+// it: it stops receiving actions and is deleted from the DriverHub.
+func (c *NetsocsDriverClient) UnregisterObject(objectID string) error {
+	return c.objectsRunner.UnregisterObject(objectID)
+}
+
+// UpdateObject pushes the name, type, tags, states and actions of an object
+// registered earlier, which RegisterObject leaves untouched.
+func (c *NetsocsDriverClient) UpdateObject(obj objects.RegistrableObject) error {
+	return c.objectsRunner.UpdateObject(obj)
+}
+
+// Reconcile makes the DriverHub objects of a device match desired, creating,
+// updating, enabling and disabling (or deleting, see objects.ReconcileOptions)
+// objects as needed. Call it after connecting to a device, with every object
+// it currently exposes.
+func (c *NetsocsDriverClient) Reconcile(deviceID string, desired []objects.RegistrableObject, opts ...objects.ReconcileOptions) (objects.ReconcileResult, error) {
+	return c.objectsRunner.Reconcile(deviceID, desired, opts...)
+}
+
+// DeviceObjects returns the objects registered for a device, by ID.
+func (c *NetsocsDriverClient) DeviceObjects(deviceID string) []objects.RegistrableObject {
+	return c.objectsRunner.DeviceObjects(deviceID)
+}
+
+// GetController returns the controller the registered objects use to reach
+// the DriverHub.
+func (c *NetsocsDriverClient) GetController() objects.ObjectController {
+	return c.objectsRunner.GetController()
+}
+
+// SetObjectExecutionPolicy limits how many action executions run at the same
+// time on one object, e.g. objects.ExecutionPolicy{Mode: objects.ExecutionSerial}
+// for a PTZ camera that cannot take overlapping commands.
+func (c *NetsocsDriverClient) SetObjectExecutionPolicy(objectID string, policy objects.ExecutionPolicy) error {
+	return c.objectsRunner.SetObjectExecutionPolicy(objectID, policy)
+}
+
+// SetDomainExecutionPolicy limits how many action executions run at the same
+// time across all objects of a domain. An object's own policy takes precedence.
+func (c *NetsocsDriverClient) SetDomainExecutionPolicy(domain string, policy objects.ExecutionPolicy) error {
+	return c.objectsRunner.SetDomainExecutionPolicy(domain, policy)
+}
+
+// SetActionTimeout sets a default deadline for every execution of action.
+// A "timeout" (seconds) in the action payload takes precedence. When the
+// deadline passes, the action context is cancelled and the hub receives an
+// error result with error_code "timeout".
+func (c *NetsocsDriverClient) SetActionTimeout(action string, timeout time.Duration) {
+	c.objectsRunner.SetActionTimeout(action, timeout)
+}
+
+// EnableStructuredActionResults makes action results carry their status
+// ("queued", "running", "succeeded", "failed", "cancelled"), progress, error
+// code and typed data. Leave it off for DriverHubs that only read the
+// flattened {"result": {...}} strings.
+func (c *NetsocsDriverClient) EnableStructuredActionResults() error {
+	controller, ok := c.objectsRunner.GetController().(interface{ EnableStructuredActionResults() })
+	if !ok {
+		return errors.New("the objects controller does not support structured action results")
+	}
+	controller.EnableStructuredActionResults()
+	return nil
+}
+
+// EnableStateBatching makes SetState and UpdateStateAttributes write behind:
+// changes are collected over a short window, repeated changes to the same
+// object are coalesced, and the lot is sent in one /objects/states-batch
+// request. Callers still block until their change is sent and receive the
+// error the hub reported for their object. Shutdown flushes the last batch.
+func (c *NetsocsDriverClient) EnableStateBatching(opts objects.StateBatchOptions) error {
+	batcher, ok := c.objectsRunner.GetController().(objects.StateBatchController)
+	if !ok {
+		return errors.New("the objects controller does not support state batching")
+	}
+	return batcher.EnableStateBatching(opts)
+}
+
+// CachedState returns the last state and attributes the SDK pushed to, or read
+// from, the hub for an object, without a request. ok is false when nothing is
+// known about it yet.
+func (c *NetsocsDriverClient) CachedState(objectID string) (state objects.State, ok bool) {
+	return objects.CachedState(c.objectsRunner.GetController(), objectID)
+}
+
+// EnableStateSuppression skips SetState and UpdateStateAttributes calls that
+// would not change the cached state, so polling drivers do not flood the
+// hub's state history. An identical update is still sent once heartbeat has
+// passed since the last one; a zero heartbeat never resends it.
+func (c *NetsocsDriverClient) EnableStateSuppression(heartbeat time.Duration) error {
+	cache, ok := c.objectsRunner.GetController().(objects.StateCacheController)
+	if !ok {
+		return errors.New("the objects controller does not keep a state cache")
+	}
+	cache.EnableStateSuppression(heartbeat)
+	return nil
+}
+
+// RecoveredPanics returns how many panics raised by driver code were recovered
+// since the process started, per dispatch boundary ("action", "config",
+// "eventbus", "setup", "close"). Each one was reported as an error and logged
+// with its stack trace instead of crashing the driver.
+func (c *NetsocsDriverClient) RecoveredPanics() map[string]uint64 {
+	return recovery.Counts()
+}
+
+// OnActionsConnectionStateChange registers fn to be called every time the
+// objects websocket, the one that delivers action requests, connects or drops.
+// The SDK reconnects on its own; use it to log outages or mark devices as
+// degraded. fn must not block.
+func (c *NetsocsDriverClient) OnActionsConnectionStateChange(fn func(httpx.ConnectionStateChange)) error {
+	notifier, ok := c.objectsRunner.GetController().(objects.ConnectionStateNotifier)
+	if !ok {
+		return errors.New("the objects controller does not report its connection state")
+	}
+	notifier.OnConnectionStateChange(fn)
+	return nil
+}
+
 func (c *NetsocsDriverClient) AddEventTypes(eventTypes []objects.EventType) error {
 	err := c.objectsRunner.GetController().AddEventTypes(eventTypes)
 
 	return err
 }
 
+// DispatchEvent sends an event of type domain.eventKey and returns the ID the
+// hub gave it. See DispatchEventType; a queued event returns
+// outbox.ErrQueued.
 func (c *NetsocsDriverClient) DispatchEvent(domain string, eventKey string, eventData objects.Event) (string, error) {
+	resp, err := c.DispatchEventType(domain+"."+eventKey, eventData)
+	return resp.ID, err
+}
+
+// DispatchEventType sends an event of a registered type. The event is first
+// checked against the types registered with AddEventTypes and their schemas,
+// as SetEventValidation decides. An event queued in the outbox returns an
+// error wrapping outbox.ErrQueued and no ID: it is sent once the hub is
+// reachable again.
+func (c *NetsocsDriverClient) DispatchEventType(eventType string, eventData objects.Event) (objects.EventDispatchResponse, error) {
+	if err := c.validateEvent(eventType, eventData.Properties); err != nil {
+		return objects.EventDispatchResponse{}, err
+	}
 	req := objects.NewEventRequestBodySchema{}
-	req.EventType = domain + "." + eventKey
+	req.EventType = eventType
 	req.EventAdditionalProperties = eventData.Properties
 	req.Images = eventData.ImageURLs
 	req.VideoClips = eventData.VideoURLs
@@ -263,13 +534,47 @@ func (c *NetsocsDriverClient) DispatchEvent(domain string, eventKey string, even
 		req.Rels = append(req.Rels, fmt.Sprintf("/objects/%s", objID))
 	}
 
-	resp, err := httpx.Resty().R().SetHeader("X-Auth-Token", c.token).SetBody(req).Post(c.driverHubHost + "/objects/events")
+	// A queued event is replayed later, so it must carry the time it
+	// happened.
+	occurredAt := eventData.Timestamp
+	if occurredAt.IsZero() && c.outbox.Load() != nil {
+		occurredAt = time.Now()
+	}
+	if !occurredAt.IsZero() {
+		properties := make(map[string]string, len(eventData.Properties)+1)
+		for k, v := range eventData.Properties {
+			properties[k] = v
+		}
+		properties[objects.EventPropertyOccurredAt] = occurredAt.UTC().Format(time.RFC3339Nano)
+		req.EventAdditionalProperties = properties
+	}
 
-	if err != nil {
-		return "", err
+	// The same key goes with the retries, when enabled, and with the replay
+	// from the outbox, so a hub honouring it creates the event once.
+	idempotencyKey := httpx.NewIdempotencyKey()
+	if queued, err := c.queueEvent(req, idempotencyKey, 0, nil); queued {
+		return objects.EventDispatchResponse{}, err
+	}
+	resp, err := httpx.Resty().R().
+		SetHeader("X-Auth-Token", c.token).
+		SetHeader(httpx.IdempotencyKeyHeader, idempotencyKey).
+		SetBody(req).
+		Post(c.driverHubHost + "/objects/events")
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode()
+	}
+	if queued, queueErr := c.queueEvent(req, idempotencyKey, statusCode, err); queued {
+		return objects.EventDispatchResponse{}, queueErr
 	}
-	return resp.String(), nil
 
+	if err != nil {
+		return objects.EventDispatchResponse{}, err
+	}
+	if resp.IsError() {
+		return objects.EventDispatchResponse{}, httpx.NewHubError(resp)
+	}
+	return objects.ParseEventDispatchResponse(resp.Body()), nil
 }
 
 func (c *NetsocsDriverClient) GetEvent(eventId string) (objects.EventRecord, error) {
@@ -327,7 +632,7 @@ func (c *NetsocsDriverClient) PatchEvent(eventId string, event objects.EventReco
 	}
 
 	if resp.IsError() {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 
 	return nil
@@ -343,7 +648,7 @@ func (c *NetsocsDriverClient) SetObjectsBatchState(states []objects.ObjectStateC
 	}
 
 	if resp.IsError() {
-		return []objects.ChangeStateBatchResponse{}, errors.New(resp.String())
+		return []objects.ChangeStateBatchResponse{}, httpx.NewHubError(resp)
 	}
 	var response []objects.ChangeStateBatchResponse
 	if err := json.Unmarshal(resp.Body(), &response); err != nil {
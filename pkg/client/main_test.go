@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHubServer accepts every websocket and reads until the client closes it.
+func newHubServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNetsocsDriverClient_Shutdown(t *testing.T) {
+	srv := newHubServer(t)
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	// Several clients can live in the same process and stop independently.
+	for i := 0; i < 2; i++ {
+		client := NewNetsocsDriverClient("key", host, false)
+		listenErr := make(chan error, 1)
+		go func() { listenErr <- client.ListenConfig() }()
+
+		require.Eventually(t, func() bool {
+			return client.ConfigConnectionState() == httpx.ConnectionStateConnected
+		}, 2*time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		require.NoError(t, client.Shutdown(ctx))
+		cancel()
+
+		select {
+		case err := <-listenErr:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("ListenConfig did not return after Shutdown")
+		}
+	}
+}
+
+func TestNetsocsDriverClient_DispatchEvent_queuedWhileHubIsDown(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	events := make(chan map[string]any, 1)
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			if ws, err := upgrader.Upgrade(w, r, nil); err == nil {
+				defer ws.Close()
+				for {
+					if _, _, err := ws.ReadMessage(); err != nil {
+						return
+					}
+				}
+			}
+			return
+		}
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		events <- body
+	}))
+	defer srv.Close()
+
+	client := NewNetsocsDriverClient("key", srv.URL, false)
+	defer client.Shutdown(context.Background())
+	require.NoError(t, client.EnableOutbox(outbox.Options{Path: filepath.Join(t.TempDir(), "outbox.jsonl")}))
+
+	occurredAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	id, err := client.DispatchEvent("door", "forced", objects.Event{ObjectIDs: []string{"door-1"}, Timestamp: occurredAt})
+	require.ErrorIs(t, err, outbox.ErrQueued)
+	assert.Empty(t, id)
+	assert.Equal(t, 1, client.OutboxLen())
+
+	down.Store(false)
+	client.outbox.Load().Kick()
+	select {
+	case body := <-events:
+		assert.Equal(t, "door.forced", body["event_type"])
+		assert.Equal(t, map[string]any{objects.EventPropertyOccurredAt: "2026-03-04T05:06:07Z"}, body["event_additional_properties"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued event was not replayed")
+	}
+	require.Eventually(t, func() bool { return client.OutboxLen() == 0 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestNetsocsDriverClient_ServeMetrics(t *testing.T) {
+	hub := newHubServer(t)
+	client := NewNetsocsDriverClient("key", hub.URL, false)
+
+	require.NoError(t, client.ServeMetrics("127.0.0.1:0"))
+	assert.Error(t, client.ServeMetrics("127.0.0.1:0"), "the endpoint can only be served once")
+
+	res, err := http.Get("http://" + client.MetricsAddr() + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	for _, family := range []string{
+		"driver_action_executions_total",
+		"driver_action_duration_seconds",
+		"driver_config_messages_total",
+		"driver_config_queue_depth",
+		"driver_hub_http_requests_total",
+		"driver_websocket_reconnects_total",
+		"driver_audio_sessions_active",
+		"driver_recovered_panics_total",
+	} {
+		assert.Contains(t, string(body), "# TYPE "+family+" ")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, client.Shutdown(ctx))
+	_, err = http.Get("http://" + client.MetricsAddr() + "/metrics")
+	assert.Error(t, err, "Shutdown stops the endpoint")
+}
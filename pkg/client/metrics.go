@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+)
+
+// ServeMetrics serves the SDK metrics at /metrics on addr, in the Prometheus
+// text exposition format. Bind it to a local address, e.g. "127.0.0.1:9464";
+// addr may use port 0, see MetricsAddr. It returns once the endpoint is
+// listening. Shutdown stops it.
+//
+// The metrics cover action executions and their latency, config messages and
+// handler latency, the HTTP requests sent to the DriverHub, websocket
+// reconnects, the config queue depth, open talkback and microphone sessions,
+// and recovered panics.
+func (c *NetsocsDriverClient) ServeMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if !c.metricsServer.CompareAndSwap(nil, srv) {
+		listener.Close()
+		return errors.New("metrics endpoint already served")
+	}
+	c.metricsAddr.Store(listener.Addr().String())
+	go srv.Serve(listener)
+	return nil
+}
+
+// MetricsAddr returns the address ServeMetrics listens on, or "" when it was
+// not called.
+func (c *NetsocsDriverClient) MetricsAddr() string {
+	addr, _ := c.metricsAddr.Load().(string)
+	return addr
+}
+
+// MetricsHandler returns the handler ServeMetrics mounts at /metrics, for
+// drivers that already run an HTTP server.
+func (c *NetsocsDriverClient) MetricsHandler() http.Handler {
+	return metrics.Handler()
+}
+
+func (c *NetsocsDriverClient) closeMetrics(ctx context.Context) error {
+	srv := c.metricsServer.Load()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
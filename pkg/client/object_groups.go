@@ -2,7 +2,6 @@ package client
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
@@ -104,7 +103,7 @@ func (c *NetsocsDriverClient) CreateObjectGroup(req CreateObjectGroupRequest, pa
 		return ObjectGroup{}, err
 	}
 	if resp.IsError() {
-		return ObjectGroup{}, fmt.Errorf("object-groups API create: %s", resp.String())
+		return ObjectGroup{}, fmt.Errorf("object-groups API create: %w", httpx.NewHubError(resp))
 	}
 	var group ObjectGroup
 	if err := json.Unmarshal(resp.Body(), &group); err != nil {
@@ -123,7 +122,7 @@ func (c *NetsocsDriverClient) GetObjectGroup(id string) (ObjectGroup, error) {
 		return ObjectGroup{}, err
 	}
 	if resp.IsError() {
-		return ObjectGroup{}, fmt.Errorf("object-groups API get: %s", resp.String())
+		return ObjectGroup{}, fmt.Errorf("object-groups API get: %w", httpx.NewHubError(resp))
 	}
 	var group ObjectGroup
 	if err := json.Unmarshal(resp.Body(), &group); err != nil {
@@ -144,7 +143,7 @@ func (c *NetsocsDriverClient) UpdateObjectGroup(id string, req UpdateObjectGroup
 		return ObjectGroup{}, err
 	}
 	if resp.IsError() {
-		return ObjectGroup{}, errors.New(resp.String())
+		return ObjectGroup{}, httpx.NewHubError(resp)
 	}
 	var group ObjectGroup
 	if err := json.Unmarshal(resp.Body(), &group); err != nil {
@@ -163,7 +162,7 @@ func (c *NetsocsDriverClient) DeleteObjectGroup(id string) error {
 		return err
 	}
 	if resp.IsError() {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 	return nil
 }
@@ -179,7 +178,7 @@ func (c *NetsocsDriverClient) GetObjectGroupTree() ([]ObjectGroupTree, error) {
 		return nil, err
 	}
 	if resp.IsError() {
-		return nil, fmt.Errorf("object-groups API tree: %s", resp.String())
+		return nil, fmt.Errorf("object-groups API tree: %w", httpx.NewHubError(resp))
 	}
 	var result struct {
 		Tree []ObjectGroupTree `json:"tree"`
@@ -201,7 +200,7 @@ func (c *NetsocsDriverClient) GetObjectGroupChildren(parentID string) ([]ObjectG
 		return nil, err
 	}
 	if resp.IsError() {
-		return nil, fmt.Errorf("object-groups API children: %s", resp.String())
+		return nil, fmt.Errorf("object-groups API children: %w", httpx.NewHubError(resp))
 	}
 	var result struct {
 		Tree []ObjectGroupTreeNode `json:"tree"`
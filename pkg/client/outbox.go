@@ -0,0 +1,88 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
+)
+
+// EnableOutbox turns on offline buffering. While the DriverHub is unreachable,
+// SetState, UpdateStateAttributes and DispatchEvent queue their writes in an
+// outbox, persisted to opts.Path, instead of failing; they are replayed in
+// order, with events keeping their original timestamp, as soon as the hub is
+// reachable again. Call it once, before registering objects. Shutdown stops
+// the replay and closes the outbox; entries not yet delivered stay in the
+// file for the next run.
+func (c *NetsocsDriverClient) EnableOutbox(opts outbox.Options) error {
+	controller, ok := c.objectsRunner.GetController().(objects.OutboxController)
+	if !ok {
+		return errors.New("the objects controller does not support an outbox")
+	}
+	if c.outbox.Load() != nil {
+		return errors.New("outbox already enabled")
+	}
+	ob, err := outbox.Open(opts)
+	if err != nil {
+		return err
+	}
+	if !c.outbox.CompareAndSwap(nil, ob) {
+		ob.Close()
+		return errors.New("outbox already enabled")
+	}
+	controller.SetOutbox(ob)
+
+	// Replay right away when the objects websocket is back, rather than
+	// waiting for the next backoff delay.
+	c.OnActionsConnectionStateChange(func(change httpx.ConnectionStateChange) {
+		if change.State == httpx.ConnectionStateConnected {
+			ob.Kick()
+		}
+	})
+
+	c.outboxDone = make(chan struct{})
+	go func() {
+		defer close(c.outboxDone)
+		ob.Run(c.ctx, controller.ReplayOutboxEntry)
+	}()
+	return nil
+}
+
+// OutboxLen returns how many writes are waiting in the outbox; 0 when it is
+// not enabled.
+func (c *NetsocsDriverClient) OutboxLen() int {
+	if ob := c.outbox.Load(); ob != nil {
+		return ob.Len()
+	}
+	return 0
+}
+
+// queueEvent queues an event in the outbox when the hub is unreachable or
+// earlier writes are still waiting. queued reports whether it did; err is then
+// outbox.ErrQueued, or the error that kept the event out of the outbox.
+func (c *NetsocsDriverClient) queueEvent(req objects.NewEventRequestBodySchema, idempotencyKey string, statusCode int, sendErr error) (queued bool, err error) {
+	ob := c.outbox.Load()
+	if ob == nil {
+		return false, nil
+	}
+	if ob.Len() == 0 && !httpx.Unreachable(statusCode, sendErr) {
+		return false, nil
+	}
+	if err := ob.AppendIdempotent(http.MethodPost, "/objects/events", req, idempotencyKey); err != nil {
+		return true, err
+	}
+	return true, outbox.ErrQueued
+}
+
+// closeOutbox waits for the replay loop, which stops with c.ctx, and closes
+// the outbox.
+func (c *NetsocsDriverClient) closeOutbox() error {
+	ob := c.outbox.Load()
+	if ob == nil {
+		return nil
+	}
+	<-c.outboxDone
+	return ob.Close()
+}
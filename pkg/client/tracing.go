@@ -0,0 +1,15 @@
+package client
+
+import "github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
+
+// SetTraceExporter enables tracing: a span is recorded for every action
+// execution, config message and DriverHub call, and handed to e when it
+// ends. HTTP calls carry the span context in the traceparent header. A nil e
+// disables tracing, the default.
+//
+// Use tracing.NewWriterExporter(os.Stdout) to print the spans while
+// debugging, or implement tracing.Exporter to forward them to a tracing
+// backend. The exporter is process-wide.
+func (n *NetsocsDriverClient) SetTraceExporter(e tracing.Exporter) {
+	tracing.SetExporter(e)
+}
@@ -2,7 +2,6 @@ package client
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
@@ -45,7 +44,7 @@ func (c *NetsocsDriverClient) CreateUserGroup(req CreateUserGroupRequest) (UserG
 		return UserGroup{}, err
 	}
 	if resp.IsError() {
-		return UserGroup{}, fmt.Errorf("user-groups API: %s", resp.String())
+		return UserGroup{}, fmt.Errorf("user-groups API: %w", httpx.NewHubError(resp))
 	}
 	var group UserGroup
 	if err := json.Unmarshal(resp.Body(), &group); err != nil {
@@ -64,7 +63,7 @@ func (c *NetsocsDriverClient) GetUserGroups() ([]UserGroup, error) {
 		return nil, err
 	}
 	if resp.IsError() {
-		return nil, errors.New(resp.String())
+		return nil, httpx.NewHubError(resp)
 	}
 	var groups []UserGroup
 	if err := json.Unmarshal(resp.Body(), &groups); err != nil {
@@ -83,7 +82,7 @@ func (c *NetsocsDriverClient) GetUserGroup(id string) (UserGroup, error) {
 		return UserGroup{}, err
 	}
 	if resp.IsError() {
-		return UserGroup{}, errors.New(resp.String())
+		return UserGroup{}, httpx.NewHubError(resp)
 	}
 	var group UserGroup
 	if err := json.Unmarshal(resp.Body(), &group); err != nil {
@@ -104,7 +103,7 @@ func (c *NetsocsDriverClient) UpdateUserGroup(id string, req UpdateUserGroupRequ
 		return UserGroup{}, err
 	}
 	if resp.IsError() {
-		return UserGroup{}, errors.New(resp.String())
+		return UserGroup{}, httpx.NewHubError(resp)
 	}
 	var group UserGroup
 	if err := json.Unmarshal(resp.Body(), &group); err != nil {
@@ -123,7 +122,7 @@ func (c *NetsocsDriverClient) DeleteUserGroup(id string) error {
 		return err
 	}
 	if resp.IsError() {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 	return nil
 }
@@ -54,7 +54,7 @@ func (n *NetsocsDriverClient) RTSPToStreamID(rtsp string, streamID string, opts
 	}
 
 	if resp.StatusCode() >= 400 {
-		return "", fmt.Errorf("error converting rtsp to stream id: %s", resp.String())
+		return "", fmt.Errorf("error converting rtsp to stream id: %w", httpx.NewHubError(resp))
 	}
 
 	return videoEngineDefaultId, nil
@@ -77,7 +77,7 @@ func (n *NetsocsDriverClient) HTTPToStreamID(httpUrl string, streamID string) (v
 	}
 
 	if resp.StatusCode() >= 400 {
-		return "", fmt.Errorf("error converting http to stream id: %s", resp.String())
+		return "", fmt.Errorf("error converting http to stream id: %w", httpx.NewHubError(resp))
 	}
 	return videoEngineDefaultId, nil
 }
@@ -98,7 +98,7 @@ func (n *NetsocsDriverClient) PublishToStreamID(streamID string) (videoEngine st
 	}
 
 	if resp.StatusCode() >= 400 {
-		return "", fmt.Errorf("error converting http to stream id: %s", resp.String())
+		return "", fmt.Errorf("error converting http to stream id: %w", httpx.NewHubError(resp))
 	}
 	return videoEngineDefaultId, nil
 }
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Empty is the request or response schema of config keys that carry no data.
+// A handler returning Empty answers the DriverHub with the default "OK" reply.
+type Empty struct{}
+
+// Validator is implemented by request schemas that can check their own fields.
+// Handle calls Validate after decoding and answers with an error when it fails,
+// so the handler only sees well-formed requests.
+type Validator interface {
+	Validate() error
+}
+
+// Key ties a NetsocsConfigKey to the request and response schemas the
+// DriverHub uses for it. The known keys are declared in typed_keys.go; use
+// NewKey for driver-specific ones.
+type Key[Req, Resp any] struct {
+	name NetsocsConfigKey
+}
+
+// NewKey declares a typed config key.
+func NewKey[Req, Resp any](name NetsocsConfigKey) Key[Req, Resp] {
+	return Key[Req, Resp]{name: name}
+}
+
+// Name returns the raw config key.
+func (k Key[Req, Resp]) Name() NetsocsConfigKey {
+	return k.name
+}
+
+// TypedHandler handles a decoded and validated config request.
+type TypedHandler[Req, Resp any] func(req Req, deviceData *ConfigMessageDeviceData) (Resp, error)
+
+// Handle registers fn for key on top of AddConfigHandler. The request value is
+// decoded into Req and validated when Req implements Validator, and the Resp
+// returned by fn is encoded as the reply. Since key carries both schemas,
+// wiring a key to a handler of the wrong types does not compile:
+//
+//	config.Handle(config.KeyGetChannels, func(_ config.Empty, dev *config.ConfigMessageDeviceData) (config.GetChannelResponse, error) {
+//		...
+//	})
+func Handle[Req, Resp any](key Key[Req, Resp], fn TypedHandler[Req, Resp]) error {
+	if fn == nil {
+		return fmt.Errorf("config handler for %q cannot be nil", key.name)
+	}
+	return AddConfigHandler(key.name, func(value HandlerValue) (interface{}, error) {
+		req, err := decodeRequest[Req](value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid request: %w", key.name, err)
+		}
+		resp, err := fn(req, value.DeviceData)
+		if err != nil {
+			return nil, err
+		}
+		if _, empty := any(resp).(Empty); empty {
+			return nil, nil
+		}
+		return resp, nil
+	})
+}
+
+// decodeRequest unmarshals a config value into Req and validates it. An empty
+// value leaves Req at its zero value, as the hub sends nothing for requests
+// without parameters.
+func decodeRequest[Req any](value string) (Req, error) {
+	var req Req
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), &req); err != nil {
+			return req, err
+		}
+	}
+	if v, ok := any(&req).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs one message through handleConfigMessage and returns the reply.
+func serve(t *testing.T, message *ConfigMessage) *s_response {
+	t.Helper()
+	go handleConfigMessage(message, nil)
+	select {
+	case resp := <-responses:
+		return resp
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply")
+		return nil
+	}
+}
+
+func TestHandle_decodesRequestAndEncodesResponse(t *testing.T) {
+	require.NoError(t, Handle(KeyGetRecordingRanges, func(req GetRecordingRangesRequest, _ *ConfigMessageDeviceData) (GetRecordingRangesResponse, error) {
+		return GetRecordingRangesResponse{{UTCStart: "start-" + req.ChannelNumber, UTCEnd: "end"}}, nil
+	}))
+
+	resp := serve(t, &ConfigMessage{ConfigKey: GET_RECORDING_RANGES, RequestID: "r1", Value: `{"channelNumber":"3"}`})
+	assert.Equal(t, "r1", resp.RequestId)
+	assert.JSONEq(t, `[{"utcStart":"start-3","utcEnd":"end"}]`, resp.Data)
+}
+
+func TestHandle_rejectsInvalidRequests(t *testing.T) {
+	called := false
+	require.NoError(t, Handle(KeyActionZoom, func(ActionZoomRequest, *ConfigMessageDeviceData) (Empty, error) {
+		called = true
+		return Empty{}, nil
+	}))
+
+	resp := serve(t, &ConfigMessage{ConfigKey: ACTION_ZOOM, RequestID: "z1", Value: `{"zoomDirection":0}`})
+	assert.JSONEq(t, `{"error":true,"msg":"actionZoom: invalid request: channelNumber is required"}`, resp.Data)
+
+	resp = serve(t, &ConfigMessage{ConfigKey: ACTION_ZOOM, RequestID: "z2", Value: `not json`})
+	assert.Contains(t, resp.Data, `"error":true`)
+	assert.False(t, called)
+}
+
+func TestHandle_emptyResponseRepliesOK(t *testing.T) {
+	require.NoError(t, Handle(KeyGetChannels, func(Empty, *ConfigMessageDeviceData) (GetChannelResponse, error) {
+		return GetChannelResponse{{ChannelNumber: "1"}}, nil
+	}))
+	require.NoError(t, Handle(KeySetFlipVideo, func(SetFlipVideoRequest, *ConfigMessageDeviceData) (Empty, error) {
+		return Empty{}, nil
+	}))
+
+	resp := serve(t, &ConfigMessage{ConfigKey: GET_CHANNELS, RequestID: "c1"})
+	assert.Contains(t, resp.Data, `"channelNumber":"1"`)
+
+	resp = serve(t, &ConfigMessage{ConfigKey: SET_FLIP_VIDEO, RequestID: "f1", Value: `{}`})
+	assert.JSONEq(t, `{"error":false,"msg":"OK"}`, resp.Data)
+}
+
+func TestHandle_nilHandler(t *testing.T) {
+	assert.Error(t, Handle[Empty, GetChannelResponse](KeyGetChannels, nil))
+}
@@ -1,20 +1,17 @@
 package config
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"net/url"
 	"os"
-	"os/signal"
 	"strconv"
 	"sync"
+	"time"
 
-	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
-	"github.com/Netsocs-Team/driver.sdk_go/pkg/tools"
-	"github.com/gorilla/websocket"
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
 )
 
 type ConfigMessagePort interface {
@@ -101,73 +98,219 @@ func configWorkerCount() int {
 	return defaultConfigWorkers
 }
 
+var (
+	workersMu sync.Mutex
+	workers   *workerPool
+	// workerRefs counts the holders of the pool: each running Session holds
+	// it, so stopping one session leaves the others served.
+	workerRefs int
+)
+
+// workerPool is the running set of config workers; see startConfigWorkers.
+type workerPool struct {
+	stop    chan struct{} // closed to stop dispatching queued messages
+	abandon chan struct{} // closed to drop replies nobody will write
+	stopped chan struct{} // closed once every worker has returned
+	queues  []chan *ConfigMessage
+}
+
 // startConfigWorkers launches the pool that consumes `messages`. Messages for
 // the same device always land on the same worker, so per-device order is
 // preserved (requestCreateObjects runs before that device's actionListenEvent);
 // messages without device data share worker 0. Different devices initialize in
 // parallel, so one slow or unreachable device cannot stall the whole fleet nor
-// the websocket read loop.
-var startWorkersOnce sync.Once
-
+// the websocket read loop. A pool already running is shared: every call
+// takes a reference that releaseConfigWorkers gives back.
 func startConfigWorkers() {
-	startWorkersOnce.Do(func() {
-		workers := configWorkerCount()
-		queues := make([]chan *ConfigMessage, workers)
-		for i := range queues {
-			queues[i] = make(chan *ConfigMessage, 256)
-			go func(q chan *ConfigMessage) {
-				for message := range q {
-					handleConfigMessage(message)
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	workerRefs++
+	if workers != nil {
+		return
+	}
+
+	pool := &workerPool{
+		stop:    make(chan struct{}),
+		abandon: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	count := configWorkerCount()
+	queues := make([]chan *ConfigMessage, count)
+	pool.queues = queues
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan *ConfigMessage, 256)
+		wg.Add(1)
+		go func(q chan *ConfigMessage) {
+			defer wg.Done()
+			for message := range q {
+				select {
+				case <-pool.stop:
+					// Stopping: drop what is still queued.
+				default:
+					handleConfigMessage(message, pool.abandon)
 				}
-			}(queues[i])
-		}
-		go func() {
-			for message := range messages {
+			}
+		}(queues[i])
+	}
+	go func() {
+		defer close(pool.stopped)
+		defer wg.Wait()
+		defer func() {
+			for _, q := range queues {
+				close(q)
+			}
+		}()
+		for {
+			select {
+			case message := <-messages:
 				idx := 0
 				if message.DeviceData != nil {
-					idx = message.DeviceData.ID % workers
+					idx = message.DeviceData.ID % count
 					if idx < 0 {
 						idx = -idx
 					}
 				}
 				queues[idx] <- message
+			case <-pool.stop:
+				discardQueuedMessages()
+				return
 			}
-		}()
-	})
+		}
+	}()
+	workers = pool
+}
+
+// discardQueuedMessages empties `messages` so a stopped session's requests
+// are not served by the next pool.
+func discardQueuedMessages() {
+	for {
+		select {
+		case <-messages:
+		default:
+			return
+		}
+	}
+}
+
+// StopWorkers stops the config worker pool of the whole process, whichever
+// sessions still use it: queued messages are dropped, and it waits for the
+// handlers already running to return and their replies to be written. If ctx
+// expires first, pending replies are dropped and ctx.Err() is returned. The
+// next Session.Run starts a fresh pool. To stop serving one session only, call
+// Session.StopWorkers.
+func StopWorkers(ctx context.Context) error {
+	workersMu.Lock()
+	pool := workers
+	workers = nil
+	workerRefs = 0
+	workersMu.Unlock()
+	return pool.shutdown(ctx)
+}
+
+// releaseConfigWorkers gives back a reference taken by startConfigWorkers,
+// and stops the pool as StopWorkers does once nobody holds it.
+func releaseConfigWorkers(ctx context.Context) error {
+	workersMu.Lock()
+	if workerRefs > 0 {
+		workerRefs--
+	}
+	if workerRefs > 0 {
+		workersMu.Unlock()
+		return nil
+	}
+	pool := workers
+	workers = nil
+	workersMu.Unlock()
+	return pool.shutdown(ctx)
+}
+
+// shutdown stops the pool, see StopWorkers. p may be nil.
+func (p *workerPool) shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	close(p.stop)
+	select {
+	case <-p.stopped:
+		return nil
+	case <-ctx.Done():
+		close(p.abandon)
+		return ctx.Err()
+	}
 }
 
 // handleConfigMessage dispatches one config message to its registered handler
-// and pushes the reply onto `responses`.
-func handleConfigMessage(message *ConfigMessage) {
+// and pushes the reply onto `responses`. The reply is dropped once abandon is
+// closed, since nobody may be left to write it to the websocket.
+func handleConfigMessage(message *ConfigMessage, abandon <-chan struct{}) {
+	key := string(message.ConfigKey)
+	log := logger.Logger().With("request_id", message.RequestID, "config_key", key)
+	_, span := tracing.Start(context.Background(), "config.message")
+	defer span.End()
+	span.SetAttribute("request_id", message.RequestID)
+	span.SetAttribute("config_key", key)
+	if span != nil {
+		log = log.With("trace_id", span.SpanContext().TraceID)
+	}
 	handler := handlersMap[message.ConfigKey]
 	if handler == nil {
-		sendDefaultResponse(message.RequestID, true, fmt.Sprintf("'%s' not found on the driver", message.ConfigKey))
+		configMessages.Inc(key, configOutcomeUnknownKey)
+		span.SetStatus(tracing.StatusError, "no handler registered")
+		log.Warn("no handler registered for config key")
+		sendDefaultResponse(message.RequestID, true, fmt.Sprintf("'%s' not found on the driver", message.ConfigKey), abandon)
 		return
 	}
-	response, err := handler(message.Value, message.DeviceData)
+	start := time.Now()
+	response, err := runConfigHandler(handler, message)
+	configHandlerDuration.ObserveSince(start, key)
 	if err != nil {
-		sendDefaultResponse(message.RequestID, true, err.Error())
+		configMessages.Inc(key, configOutcomeError)
+		span.RecordError(err)
+		log.Infow("config handler failed", "error", err)
+		sendDefaultResponse(message.RequestID, true, err.Error(), abandon)
 		return
 	}
+	configMessages.Inc(key, configOutcomeOK)
+	span.SetStatus(tracing.StatusOK, "")
+	log.Debug("config handler succeeded")
 	if response == "" || response == "null" {
-		sendDefaultResponse(message.RequestID, false, "OK")
+		sendDefaultResponse(message.RequestID, false, "OK", abandon)
 		return
 	}
-	responses <- &s_response{
+	sendResponse(&s_response{
 		RequestId: message.RequestID,
 		Data:      response,
-	}
+	}, abandon)
 }
 
-func sendDefaultResponse(requestID string, isError bool, msg string) {
+// runConfigHandler calls handler, turning a panic into an error reply so a
+// faulty handler cannot take the driver down.
+func runConfigHandler(handler handlerFunction, message *ConfigMessage) (response string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovery.Error(recovery.BoundaryConfig, r)
+		}
+	}()
+	return handler(message.Value, message.DeviceData)
+}
+
+func sendDefaultResponse(requestID string, isError bool, msg string, abandon <-chan struct{}) {
 	jsondata, err := json.Marshal(&defaultDataResponse{Error: isError, Msg: msg})
 	if err != nil {
-		fmt.Println("Error in handler:", err)
+		logger.Logger().Errorw("failed to marshal config response", "request_id", requestID, "error", err)
 		return
 	}
-	responses <- &s_response{
+	sendResponse(&s_response{
 		RequestId: requestID,
 		Data:      string(jsondata),
+	}, abandon)
+}
+
+func sendResponse(response *s_response, abandon <-chan struct{}) {
+	select {
+	case responses <- response:
+	case <-abandon:
 	}
 }
 
@@ -176,120 +319,19 @@ func sendDefaultResponse(requestID string, isError bool, msg string) {
 // In the SDK, there is a map of handlers that can be registered for each configuration.
 // This function, upon receiving a configuration, will look in the map of handlers
 // to see if there is a handler for that configuration. If there is no handler, it will return an error.
+// The connection is supervised by a Session: it is retried while the DriverHub
+// is unreachable and re-established after read/write errors, so this call only
+// returns on interrupt.
 // More information here https://.../docs
 func ListenConfig(host string, driverKey string, siteId string, token string, driverID string, setVideoEngineID func(string, VideoEngineAdditionalProperties), driverVersion string, driverDocumentation string) error {
-	startConfigWorkers()
-
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-
-	// Convert the URL using the utility function with query parameters
-	documentationInBase64 := ""
-	if driverDocumentation != "" {
-		documentationInBase64 = base64.StdEncoding.EncodeToString([]byte(driverDocumentation))
-	}
-	path := fmt.Sprintf("ws/v1/config_communication?site_id=%s&driver_id=%s&driver_version=%s&driver_documentation=%s", siteId, driverID, driverVersion, documentationInBase64)
-	wsURL := tools.ConvertToWebSocketURL(host, path)
-
-	u, err := url.Parse(wsURL)
-	if err != nil {
-		return err
-	}
-
-	log.Printf("connecting to %s", u.String())
-
-	// Shared dialer: honours the SDK TLS configuration (custom CA bundle or
-	// verification opt-out). See pkg/httpx.
-	dialer := httpx.WebsocketDialer()
-
-	c, _, err := dialer.Dial(u.String(), http.Header{
-		"Authorization": []string{driverKey},
-		"X-Auth-Token":  []string{token},
-	})
-	if err != nil {
-		log.Fatal("dial:", err)
-	}
-	defer c.Close()
-
-	done := make(chan struct{})
-
-	go func() {
-		defer close(done)
-		for {
-			_, message, err := c.ReadMessage()
-			if err != nil {
-				log.Println("read:", err)
-				return
-			}
-			configMessage := &ConfigMessage{}
-			err = json.Unmarshal(message, configMessage)
-			if err != nil {
-				log.Println("unmarshal:", err)
-			} else {
-				// Handle PING message immediately
-				if configMessage.ConfigKey == PING {
-					log.Printf("recv PING, responding with PONG")
-					responses <- &s_response{
-						RequestId: configMessage.RequestID,
-						Data:      "pong",
-					}
-					continue
-				}
-
-				messages <- configMessage
-			}
-
-			if configMessage.ConfigKey == SAVE_VIDEO_ENGINE {
-				type msg struct {
-					VideoEngine                     string                          `json:"video_engine"`
-					VideoEngineAdditionalProperties VideoEngineAdditionalProperties `json:"video_engine_additional_properties"`
-				}
-
-				var msgData msg
-				err = json.Unmarshal([]byte(configMessage.Value), &msgData)
-				if err != nil {
-					log.Println("unmarshal msgData:", err)
-				} else {
-					if setVideoEngineID != nil {
-						setVideoEngineID(msgData.VideoEngine, msgData.VideoEngineAdditionalProperties)
-					}
-				}
-			}
-
-			log.Printf("recv: %s", message)
-		}
-	}()
-
-	for {
-		select {
-		case response := <-responses:
-			jsondata, err := json.Marshal(response)
-			if err != nil {
-				log.Println("marshal:", err)
-			} else {
-				err = c.WriteMessage(websocket.TextMessage, jsondata)
-				if err != nil {
-					log.Println("write:", err)
-					return err
-				}
-			}
-
-		case <-done:
-			return nil
-		case <-interrupt:
-			log.Println("interrupt")
-
-			// Cleanly close the connection by sending a close message and then
-			// waiting (with timeout) for the server to close the connection.
-			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-			if err != nil {
-				log.Println("write close:", err)
-				return err
-			}
-			<-done
-
-			return nil
-		}
-	}
-
+	return NewSession(SessionParams{
+		Host:                host,
+		DriverKey:           driverKey,
+		SiteID:              siteId,
+		Token:               token,
+		DriverID:            driverID,
+		DriverVersion:       driverVersion,
+		DriverDocumentation: driverDocumentation,
+		SetVideoEngineID:    setVideoEngineID,
+	}).Run(context.Background())
 }
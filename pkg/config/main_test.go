@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopWorkers_waitsForRunningHandlers(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, AddConfigHandler("test.stop.drain", func(HandlerValue) (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	}))
+
+	startConfigWorkers()
+	messages <- &ConfigMessage{ConfigKey: "test.stop.drain", RequestID: "drain-1"}
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- StopWorkers(context.Background()) }()
+
+	select {
+	case <-stopped:
+		t.Fatal("StopWorkers returned while a handler was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case resp := <-responses:
+		assert.Equal(t, "drain-1", resp.RequestId)
+		assert.Equal(t, `"done"`, resp.Data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the handler reply was not delivered")
+	}
+	require.NoError(t, <-stopped)
+}
+
+func TestStopWorkers_dropsRepliesOnDeadline(t *testing.T) {
+	started := make(chan struct{})
+	require.NoError(t, AddConfigHandler("test.stop.deadline", func(HandlerValue) (interface{}, error) {
+		close(started)
+		return "late", nil
+	}))
+
+	startConfigWorkers()
+	messages <- &ConfigMessage{ConfigKey: "test.stop.deadline", RequestID: "deadline-1"}
+	<-started
+
+	// Nobody reads responses, so the reply can only be dropped.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, StopWorkers(ctx), context.DeadlineExceeded)
+
+	// A new pool starts after a stop.
+	startConfigWorkers()
+	require.NoError(t, StopWorkers(context.Background()))
+}
+
+func TestSessionStopWorkers_keepsPoolOfOtherSessions(t *testing.T) {
+	require.NoError(t, AddConfigHandler("test.stop.shared", func(HandlerValue) (interface{}, error) {
+		return "served", nil
+	}))
+	first, second := NewSession(SessionParams{}), NewSession(SessionParams{})
+	for _, s := range []*Session{first, second} {
+		startConfigWorkers()
+		s.holdsWorkers = true
+	}
+
+	require.NoError(t, first.StopWorkers(context.Background()))
+	require.NoError(t, first.StopWorkers(context.Background()), "a second stop is a no-op")
+	messages <- &ConfigMessage{ConfigKey: "test.stop.shared", RequestID: "shared-1"}
+	select {
+	case resp := <-responses:
+		assert.Equal(t, "shared-1", resp.RequestId)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the pool stopped with a session still holding it")
+	}
+
+	require.NoError(t, second.StopWorkers(context.Background()))
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	assert.Nil(t, workers, "the last session stops the pool")
+}
+
+func TestHandleConfigMessage_recoversPanics(t *testing.T) {
+	require.NoError(t, AddConfigHandler("test.panic", func(HandlerValue) (interface{}, error) {
+		panic("bad device model")
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handleConfigMessage(&ConfigMessage{ConfigKey: "test.panic", RequestID: "panic-1"}, nil)
+	}()
+
+	select {
+	case resp := <-responses:
+		assert.Equal(t, "panic-1", resp.RequestId)
+		assert.Contains(t, resp.Data, `"error":true`)
+		assert.Contains(t, resp.Data, "bad device model")
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply for the panicking handler")
+	}
+	<-done
+}
+
+func TestHandleConfigMessage_recordsMetrics(t *testing.T) {
+	require.NoError(t, AddConfigHandler("test.metrics", func(HandlerValue) (interface{}, error) {
+		return nil, nil
+	}))
+
+	for _, key := range []NetsocsConfigKey{"test.metrics", "test.metrics.unknown"} {
+		go handleConfigMessage(&ConfigMessage{ConfigKey: key, RequestID: "metrics-" + string(key)}, nil)
+		select {
+		case <-responses:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no reply for %s", key)
+		}
+	}
+
+	assert.Equal(t, float64(1), configMessages.Value("test.metrics", configOutcomeOK))
+	assert.Equal(t, float64(1), configMessages.Value("test.metrics.unknown", configOutcomeUnknownKey))
+	assert.Equal(t, uint64(1), configHandlerDuration.Count("test.metrics"))
+}
+
+func TestHandleConfigMessage_recordsSpan(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+	require.NoError(t, AddConfigHandler("test.trace", func(HandlerValue) (interface{}, error) {
+		return nil, errors.New("device offline")
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handleConfigMessage(&ConfigMessage{ConfigKey: "test.trace", RequestID: "trace-1"}, nil)
+	}()
+	<-responses
+	<-done
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "config.message", spans[0].Name)
+	assert.Equal(t, "trace-1", spans[0].Attributes["request_id"])
+	assert.Equal(t, "test.trace", spans[0].Attributes["config_key"])
+	assert.Equal(t, tracing.StatusError, spans[0].Status)
+	assert.Equal(t, "device offline", spans[0].Error)
+}
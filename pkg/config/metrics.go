@@ -0,0 +1,39 @@
+package config
+
+import "github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+
+// Outcomes of driver_config_messages_total.
+const (
+	configOutcomeOK         = "ok"
+	configOutcomeError      = "error"
+	configOutcomeUnknownKey = "unknown_key"
+)
+
+var (
+	configMessages = metrics.NewCounterVec("driver_config_messages_total",
+		"Config messages handled, by config key and outcome (ok, error, unknown_key). PINGs are not counted.",
+		"key", "outcome")
+	configHandlerDuration = metrics.NewHistogramVec("driver_config_handler_duration_seconds",
+		"Duration of the config handlers, by config key.", nil, "key")
+)
+
+func init() {
+	metrics.NewGaugeFunc("driver_config_queue_depth",
+		"Config messages received and waiting for a worker.", func() float64 {
+			return float64(configQueueDepth())
+		})
+}
+
+// configQueueDepth counts the messages waiting in `messages` and in the
+// worker queues.
+func configQueueDepth() int {
+	depth := len(messages)
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	if workers != nil {
+		for _, q := range workers.queues {
+			depth += len(q)
+		}
+	}
+	return depth
+}
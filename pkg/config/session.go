@@ -0,0 +1,296 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tools"
+	"github.com/gorilla/websocket"
+)
+
+// SessionParams holds what a Session needs to reach the DriverHub config
+// websocket.
+type SessionParams struct {
+	Host                string
+	DriverKey           string
+	SiteID              string
+	Token               string
+	DriverID            string
+	DriverVersion       string
+	DriverDocumentation string
+
+	// SetVideoEngineID is called when the hub sends SAVE_VIDEO_ENGINE.
+	SetVideoEngineID func(string, VideoEngineAdditionalProperties)
+	// OnConnectionStateChange is called on every connection state change. It
+	// runs on the session goroutine and must not block.
+	OnConnectionStateChange func(httpx.ConnectionStateChange)
+	// Backoff paces the reconnection attempts. Zero means httpx.DefaultBackoff.
+	Backoff httpx.Backoff
+}
+
+// Session is a long-lived connection to the DriverHub config websocket. Sites
+// often boot the hub after the drivers, and the hub may restart at any time, so
+// the session keeps dialing until the hub answers and reconnects after any
+// read or write error. Registered handlers are process-wide; the worker pool is
+// shared by the running sessions and survives reconnections.
+type Session struct {
+	params SessionParams
+
+	mu    sync.Mutex
+	state httpx.ConnectionState
+	// holdsWorkers is set while Run holds a reference to the worker pool.
+	holdsWorkers bool
+}
+
+// NewSession returns a Session ready to Run.
+func NewSession(params SessionParams) *Session {
+	if params.Backoff == (httpx.Backoff{}) {
+		params.Backoff = httpx.DefaultBackoff()
+	}
+	return &Session{params: params, state: httpx.ConnectionStateDisconnected}
+}
+
+// StopWorkers releases the config worker pool held by Run, and waits for the
+// pool to stop when no other session holds it: queued messages are dropped,
+// and the handlers already running return and their replies are written. Call
+// it before cancelling Run so those replies still reach the DriverHub. If ctx
+// expires first, pending replies are dropped and ctx.Err() is returned.
+func (s *Session) StopWorkers(ctx context.Context) error {
+	s.mu.Lock()
+	held := s.holdsWorkers
+	s.holdsWorkers = false
+	s.mu.Unlock()
+	if !held {
+		return nil
+	}
+	return releaseConfigWorkers(ctx)
+}
+
+// State returns the current connection state.
+func (s *Session) State() httpx.ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *Session) setState(change httpx.ConnectionStateChange) {
+	s.mu.Lock()
+	s.state = change.State
+	s.mu.Unlock()
+	if s.params.OnConnectionStateChange != nil {
+		s.params.OnConnectionStateChange(change)
+	}
+}
+
+func (s *Session) url() (string, error) {
+	// Convert the URL using the utility function with query parameters
+	documentationInBase64 := ""
+	if s.params.DriverDocumentation != "" {
+		documentationInBase64 = base64.StdEncoding.EncodeToString([]byte(s.params.DriverDocumentation))
+	}
+	path := fmt.Sprintf("ws/v1/config_communication?site_id=%s&driver_id=%s&driver_version=%s&driver_documentation=%s", s.params.SiteID, s.params.DriverID, s.params.DriverVersion, documentationInBase64)
+	wsURL := tools.ConvertToWebSocketURL(s.params.Host, path)
+
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Run connects to the DriverHub and serves config requests until ctx is
+// cancelled or the process receives an interrupt, reconnecting with backoff in
+// between. It only returns an error when the hub URL is invalid.
+func (s *Session) Run(ctx context.Context) error {
+	wsURL, err := s.url()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if !s.holdsWorkers {
+		startConfigWorkers()
+		s.holdsWorkers = true
+	}
+	s.mu.Unlock()
+	defer func() {
+		// Run is over: replies of handlers still running have nowhere to go.
+		expired, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = s.StopWorkers(expired)
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			logger.Logger().Info("interrupt received, closing the config websocket")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	attempt := 0
+	for first := true; ; first = false {
+		if !first {
+			metrics.WebsocketReconnects.Inc("config")
+		}
+		s.setState(httpx.ConnectionStateChange{State: httpx.ConnectionStateConnecting, Attempt: attempt})
+		connected, err := s.serve(ctx, wsURL)
+		if connected {
+			attempt = 0
+		}
+		if ctx.Err() != nil {
+			s.setState(httpx.ConnectionStateChange{State: httpx.ConnectionStateDisconnected})
+			return nil
+		}
+		s.setState(httpx.ConnectionStateChange{State: httpx.ConnectionStateDisconnected, Err: err, Attempt: attempt})
+
+		delay := s.params.Backoff.Delay(attempt)
+		logger.Logger().Warnw("config websocket disconnected, reconnecting", "error", err, "attempt", attempt, "retry_in", delay.String())
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+		attempt++
+	}
+}
+
+// serve dials the config websocket once and pumps messages until the
+// connection fails or ctx is cancelled. connected reports whether the dial
+// succeeded.
+func (s *Session) serve(ctx context.Context, wsURL string) (connected bool, err error) {
+	logger.Logger().Infow("connecting to the config websocket", "url", wsURL)
+
+	// Shared dialer: honours the SDK TLS configuration (custom CA bundle or
+	// verification opt-out). See pkg/httpx.
+	dialer := httpx.WebsocketDialer()
+
+	c, _, err := dialer.DialContext(ctx, wsURL, http.Header{
+		"Authorization": []string{s.params.DriverKey},
+		"X-Auth-Token":  []string{s.params.Token},
+	})
+	if err != nil {
+		return false, fmt.Errorf("dial: %w", err)
+	}
+	defer c.Close()
+
+	s.setState(httpx.ConnectionStateChange{State: httpx.ConnectionStateConnected})
+
+	done := make(chan struct{})
+	var readErr error
+	// PONGs belong to this connection: once serve returns, they are dropped
+	// rather than sent on the next one.
+	pongs := make(chan *s_response)
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	go func() {
+		defer close(done)
+		for {
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				readErr = fmt.Errorf("read: %w", err)
+				return
+			}
+			s.handleMessage(message, pongs, stopped)
+		}
+	}()
+
+	write := func(response *s_response) error {
+		jsondata, err := json.Marshal(response)
+		if err != nil {
+			logger.Logger().Errorw("failed to marshal config response", "request_id", response.RequestId, "error", err)
+			return nil
+		}
+		if err := c.WriteMessage(websocket.TextMessage, jsondata); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case response := <-pongs:
+			if err := write(response); err != nil {
+				return true, err
+			}
+		case response := <-responses:
+			if err := write(response); err != nil {
+				return true, err
+			}
+		case <-done:
+			return true, readErr
+		case <-ctx.Done():
+			// Cleanly close the connection by sending a close message and then
+			// waiting (with timeout) for the server to close the connection.
+			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			if err != nil {
+				logger.Logger().Errorw("failed to close the config websocket", "error", err)
+				return true, err
+			}
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+			}
+			return true, nil
+		}
+	}
+}
+
+// handleMessage answers PINGs right away on pongs, unless the connection is
+// stopped, and queues everything else for the worker pool.
+func (s *Session) handleMessage(message []byte, pongs chan<- *s_response, stopped <-chan struct{}) {
+	configMessage := &ConfigMessage{}
+	err := json.Unmarshal(message, configMessage)
+	if err != nil {
+		logger.Logger().Errorw("failed to unmarshal config message", "error", err)
+	} else {
+		// Handle PING message immediately
+		if configMessage.ConfigKey == PING {
+			logger.Logger().Debugw("recv PING, responding with PONG", "request_id", configMessage.RequestID)
+			select {
+			case pongs <- &s_response{RequestId: configMessage.RequestID, Data: "pong"}:
+			case <-stopped:
+			}
+			return
+		}
+
+		messages <- configMessage
+	}
+
+	if configMessage.ConfigKey == SAVE_VIDEO_ENGINE {
+		type msg struct {
+			VideoEngine                     string                          `json:"video_engine"`
+			VideoEngineAdditionalProperties VideoEngineAdditionalProperties `json:"video_engine_additional_properties"`
+		}
+
+		var msgData msg
+		err = json.Unmarshal([]byte(configMessage.Value), &msgData)
+		if err != nil {
+			logger.Logger().Errorw("failed to unmarshal video engine", "request_id", configMessage.RequestID, "error", err)
+		} else {
+			if s.params.SetVideoEngineID != nil {
+				s.params.SetVideoEngineID(msgData.VideoEngine, msgData.VideoEngineAdditionalProperties)
+			}
+		}
+	}
+
+	logger.Logger().Debugw("config message received", "request_id", configMessage.RequestID, "config_key", configMessage.ConfigKey, "message", string(message))
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+func pingAndExpectPong(t *testing.T, ws *websocket.Conn, requestID string) {
+	t.Helper()
+	require.NoError(t, ws.WriteJSON(ConfigMessage{ConfigKey: PING, RequestID: requestID}))
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var resp s_response
+	require.NoError(t, ws.ReadJSON(&resp))
+	assert.Equal(t, requestID, resp.RequestId)
+	assert.Equal(t, "pong", resp.Data)
+}
+
+func TestSession_reconnectsAfterConnectionLoss(t *testing.T) {
+	var connections atomic.Int32
+	secondServed := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		n := connections.Add(1)
+		pingAndExpectPong(t, ws, fmt.Sprintf("req-%d", n))
+		if n == 1 {
+			// Drop the first connection, as a restarting hub would.
+			ws.Close()
+			return
+		}
+		close(secondServed)
+		// Keep the second one open until the client closes it.
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	var states []httpx.ConnectionState
+	session := NewSession(SessionParams{
+		Host:    srv.URL,
+		Backoff: httpx.Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2},
+		OnConnectionStateChange: func(change httpx.ConnectionStateChange) {
+			states = append(states, change.State)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- session.Run(ctx) }()
+
+	select {
+	case <-secondServed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not reconnect")
+	}
+	assert.Equal(t, httpx.ConnectionStateConnected, session.State())
+
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	assert.Equal(t, httpx.ConnectionStateDisconnected, session.State())
+	assert.Contains(t, states, httpx.ConnectionStateDisconnected)
+	assert.GreaterOrEqual(t, connections.Load(), int32(2))
+}
+
+func TestSession_retriesWhileHubIsDown(t *testing.T) {
+	var attempts atomic.Int32
+	session := NewSession(SessionParams{
+		// Nothing listens on this port.
+		Host:    "http://127.0.0.1:1",
+		Backoff: httpx.Backoff{Initial: 5 * time.Millisecond, Max: 10 * time.Millisecond, Multiplier: 2},
+		OnConnectionStateChange: func(change httpx.ConnectionStateChange) {
+			if change.State == httpx.ConnectionStateDisconnected && change.Err != nil {
+				attempts.Add(1)
+			}
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, session.Run(ctx))
+	assert.Greater(t, attempts.Load(), int32(1), "the session should keep retrying instead of exiting")
+}
+
+func TestSession_dropsPongOfStoppedConnection(t *testing.T) {
+	session := NewSession(SessionParams{})
+	ping, err := json.Marshal(ConfigMessage{ConfigKey: PING, RequestID: "req-1"})
+	require.NoError(t, err)
+	pongs := make(chan *s_response)
+	stopped := make(chan struct{})
+	close(stopped)
+
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		session.handleMessage(ping, pongs, stopped)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the reader blocked on a PONG nobody will write")
+	}
+	select {
+	case resp := <-responses:
+		t.Fatalf("the PONG leaked to the shared responses: %+v", resp)
+	default:
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+// Typed config keys: each known NetsocsConfigKey bound to the request and
+// response schemas of schemas.go, for use with Handle. Keys whose payload
+// has no schema in the SDK (actions such as ACTION_PING_DEVICE) are missing
+// here; register them with AddConfigHandler or declare them with NewKey.
+var (
+	KeyActionAlarmArmPartition            = NewKey[ActionAlarmArmPartitionRequest, Empty](ACTION_ALARM_ARM_PARTITION)
+	KeyActionAlarmDisarmPartition         = NewKey[ActionAlarmDisarmPartitionRequest, Empty](ACTION_ALARM_DISARM_PARTITION)
+	KeyActionAlarmFAPPartition            = NewKey[ActionAlarmFAPPartitionRequest, Empty](ACTION_ALARM_FAP_PARTITION)
+	KeyActionPlayAudioClip                = NewKey[ActionPlayAudioClipRequest, Empty](ACTION_PLAY_AUDIO_CLIP)
+	KeyActionZoom                         = NewKey[ActionZoomRequest, Empty](ACTION_ZOOM)
+	KeyActionPTZGotoPreset                = NewKey[ActionPTZGotoPresetRequest, Empty](ACTION_PTZ_GOTO_PRESET)
+	KeyDeleteAllPeopleAC                  = NewKey[DeleteAllPeopleACRequest, Empty](DELETE_ALL_PEOPLE_AC)
+	KeyDeleteUser                         = NewKey[DeleteUserRequest, Empty](DELETE_USER)
+	KeyGetAlarmArmStates                  = NewKey[Empty, GetAlarmArmStatesResponse](GET_ALARM_ARM_STATES)
+	KeyGetAlarmFAPStates                  = NewKey[Empty, GetAlarmFAPStatesResponse](GET_ALARM_FAP_STATES)
+	KeyGetAlarmPartitionZones             = NewKey[GetAlarmPartitionZonesRequest, GetAlarmPartitionZonesResponse](GET_ALARM_PARTITION_ZONES)
+	KeyGetAlarmPartitions                 = NewKey[Empty, GetAlarmPartitionsResponse](GET_ALARM_PARTITIONS)
+	KeyGetAlarmUsers                      = NewKey[Empty, GetAlarmUsersResponse](GET_ALARM_USERS)
+	KeySetBackgroundImage                 = NewKey[SetBackgroundImageRequest, Empty](SET_BACKGROUND_IMAGE)
+	KeyGetAlarmZoneStatus                 = NewKey[GetAlarmZoneStatusRequest, GetAlarmZoneStatusResponse](GET_ALARM_ZONE_STATUS)
+	KeyGetAlarmZones                      = NewKey[Empty, GetAlarmZonesResponse](GET_ALARM_ZONES)
+	KeyGetAllPeopleFromAC                 = NewKey[GetAllPeopleFromACRequest, GetAllPeopleFromACResponse](GET_ALL_PEOPLE_FROM_AC)
+	KeyGetAvailableOutputs                = NewKey[GetAvailableOutputsRequest, GetAvailableOutputsResponse](GET_AVAILABLE_OUTPUTS)
+	KeyGetAvailableSpeakers               = NewKey[GetAvailableSpeakersRequest, GetAvailableSpeakersResponse](GET_AVAILABLE_SPEAKERS)
+	KeyGetAvailableVideoResolutions       = NewKey[GetAvailableVideoResolutionsRequest, GetAvailableVideoResolutionsResponse](GET_AVAILABLE_VIDEO_RESOLUTIONS)
+	KeyGetChannels                        = NewKey[Empty, GetChannelResponse](GET_CHANNELS)
+	KeyGetCurrentVideoResolutionByChannel = NewKey[GetCurrentVideoResolutionByChannelRequest, GetCurrentVideoResolutionByChannelResponse](GET_CURRENT_VIDEO_RESOLUTION_BY_CHANNEL)
+	KeyGetFlipVideoStatus                 = NewKey[GetFlipVideoStatusRequest, GetFlipVideoStatusResponse](GET_FLIP_VIDEO_STATUS)
+	KeyGetFtpInfo                         = NewKey[GetFtpInfoRequest, GetFtpInfoResponse](GET_FTP_INFO)
+	KeyGetHeatmapImage                    = NewKey[GetHeatmapImageRequest, GetHeatmapImageResponse](GET_HEATMAP_IMAGE)
+	KeyGetInputs                          = NewKey[Empty, GetInputsResponse](GET_INPUTS)
+	KeyGetMicrophones                     = NewKey[Empty, GetMicrophoneResponse](GET_MICROPHONES)
+	KeyGetMirrorVideoStatus               = NewKey[GetMirrorVideoStatusRequest, GetMirrorVideoStatusResponse](GET_MIRROR_VIDEO_STATUS)
+	KeyGetRecordingSource                 = NewKey[GetRecordingSourceRequest, GetRecordingSourceResponse](GET_RECORDING_SOURCE)
+	KeyGetRecordingRanges                 = NewKey[GetRecordingRangesRequest, GetRecordingRangesResponse](GET_RECORDING_RANGES)
+	KeyGetStorages                        = NewKey[GetStorageRequest, GetStorageResponse](GET_STORAGES)
+	KeyGetUsers                           = NewKey[GetUserRequest, GetUserResponse](GET_USERS)
+	KeyGetVideoInBlackAndWhiteStatus      = NewKey[GetVideoInBlackAndWhiteStatusRequest, GetVideoInBlackAndWhiteStatusResponse](GET_VIDEO_IN_BLACK_AND_WHITE_STATUS)
+	KeySetActionUnlockDevice              = NewKey[SetActionUnlockDeviceRequest, Empty](SET_ACTION_UNLOCK_DEVICE)
+	KeyGetUnlockDeviceStatus              = NewKey[GetUnlockDeviceStatusRequest, GetUnlockDeviceStatusResponse](GET_UNLOCK_DEVICE_STATUS)
+	KeyGetSubdevices                      = NewKey[GetSubdevicesRequest, GetSubdevicesResponse](GET_SUBDEVICES)
+	KeyGetExtraDeviceFields               = NewKey[Empty, GetDeviceExtraFieldsResponse](GET_EXTRA_DEVICE_FIELDS)
+	KeyGetDiscoveredDevices               = NewKey[GetDiscoveredDevicesRequest, GetDiscoveredDevicesResponse](GET_DISCOVERED_DEVICES)
+	KeySetAddAlarmPartitionZone           = NewKey[SetAddAlarmPartitionZoneRequest, Empty](SET_ADD_ALARM_PARTITION_ZONE)
+	KeySetAddAlarmUser                    = NewKey[SetAddAlarmUserRequest, Empty](SET_ADD_ALARM_USER)
+	KeySetAddPersonToAC                   = NewKey[SetAddPersonToACRequest, Empty](SET_ADD_PERSON_TO_AC)
+	KeySetAlarmPartition                  = NewKey[SetAlarmPartitionRequest, Empty](SET_ALARM_PARTITION)
+	KeySetAlarmUser                       = NewKey[SetAlarmUserRequest, Empty](SET_ALARM_USER)
+	KeySetAlarmZone                       = NewKey[SetAlarmZoneRequest, Empty](SET_ALARM_ZONE)
+	KeySetAlarmPartitionZoneBypass        = NewKey[SetAlarmPartitionZoneBypassRequest, Empty](SET_ALARM_PARTITION_ZONE_BYPASSS)
+	KeySetBlockPersonToAC                 = NewKey[SetBlockPersonToACRequest, Empty](SET_BLOCK_PERSON_TO_AC)
+	KeySetCardToPersonAC                  = NewKey[SetCardToPersonACRequest, Empty](SET_CARD_TO_PERSON_AC)
+	KeySetDelPersonToAC                   = NewKey[SetDelToPersonToACRequest, Empty](SET_DEL_PERSON_TO_AC)
+	KeySetDeleteAlarmPartitionZone        = NewKey[SetDeleteAlarmPartitionZoneRequest, Empty](SET_DELETE_ALARM_PARTITION_ZONE)
+	KeySetFaceToPersonAC                  = NewKey[SetFaceToPersonACRequest, Empty](SET_FACE_TO_PERSON_AC)
+	KeySetFlipVideo                       = NewKey[SetFlipVideoRequest, Empty](SET_FLIP_VIDEO)
+	KeySetFtpInfo                         = NewKey[SetFtpInfoRequest, Empty](SET_FTP_INFO)
+	KeySetMirrorVideo                     = NewKey[SetMirrorVideoRequest, Empty](SET_MIRROR_VIDEO)
+	KeySetUsers                           = NewKey[SetUserRequest, Empty](SET_USERS)
+	KeySetVideoInBlackAndWhite            = NewKey[SetVideoInBlackAndWhiteRequest, Empty](SET_VIDEO_IN_BLACK_AND_WHITE)
+	KeySetVideoResolution                 = NewKey[SetVideoResolutionRequest, Empty](SET_VIDEO_RESOLUTION)
+	KeySetQRToPersonAC                    = NewKey[SetQRToPersonACRequest, Empty](SET_QR_TO_PERSON_AC)
+	KeyGetEventsAvailable                 = NewKey[GetEventsAvailableRequest, GetEventsAvailableResponse](GET_EVENTS_AVAILABLE)
+	KeyGetPeopleCounting                  = NewKey[GetPeopleCountingRequest, GetPeopleCountingResponse](GET_PEOPLE_COUNTING)
+)
@@ -0,0 +1,44 @@
+package config
+
+import "errors"
+
+// Validate implements Validator.
+func (r GetRecordingSourceRequest) Validate() error {
+	if r.ChannelNumber == "" {
+		return errors.New("channelNumber is required")
+	}
+	if r.UTCStart == "" {
+		return errors.New("utcStart is required")
+	}
+	return nil
+}
+
+// Validate implements Validator.
+func (r GetRecordingRangesRequest) Validate() error {
+	if r.ChannelNumber == "" {
+		return errors.New("channelNumber is required")
+	}
+	return nil
+}
+
+// Validate implements Validator.
+func (r ActionZoomRequest) Validate() error {
+	if r.ChannelNumber == "" {
+		return errors.New("channelNumber is required")
+	}
+	if r.ZoomDirection != ZoomIn && r.ZoomDirection != ZoomOut {
+		return errors.New("zoomDirection must be 0 (in) or 1 (out)")
+	}
+	return nil
+}
+
+// Validate implements Validator.
+func (r ActionPTZGotoPresetRequest) Validate() error {
+	if r.ChannelNumber == "" {
+		return errors.New("channelNumber is required")
+	}
+	if r.Preset.Token == "" {
+		return errors.New("preset.token is required")
+	}
+	return nil
+}
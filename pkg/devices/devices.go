@@ -0,0 +1,110 @@
+// Package devices runs the lifecycle of the devices a driver integrates: one
+// connection per device, its objects, its event loop, reconnection when the
+// connection drops, and the device state shown on the platform.
+//
+// Drivers used to copy a device manager from the template and wire it to the
+// requestCreateObjects and actionListenEvent config messages by hand. With
+// this package, a driver implements DeviceConnector for one device and hands
+// a ConnectorFactory to NewManager; the Manager creates a connector per
+// device configured on the platform and does the rest:
+//
+//   - Connect, then CreateObjects, reconciled with the DriverHub (see
+//     ManagedObjectRunner.Reconcile), then ListenEvents until the connection
+//     drops;
+//   - Online, Offline, AuthenticationFailure and ConfigurationFailure device
+//     states, sent on every transition;
+//   - reconnection with a jittered exponential backoff;
+//   - disconnection and cleanup of the objects when the device is removed.
+//
+// For devices probed rather than connected, HealthMonitor runs a Probe on a
+// schedule and reports the device state, debounced, from its outcome.
+package devices
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/client"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+)
+
+var (
+	// ErrAuthentication, wrapped in a Connect error, reports credentials the
+	// device rejected. The device state becomes AuthenticationFailure.
+	ErrAuthentication = errors.New("devices: authentication failed")
+	// ErrConfiguration, wrapped in a ConnectorFactory or Connect error,
+	// reports device settings the driver cannot use, such as a missing extra
+	// field. The device state becomes ConfigurationFailure.
+	ErrConfiguration = errors.New("devices: invalid configuration")
+)
+
+// Device is a device configured on the platform, as the config messages
+// describe it.
+type Device struct {
+	ID          int
+	ChildID     string
+	Name        string
+	IP          string
+	Port        int
+	IsSSL       bool
+	SSLPort     int
+	Username    string
+	Password    string
+	Extrafields map[string]interface{}
+}
+
+// DeviceFromConfig returns the Device described by the data of a config
+// message.
+func DeviceFromConfig(data *config.ConfigMessageDeviceData) Device {
+	return Device{
+		ID:          data.ID,
+		ChildID:     data.ChildID,
+		Name:        data.Name,
+		IP:          data.IP,
+		Port:        data.Port,
+		IsSSL:       data.IsSSL,
+		SSLPort:     data.SSLPort,
+		Username:    data.Username,
+		Password:    data.Password,
+		Extrafields: data.Extrafields,
+	}
+}
+
+// ObjectDeviceID is the device ID in the format of ObjectMetadata.DeviceID.
+// The objects returned by CreateObjects must carry it.
+func (d Device) ObjectDeviceID() string {
+	return strconv.Itoa(d.ID)
+}
+
+// DeviceConnector is implemented by the driver for one device. The Manager
+// calls Connect, CreateObjects, ListenEvents and Disconnect in that order,
+// again on every reconnection, and never two of them at the same time, except
+// CreateObjects, which a new requestCreateObjects may call while ListenEvents
+// runs.
+type DeviceConnector interface {
+	// Connect opens the connection to the device. Wrap ErrAuthentication or
+	// ErrConfiguration in the error when they apply.
+	Connect(ctx context.Context) error
+	// CreateObjects returns the objects the device exposes, each with
+	// ObjectDeviceID as DeviceID.
+	CreateObjects(ctx context.Context) ([]objects.RegistrableObject, error)
+	// ListenEvents receives the events of the device until ctx is done, and
+	// returns when the connection is lost.
+	ListenEvents(ctx context.Context) error
+	// Disconnect closes the connection.
+	Disconnect(ctx context.Context) error
+}
+
+// ConnectorFactory returns the connector of a device. It is called once per
+// device, and again when the platform changes its settings.
+type ConnectorFactory func(device Device) (DeviceConnector, error)
+
+// DriverClient is the part of the SDK client the Manager uses.
+// *client.NetsocsDriverClient implements it.
+type DriverClient interface {
+	AddConfigHandler(configKey config.NetsocsConfigKey, configHandler config.FuncConfigHandler) error
+	Reconcile(deviceID string, desired []objects.RegistrableObject, opts ...objects.ReconcileOptions) (objects.ReconcileResult, error)
+	SetDeviceState(deviceId int, state client.DeviceState) (client.DeviceStateResponse, error)
+}
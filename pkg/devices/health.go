@@ -0,0 +1,378 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/client"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"go.uber.org/zap"
+)
+
+// Probe checks that a device answers, e.g. by logging in or reading its
+// clock. It returns nil for a healthy device; wrap ErrAuthentication or
+// ErrConfiguration in the error when they apply.
+type Probe func(ctx context.Context, device Device) error
+
+// PingHandler returns the ACTION_PING_DEVICE handler running probe, so the
+// platform ping and the HealthMonitor check devices the same way.
+func PingHandler(probe Probe, timeout time.Duration) config.FuncConfigHandler {
+	return func(value config.HandlerValue) (interface{}, error) {
+		if value.DeviceData == nil {
+			return nil, errors.New("the config message carries no device")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := runProbe(ctx, probe, DeviceFromConfig(value.DeviceData)); err != nil {
+			return map[string]interface{}{
+				"status": false,
+				"error":  true,
+				"msg":    fmt.Sprintf("Device unreachable: %v", err),
+			}, nil
+		}
+		return map[string]interface{}{
+			"status": true,
+			"error":  false,
+			"msg":    "Device is online",
+		}, nil
+	}
+}
+
+func runProbe(ctx context.Context, probe Probe, device Device) (err error) {
+	if panicErr := recovery.Guard(recovery.BoundaryDevice, func() { err = probe(ctx, device) }); panicErr != nil {
+		return panicErr
+	}
+	return err
+}
+
+// stateOf is the device state a probe outcome points to.
+func stateOf(err error) client.DeviceState {
+	switch {
+	case err == nil:
+		return client.DeviceStateOnline
+	case errors.Is(err, ErrAuthentication):
+		return client.DeviceStateAuthenticationFailure
+	case errors.Is(err, ErrConfiguration):
+		return client.DeviceStateConfigurationFailure
+	}
+	return client.DeviceStateOffline
+}
+
+// ObjectPolicy is what the HealthMonitor does to the objects of a device
+// when it goes down or comes back.
+type ObjectPolicy string
+
+const (
+	// ObjectsUnchanged leaves the objects alone.
+	ObjectsUnchanged ObjectPolicy = ""
+	// ObjectsDisable disables the objects of a device that is down, and
+	// enables them again when it is back online.
+	ObjectsDisable ObjectPolicy = "disable"
+	// ObjectsOfflineAttribute sets the "offline" state attribute of the
+	// objects to "true" while the device is down, like OctopusObject.SetOffline.
+	ObjectsOfflineAttribute ObjectPolicy = "offline_attribute"
+)
+
+// Defaults of HealthOptions.
+const (
+	DefaultHealthInterval    = 30 * time.Second
+	DefaultProbeTimeout      = 10 * time.Second
+	DefaultFailureThreshold  = 3
+	DefaultRecoveryThreshold = 2
+)
+
+// HealthOptions configures a HealthMonitor. Zero fields take the defaults.
+type HealthOptions struct {
+	// Interval is the time between two probes of a device.
+	Interval time.Duration
+	// Timeout bounds a probe.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed probes after which
+	// an online device is reported down.
+	FailureThreshold int
+	// RecoveryThreshold is the number of consecutive successful probes after
+	// which a device that is down is reported online.
+	RecoveryThreshold int
+	// Objects is what happens to the registered objects of a device on the
+	// transitions between online and down.
+	Objects ObjectPolicy
+}
+
+// HealthClient is the part of the SDK client the HealthMonitor uses.
+// *client.NetsocsDriverClient implements it.
+type HealthClient interface {
+	SetDeviceState(deviceId int, state client.DeviceState) (client.DeviceStateResponse, error)
+	DeviceObjects(deviceID string) []objects.RegistrableObject
+	GetController() objects.ObjectController
+}
+
+var deviceProbes = metrics.NewCounterVec("driver_device_probes_total",
+	"Health probes of devices, by outcome (ok or failed).", "outcome")
+
+// HealthMonitor probes devices on a schedule and reports their state to the
+// platform. A state is reported when the probes agree on it for the
+// threshold of the options, so a single lost probe does not take a device
+// offline and a flapping device does not flood the platform with
+// transitions. It is safe for concurrent use.
+type HealthMonitor struct {
+	client HealthClient
+	probe  Probe
+	opts   HealthOptions
+
+	mu      sync.Mutex
+	watched map[int]*watchedDevice
+	closed  bool
+}
+
+// NewHealthMonitor returns a HealthMonitor checking devices with probe. Add
+// devices to it with Watch.
+func NewHealthMonitor(c HealthClient, probe Probe, opts HealthOptions) *HealthMonitor {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultHealthInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultProbeTimeout
+	}
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.RecoveryThreshold < 1 {
+		opts.RecoveryThreshold = DefaultRecoveryThreshold
+	}
+	return &HealthMonitor{client: c, probe: probe, opts: opts, watched: make(map[int]*watchedDevice)}
+}
+
+// Watch starts probing device, at once and then every Interval. Watching a
+// device again updates its settings and keeps its reported state.
+func (h *HealthMonitor) Watch(device Device) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	if w, ok := h.watched[device.ID]; ok {
+		w.mu.Lock()
+		w.device = device
+		w.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watchedDevice{
+		monitor: h,
+		device:  device,
+		log:     logger.Logger().With("device_id", device.ID, "device_name", device.Name),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	h.watched[device.ID] = w
+	go w.run(ctx)
+}
+
+// Unwatch stops probing a device. Its state and objects are left as they
+// are.
+func (h *HealthMonitor) Unwatch(deviceID int) {
+	h.mu.Lock()
+	w := h.watched[deviceID]
+	delete(h.watched, deviceID)
+	h.mu.Unlock()
+	if w != nil {
+		w.cancel()
+		<-w.done
+	}
+}
+
+// Shutdown stops probing every device. The monitor cannot be used after.
+func (h *HealthMonitor) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	watched := h.watched
+	h.watched = map[int]*watchedDevice{}
+	h.mu.Unlock()
+	for _, w := range watched {
+		w.cancel()
+	}
+	for _, w := range watched {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// DeviceHealth is a snapshot of a watched device.
+type DeviceHealth struct {
+	Device Device
+	// State is the last state reported; empty before the first probe.
+	State client.DeviceState
+	// LastProbe is when the last probe ended, and LastError its error.
+	LastProbe time.Time
+	LastError error
+	// Streak counts the consecutive probes pointing to the same state, which
+	// may differ from State while a transition is pending.
+	Streak int
+}
+
+// Health returns the health of the watched devices, by ID.
+func (h *HealthMonitor) Health() []DeviceHealth {
+	h.mu.Lock()
+	health := make([]DeviceHealth, 0, len(h.watched))
+	for _, w := range h.watched {
+		health = append(health, w.health())
+	}
+	h.mu.Unlock()
+	sort.Slice(health, func(i, j int) bool { return health[i].Device.ID < health[j].Device.ID })
+	return health
+}
+
+// watchedDevice is the probe loop of one device.
+type watchedDevice struct {
+	monitor *HealthMonitor
+	log     *zap.SugaredLogger
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu        sync.Mutex
+	device    Device
+	reported  client.DeviceState
+	candidate client.DeviceState
+	streak    int
+	lastProbe time.Time
+	lastErr   error
+}
+
+func (w *watchedDevice) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.monitor.opts.Interval)
+	defer ticker.Stop()
+	for {
+		w.check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check runs one probe and reports the state it settles.
+func (w *watchedDevice) check(ctx context.Context) {
+	w.mu.Lock()
+	device := w.device
+	w.mu.Unlock()
+
+	probeCtx, cancel := context.WithTimeout(ctx, w.monitor.opts.Timeout)
+	err := runProbe(probeCtx, w.monitor.probe, device)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		deviceProbes.Inc("failed")
+	} else {
+		deviceProbes.Inc("ok")
+	}
+
+	state, previous, ok := w.observe(err)
+	if !ok {
+		return
+	}
+	w.log.Infow("device health changed", "state", string(state), "previous", string(previous), "error", err)
+	if reportErr := w.monitor.report(device, previous, state); reportErr != nil {
+		w.log.Warnw("failed to report the device state, retrying after the next probe", "state", string(state), "error", reportErr)
+		return
+	}
+	w.commit(state)
+}
+
+// observe records a probe outcome, and returns the state to report when the
+// probes settled on a new one. The first probe settles the state at once. The
+// state only counts as reported once committed, so a report that failed is
+// sent again after the next probe.
+func (w *watchedDevice) observe(err error) (state, previous client.DeviceState, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastProbe = time.Now()
+	w.lastErr = err
+	state = stateOf(err)
+	if state == w.candidate {
+		w.streak++
+	} else {
+		w.candidate = state
+		w.streak = 1
+	}
+	threshold := w.monitor.opts.FailureThreshold
+	if state == client.DeviceStateOnline {
+		threshold = w.monitor.opts.RecoveryThreshold
+	}
+	if state == w.reported || (w.reported != "" && w.streak < threshold) {
+		return "", "", false
+	}
+	return state, w.reported, true
+}
+
+// commit records state as reported to the platform.
+func (w *watchedDevice) commit(state client.DeviceState) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reported = state
+}
+
+func (w *watchedDevice) health() DeviceHealth {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return DeviceHealth{
+		Device:    w.device,
+		State:     w.reported,
+		LastProbe: w.lastProbe,
+		LastError: w.lastErr,
+		Streak:    w.streak,
+	}
+}
+
+// report sends a transition to the platform and, once it is accepted,
+// applies the object policy.
+func (h *HealthMonitor) report(device Device, previous, state client.DeviceState) error {
+	if _, err := h.client.SetDeviceState(device.ID, state); err != nil {
+		return err
+	}
+	online := state == client.DeviceStateOnline
+	if previous != "" && (previous == client.DeviceStateOnline) == online {
+		// From one failure to another: the objects are already down.
+		return nil
+	}
+	h.applyObjects(device, online)
+	return nil
+}
+
+func (h *HealthMonitor) applyObjects(device Device, online bool) {
+	if h.opts.Objects == ObjectsUnchanged {
+		return
+	}
+	controller := h.client.GetController()
+	for _, obj := range h.client.DeviceObjects(device.ObjectDeviceID()) {
+		id := obj.GetMetadata().ObjectID
+		var err error
+		switch {
+		case h.opts.Objects == ObjectsDisable && online:
+			err = controller.EnabledObject(id)
+		case h.opts.Objects == ObjectsDisable:
+			err = controller.DisabledObject(id)
+		case h.opts.Objects == ObjectsOfflineAttribute:
+			err = controller.UpdateStateAttributes(id, map[string]string{"offline": strconv.FormatBool(!online)})
+		}
+		if err != nil {
+			logger.Logger().Warnw("failed to update an object of the device", "device_id", device.ID, "object_id", id, "error", err)
+		}
+	}
+}
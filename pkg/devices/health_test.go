@@ -0,0 +1,163 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/client"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/drivertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchedDevice_observe(t *testing.T) {
+	w := &watchedDevice{monitor: NewHealthMonitor(nil, nil, HealthOptions{FailureThreshold: 3, RecoveryThreshold: 2})}
+	down := errors.New("timeout")
+	steps := []struct {
+		err  error
+		want client.DeviceState // empty: nothing reported
+	}{
+		{nil, client.DeviceStateOnline}, // the first probe settles the state
+		{down, ""},
+		{nil, ""}, // a lost probe is forgotten
+		{down, ""},
+		{down, ""},
+		{down, client.DeviceStateOffline},
+		{fmt.Errorf("login: %w", ErrAuthentication), ""},
+		{fmt.Errorf("login: %w", ErrAuthentication), ""},
+		{fmt.Errorf("login: %w", ErrAuthentication), client.DeviceStateAuthenticationFailure},
+		{nil, ""},
+		{nil, client.DeviceStateOnline},
+	}
+	for i, step := range steps {
+		state, _, ok := w.observe(step.err)
+		assert.Equal(t, step.want != "", ok, "step %d", i)
+		assert.Equal(t, step.want, state, "step %d", i)
+		if ok {
+			w.commit(state)
+		}
+	}
+
+	// Until committed, the state is reported again after every probe.
+	for i := 0; i < 2; i++ {
+		_, _, ok := w.observe(down)
+		assert.False(t, ok)
+	}
+	for i := 0; i < 2; i++ {
+		state, previous, ok := w.observe(down)
+		assert.True(t, ok)
+		assert.Equal(t, client.DeviceStateOffline, state)
+		assert.Equal(t, client.DeviceStateOnline, previous)
+	}
+	w.commit(client.DeviceStateOffline)
+	_, _, ok := w.observe(down)
+	assert.False(t, ok)
+}
+
+// flakyHealthClient fails the first device state reports.
+type flakyHealthClient struct {
+	HealthClient
+	mu       sync.Mutex
+	failures int
+	states   []client.DeviceState
+}
+
+func (c *flakyHealthClient) SetDeviceState(deviceID int, state client.DeviceState) (client.DeviceStateResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		return client.DeviceStateResponse{}, errors.New("driverhub unavailable")
+	}
+	c.states = append(c.states, state)
+	return client.DeviceStateResponse{}, nil
+}
+
+func (c *flakyHealthClient) reported() []client.DeviceState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]client.DeviceState(nil), c.states...)
+}
+
+func TestHealthMonitor_retriesFailedReports(t *testing.T) {
+	c := &flakyHealthClient{failures: 2}
+	m := NewHealthMonitor(c, func(context.Context, Device) error { return nil }, HealthOptions{Interval: 10 * time.Millisecond})
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	m.Watch(Device{ID: 9})
+
+	require.Eventually(t, func() bool { return len(c.reported()) > 0 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []client.DeviceState{client.DeviceStateOnline}, c.reported(), "sent until accepted, then once")
+}
+
+// switchProbe fails with err until it is changed.
+type switchProbe struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *switchProbe) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *switchProbe) probe(ctx context.Context, device Device) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func TestHealthMonitor(t *testing.T) {
+	hub := drivertest.NewHub(t)
+	c := newTestClient(t, hub)
+	device := Device{ID: 9, Name: "Panel"}
+	objs, err := newFakeConnector(device).CreateObjects(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, c.RegisterObject(objs[0]))
+
+	p := &switchProbe{}
+	m := NewHealthMonitor(c, p.probe, HealthOptions{Interval: 10 * time.Millisecond, FailureThreshold: 2, RecoveryThreshold: 2, Objects: ObjectsDisable})
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	m.Watch(device)
+	require.NoError(t, hub.WaitForDeviceState(9, string(client.DeviceStateOnline), 5*time.Second))
+
+	p.set(errors.New("connection refused"))
+	require.NoError(t, hub.WaitForDeviceState(9, string(client.DeviceStateOffline), 5*time.Second))
+	require.Eventually(t, func() bool { return !hub.Enabled("switch.9.1") }, 5*time.Second, 10*time.Millisecond,
+		"the objects of a device that is down are disabled")
+
+	p.set(nil)
+	require.NoError(t, hub.WaitForDeviceState(9, string(client.DeviceStateOnline), 5*time.Second))
+	require.Eventually(t, func() bool { return hub.Enabled("switch.9.1") }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"Online", "Offline", "Online"}, hub.DeviceStateHistory(9))
+
+	health := m.Health()
+	require.Len(t, health, 1)
+	assert.Equal(t, client.DeviceStateOnline, health[0].State)
+	m.Unwatch(9)
+	assert.Empty(t, m.Health())
+}
+
+func TestPingHandler(t *testing.T) {
+	handler := PingHandler(func(ctx context.Context, device Device) error {
+		if device.Password != "secret" {
+			return fmt.Errorf("%w: bad password", ErrAuthentication)
+		}
+		return nil
+	}, time.Second)
+
+	reply, err := handler(config.HandlerValue{DeviceData: &config.ConfigMessageDeviceData{ID: 1, Password: "secret"}})
+	require.NoError(t, err)
+	assert.Equal(t, true, reply.(map[string]interface{})["status"])
+
+	reply, err = handler(config.HandlerValue{DeviceData: &config.ConfigMessageDeviceData{ID: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, false, reply.(map[string]interface{})["status"])
+	assert.Contains(t, reply.(map[string]interface{})["msg"], "bad password")
+}
@@ -0,0 +1,522 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/client"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"go.uber.org/zap"
+)
+
+// Defaults of Options.
+const (
+	DefaultConnectTimeout    = 30 * time.Second
+	DefaultDisconnectTimeout = 10 * time.Second
+)
+
+// DefaultReconnectBackoff returns the Reconnect used when Options leaves it
+// unset: 1s, doubling up to 1m, with 20% jitter.
+func DefaultReconnectBackoff() httpx.Backoff {
+	return httpx.Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Reconnect paces the connection attempts of a device that is down; the
+	// zero value means DefaultReconnectBackoff().
+	Reconnect httpx.Backoff
+	// ConnectTimeout bounds Connect and CreateObjects; 0 means
+	// DefaultConnectTimeout.
+	ConnectTimeout time.Duration
+	// DeleteStaleObjects deletes, instead of disabling, the objects a device
+	// no longer exposes and the objects of a removed device.
+	DeleteStaleObjects bool
+}
+
+var (
+	devicesByState = metrics.NewGaugeVec("driver_devices",
+		"Devices run by the device manager, by state (Online, Offline, AuthenticationFailure, ConfigurationFailure).",
+		"state")
+	deviceReconnects = metrics.NewCounterVec("driver_device_reconnects_total",
+		"Connection attempts of devices, by cause (failed, after a failed attempt, or lost, after a lost connection).",
+		"cause")
+)
+
+// Manager runs a DeviceConnector per device. It is safe for concurrent use.
+type Manager struct {
+	client  DriverClient
+	factory ConnectorFactory
+	opts    Options
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	devices map[int]*managedDevice
+	closed  bool
+}
+
+// NewManager returns a Manager creating the connector of each device with
+// factory. Call AddConfigHandlers to drive it from the platform.
+func NewManager(c DriverClient, factory ConnectorFactory, opts Options) *Manager {
+	if opts.Reconnect == (httpx.Backoff{}) {
+		opts.Reconnect = DefaultReconnectBackoff()
+	}
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DefaultConnectTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		client:  c,
+		factory: factory,
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		devices: make(map[int]*managedDevice),
+	}
+}
+
+// AddConfigHandlers registers the handlers of the config messages that
+// drive the devices, replacing any registered before:
+//
+//   - requestCreateObjects adds the device, or creates its objects again;
+//   - actionListenEvent adds the device if it is not running yet;
+//   - actionStopListenEvent removes it.
+func (m *Manager) AddConfigHandlers() error {
+	handlers := map[config.NetsocsConfigKey]config.FuncConfigHandler{
+		config.REQUEST_CREATE_OBJECTS:   m.handleAdd,
+		config.ACTION_LISTEN_EVENTS:     m.handleListen,
+		config.ACTION_STOP_LISTEN_EVENT: m.handleRemove,
+	}
+	for key, handler := range handlers {
+		if err := m.client.AddConfigHandler(key, handler); err != nil {
+			return fmt.Errorf("failed to register handler %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) handleAdd(value config.HandlerValue) (interface{}, error) {
+	if value.DeviceData == nil {
+		return nil, errors.New("the config message carries no device")
+	}
+	return nil, m.Add(DeviceFromConfig(value.DeviceData))
+}
+
+func (m *Manager) handleListen(value config.HandlerValue) (interface{}, error) {
+	if value.DeviceData == nil {
+		return nil, errors.New("the config message carries no device")
+	}
+	return nil, m.add(DeviceFromConfig(value.DeviceData), false)
+}
+
+func (m *Manager) handleRemove(value config.HandlerValue) (interface{}, error) {
+	if value.DeviceData == nil {
+		return nil, errors.New("the config message carries no device")
+	}
+	return nil, m.Remove(value.DeviceData.ID)
+}
+
+// Add starts running device, and returns once its first connection attempt
+// and its objects are done. A failed attempt is returned, and retried in the
+// background. For a device already running with the same settings, its
+// objects are created again; with other settings, it is restarted with a new
+// connector.
+func (m *Manager) Add(device Device) error {
+	return m.add(device, true)
+}
+
+// add starts device. For a device already running with the same settings, it
+// only refreshes its objects when refresh is set.
+func (m *Manager) add(device Device, refresh bool) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return errors.New("devices: the manager is shut down")
+	}
+	current := m.devices[device.ID]
+	if current != nil && reflect.DeepEqual(current.device, device) {
+		m.mu.Unlock()
+		if !refresh {
+			return nil
+		}
+		return current.refresh()
+	}
+	if current != nil {
+		delete(m.devices, device.ID)
+	}
+	m.mu.Unlock()
+	if current != nil {
+		current.stop()
+		current.release()
+	}
+
+	connector, err := m.factory(device)
+	if err != nil {
+		if !errors.Is(err, ErrConfiguration) {
+			err = fmt.Errorf("%w: %w", ErrConfiguration, err)
+		}
+		m.reportState(device.ID, client.DeviceStateConfigurationFailure)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	d := &managedDevice{
+		manager:   m,
+		device:    device,
+		connector: connector,
+		log:       logger.Logger().With("device_id", device.ID, "device_name", device.Name),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		retry:     make(chan struct{}, 1),
+		first:     make(chan error, 1),
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		cancel()
+		return errors.New("devices: the manager is shut down")
+	}
+	// Another add of the device may have run while the factory did.
+	replaced := m.devices[device.ID]
+	if replaced != nil && reflect.DeepEqual(replaced.device, device) {
+		// It runs the same settings: ours never started, so only its
+		// context needs cancelling.
+		m.mu.Unlock()
+		cancel()
+		if !refresh {
+			return nil
+		}
+		return replaced.refresh()
+	}
+	m.devices[device.ID] = d
+	m.mu.Unlock()
+	if replaced != nil {
+		replaced.stop()
+		replaced.release()
+	}
+
+	go d.run(ctx)
+	select {
+	case err := <-d.first:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Remove stops running a device: its connection is closed and its objects
+// are disabled, or deleted with DeleteStaleObjects. Removing an unknown
+// device does nothing.
+func (m *Manager) Remove(deviceID int) error {
+	m.mu.Lock()
+	d := m.devices[deviceID]
+	delete(m.devices, deviceID)
+	m.mu.Unlock()
+	if d == nil {
+		return nil
+	}
+	d.stop()
+	d.release()
+	_, err := m.client.Reconcile(d.device.ObjectDeviceID(), nil, objects.ReconcileOptions{DeleteStale: m.opts.DeleteStaleObjects})
+	return err
+}
+
+// Shutdown stops every device and closes its connection, leaving its objects
+// and state on the platform as they are. The manager cannot be used after.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	running := make([]*managedDevice, 0, len(m.devices))
+	for _, d := range m.devices {
+		running = append(running, d)
+	}
+	m.devices = map[int]*managedDevice{}
+	m.mu.Unlock()
+	m.cancel()
+
+	for _, d := range running {
+		select {
+		case <-d.done:
+			d.release()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Connector returns the connector of a running device, for config handlers
+// and actions that need its connection.
+func (m *Manager) Connector(deviceID int) (DeviceConnector, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.devices[deviceID]
+	if !ok {
+		return nil, false
+	}
+	return d.connector, true
+}
+
+// DeviceStatus is a snapshot of a running device.
+type DeviceStatus struct {
+	Device Device
+	State  client.DeviceState
+	// Objects is the number of objects of the last CreateObjects.
+	Objects int
+	// ConnectedAt is when the current connection opened; zero while down.
+	ConnectedAt time.Time
+	// Failures counts the connection attempts failed since the last
+	// successful one.
+	Failures  int
+	LastError error
+}
+
+// Devices returns the status of the running devices, by ID.
+func (m *Manager) Devices() []DeviceStatus {
+	m.mu.Lock()
+	running := make([]*managedDevice, 0, len(m.devices))
+	for _, d := range m.devices {
+		running = append(running, d)
+	}
+	m.mu.Unlock()
+	statuses := make([]DeviceStatus, 0, len(running))
+	for _, d := range running {
+		statuses = append(statuses, d.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Device.ID < statuses[j].Device.ID })
+	return statuses
+}
+
+// reportState sends a device state to the hub, logging failures.
+func (m *Manager) reportState(deviceID int, state client.DeviceState) {
+	if _, err := m.client.SetDeviceState(deviceID, state); err != nil {
+		logger.Logger().Warnw("failed to report the device state", "device_id", deviceID, "state", string(state), "error", err)
+	}
+}
+
+// managedDevice is the loop of one device.
+type managedDevice struct {
+	manager   *Manager
+	device    Device
+	connector DeviceConnector
+	log       *zap.SugaredLogger
+
+	cancel context.CancelFunc
+	done   chan struct{} // closed when run returns
+	retry  chan struct{} // skips the reconnection delay
+	first  chan error    // receives the outcome of the first attempt
+
+	mu          sync.Mutex
+	state       client.DeviceState
+	objects     int
+	connectedAt time.Time
+	failures    int
+	lastErr     error
+	firstSent   bool
+}
+
+func (d *managedDevice) run(ctx context.Context) {
+	defer close(d.done)
+	for {
+		connected, err := d.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		cause := "failed"
+		if connected {
+			cause = "lost"
+			d.log.Warnw("device connection lost", "error", err)
+		}
+		d.fail(err)
+
+		d.mu.Lock()
+		delay := d.manager.opts.Reconnect.Delay(d.failures - 1)
+		d.mu.Unlock()
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-d.retry:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		deviceReconnects.Inc(cause)
+	}
+}
+
+// session connects, creates the objects and listens to the device until the
+// connection drops. connected reports whether the objects were created.
+func (d *managedDevice) session(ctx context.Context) (connected bool, err error) {
+	timeout := d.manager.opts.ConnectTimeout
+	connectCtx, cancel := context.WithTimeout(ctx, timeout)
+	err = d.guard(func() error { return d.connector.Connect(connectCtx) })
+	cancel()
+	if err != nil {
+		return false, fmt.Errorf("connect: %w", err)
+	}
+	if err := d.createObjects(ctx); err != nil {
+		d.disconnect()
+		return false, err
+	}
+
+	d.mu.Lock()
+	d.connectedAt = time.Now()
+	d.failures = 0
+	d.lastErr = nil
+	d.mu.Unlock()
+	d.setState(client.DeviceStateOnline)
+	d.log.Infow("device online")
+	d.reportFirst(nil)
+
+	err = d.guard(func() error { return d.connector.ListenEvents(ctx) })
+	d.mu.Lock()
+	d.connectedAt = time.Time{}
+	d.mu.Unlock()
+	d.disconnect()
+	if err == nil {
+		err = errors.New("the event listener returned")
+	}
+	return true, err
+}
+
+// createObjects runs CreateObjects and reconciles its objects with the hub.
+func (d *managedDevice) createObjects(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, d.manager.opts.ConnectTimeout)
+	defer cancel()
+	var desired []objects.RegistrableObject
+	err := d.guard(func() (err error) {
+		desired, err = d.connector.CreateObjects(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("create objects: %w", err)
+	}
+	result, err := d.manager.client.Reconcile(d.device.ObjectDeviceID(), desired, objects.ReconcileOptions{DeleteStale: d.manager.opts.DeleteStaleObjects})
+	if err != nil {
+		return fmt.Errorf("reconcile objects: %w", err)
+	}
+	d.mu.Lock()
+	d.objects = len(desired)
+	d.mu.Unlock()
+	d.log.Infow("device objects reconciled", "objects", len(desired), "created", len(result.Created),
+		"updated", len(result.Updated), "disabled", len(result.Disabled), "deleted", len(result.Deleted), "registered", len(result.Registered))
+	return nil
+}
+
+// refresh creates the objects of a connected device again, or has a device
+// that is down retry now.
+func (d *managedDevice) refresh() error {
+	d.mu.Lock()
+	connected := !d.connectedAt.IsZero()
+	lastErr := d.lastErr
+	d.mu.Unlock()
+	if connected {
+		return d.createObjects(d.manager.ctx)
+	}
+	select {
+	case d.retry <- struct{}{}:
+	default:
+	}
+	return lastErr
+}
+
+// fail records a failed or lost connection.
+func (d *managedDevice) fail(err error) {
+	state := client.DeviceStateOffline
+	switch {
+	case errors.Is(err, ErrAuthentication):
+		state = client.DeviceStateAuthenticationFailure
+	case errors.Is(err, ErrConfiguration):
+		state = client.DeviceStateConfigurationFailure
+	}
+	d.mu.Lock()
+	d.failures++
+	d.lastErr = err
+	failures := d.failures
+	d.mu.Unlock()
+	d.setState(state)
+	if failures == 1 || failures%10 == 0 {
+		d.log.Warnw("device unreachable", "state", string(state), "failures", failures, "error", err)
+	}
+	d.reportFirst(err)
+}
+
+// setState reports a state transition to the hub.
+func (d *managedDevice) setState(state client.DeviceState) {
+	d.mu.Lock()
+	previous := d.state
+	d.state = state
+	d.mu.Unlock()
+	if previous == state {
+		return
+	}
+	if previous != "" {
+		devicesByState.Add(-1, string(previous))
+	}
+	devicesByState.Add(1, string(state))
+	d.manager.reportState(d.device.ID, state)
+}
+
+func (d *managedDevice) reportFirst(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.firstSent {
+		d.firstSent = true
+		d.first <- err
+	}
+}
+
+func (d *managedDevice) disconnect() {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDisconnectTimeout)
+	defer cancel()
+	if err := d.guard(func() error { return d.connector.Disconnect(ctx) }); err != nil {
+		d.log.Debugw("device disconnect failed", "error", err)
+	}
+}
+
+// guard calls a connector method, turning a panic into an error.
+func (d *managedDevice) guard(fn func() error) (err error) {
+	if panicErr := recovery.Guard(recovery.BoundaryDevice, func() { err = fn() }); panicErr != nil {
+		return panicErr
+	}
+	return err
+}
+
+// stop ends the loop and waits for it.
+func (d *managedDevice) stop() {
+	d.cancel()
+	<-d.done
+}
+
+// release takes a stopped device out of driver_devices.
+func (d *managedDevice) release() {
+	if state := d.status().State; state != "" {
+		devicesByState.Add(-1, string(state))
+	}
+}
+
+func (d *managedDevice) status() DeviceStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DeviceStatus{
+		Device:      d.device,
+		State:       d.state,
+		Objects:     d.objects,
+		ConnectedAt: d.connectedAt,
+		Failures:    d.failures,
+		LastError:   d.lastErr,
+	}
+}
@@ -0,0 +1,186 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/client"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/drivertest"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnector fails Connect with the errors queued in connectErrs, and
+// listens until drop receives.
+type fakeConnector struct {
+	device      Device
+	connectErrs chan error
+	drop        chan struct{}
+	connects    atomic.Int32
+	disconnects atomic.Int32
+}
+
+func newFakeConnector(device Device) *fakeConnector {
+	return &fakeConnector{device: device, connectErrs: make(chan error, 4), drop: make(chan struct{})}
+}
+
+func (f *fakeConnector) Connect(ctx context.Context) error {
+	f.connects.Add(1)
+	select {
+	case err := <-f.connectErrs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (f *fakeConnector) CreateObjects(ctx context.Context) ([]objects.RegistrableObject, error) {
+	id := fmt.Sprintf("switch.%d.1", f.device.ID)
+	return []objects.RegistrableObject{objects.NewSwitchObject(objects.NewSwitchObjectParams{
+		Metadata:      objects.ObjectMetadata{ObjectID: id, DeviceID: f.device.ObjectDeviceID(), Domain: "switch", Name: "Relay"},
+		TurnOnMethod:  func(objects.RegistrableObject, objects.ObjectController) error { return nil },
+		TurnOffMethod: func(objects.RegistrableObject, objects.ObjectController) error { return nil },
+	})}, nil
+}
+
+func (f *fakeConnector) ListenEvents(ctx context.Context) error {
+	select {
+	case <-f.drop:
+		return fmt.Errorf("connection reset")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *fakeConnector) Disconnect(ctx context.Context) error {
+	f.disconnects.Add(1)
+	return nil
+}
+
+func newTestClient(t *testing.T, hub *drivertest.Hub) *client.NetsocsDriverClient {
+	t.Helper()
+	c := client.NewNetsocsDriverClient("driver-key", hub.URL(), false)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		c.Shutdown(ctx)
+	})
+	return c
+}
+
+func testOptions() Options {
+	return Options{Reconnect: httpx.Backoff{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond, Multiplier: 1}}
+}
+
+func TestManager_lifecycle(t *testing.T) {
+	hub := drivertest.NewHub(t)
+	c := newTestClient(t, hub)
+	device := Device{ID: 7, Name: "Controller", IP: "10.0.0.7"}
+	connector := newFakeConnector(device)
+	connector.connectErrs <- fmt.Errorf("login: %w", ErrAuthentication)
+	m := NewManager(c, func(Device) (DeviceConnector, error) { return connector, nil }, testOptions())
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+
+	assert.ErrorIs(t, m.Add(device), ErrAuthentication, "the first attempt is returned")
+	require.NoError(t, hub.WaitForDeviceState(7, string(client.DeviceStateOnline), 5*time.Second))
+	assert.True(t, hub.Enabled("switch.7.1"))
+	status := m.Devices()
+	require.Len(t, status, 1)
+	assert.Equal(t, client.DeviceStateOnline, status[0].State)
+	assert.Equal(t, 1, status[0].Objects)
+	assert.False(t, status[0].ConnectedAt.IsZero())
+
+	// A lost connection is reported and reopened.
+	connector.drop <- struct{}{}
+	require.Eventually(t, func() bool { return len(hub.DeviceStateHistory(7)) == 4 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"AuthenticationFailure", "Online", "Offline", "Online"}, hub.DeviceStateHistory(7))
+
+	// The same device again only refreshes its objects.
+	require.NoError(t, m.Add(device))
+	assert.Equal(t, int32(3), connector.connects.Load())
+
+	require.NoError(t, m.Remove(7))
+	assert.False(t, hub.Enabled("switch.7.1"), "the objects of a removed device are disabled")
+	assert.Equal(t, int32(2), connector.disconnects.Load())
+	assert.Empty(t, m.Devices())
+	_, ok := m.Connector(7)
+	assert.False(t, ok)
+}
+
+func TestManager_concurrentAddsRunOneConnector(t *testing.T) {
+	hub := drivertest.NewHub(t)
+	c := newTestClient(t, hub)
+	device := Device{ID: 9, Name: "Controller", IP: "10.0.0.9"}
+	var (
+		entered    = make(chan struct{}, 2)
+		release    = make(chan struct{})
+		connectors = make(chan *fakeConnector, 2)
+	)
+	m := NewManager(c, func(device Device) (DeviceConnector, error) {
+		entered <- struct{}{}
+		<-release
+		connector := newFakeConnector(device)
+		connectors <- connector
+		return connector, nil
+	}, testOptions())
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- m.Add(device) }()
+	}
+	<-entered
+	<-entered
+	close(release)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	var connects int32
+	for i := 0; i < 2; i++ {
+		connects += (<-connectors).connects.Load()
+	}
+	assert.Equal(t, int32(1), connects, "the connector that lost the race must not run")
+	assert.Len(t, m.Devices(), 1)
+}
+
+func TestManager_configHandlers(t *testing.T) {
+	hub := drivertest.NewHub(t)
+	c := newTestClient(t, hub)
+	m := NewManager(c, func(device Device) (DeviceConnector, error) {
+		if _, ok := device.Extrafields["channel"]; !ok {
+			return nil, fmt.Errorf("%w: missing channel", ErrConfiguration)
+		}
+		return newFakeConnector(device), nil
+	}, Options{DeleteStaleObjects: true})
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	require.NoError(t, m.AddConfigHandlers())
+	go c.ListenConfig()
+	require.NoError(t, hub.WaitConfigConnected(5*time.Second))
+
+	data, err := hub.SendConfig(config.REQUEST_CREATE_OBJECTS, "", &config.ConfigMessageDeviceData{ID: 8}, 5*time.Second)
+	require.NoError(t, err)
+	assert.Contains(t, data, "missing channel")
+	state, _ := hub.DeviceState(8)
+	assert.Equal(t, string(client.DeviceStateConfigurationFailure), state)
+	assert.Empty(t, m.Devices(), "a device the factory rejects is not run")
+
+	device := &config.ConfigMessageDeviceData{ID: 8, Extrafields: map[string]interface{}{"channel": 1.0}}
+	_, err = hub.SendConfig(config.REQUEST_CREATE_OBJECTS, "", device, 5*time.Second)
+	require.NoError(t, err)
+	state, _ = hub.DeviceState(8)
+	assert.Equal(t, string(client.DeviceStateOnline), state)
+	_, err = hub.SendConfig(config.ACTION_LISTEN_EVENTS, "", device, 5*time.Second)
+	require.NoError(t, err)
+	assert.Len(t, m.Devices(), 1)
+
+	_, err = hub.SendConfig(config.ACTION_STOP_LISTEN_EVENT, "", device, 5*time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, m.Devices())
+	assert.Empty(t, hub.Objects(), "with DeleteStaleObjects, the objects are deleted")
+}
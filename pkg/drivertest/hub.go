@@ -0,0 +1,205 @@
+// Package drivertest provides an in-memory DriverHub for driver integration
+// tests.
+//
+// A Hub serves the REST endpoints the SDK uses (objects, actions, states,
+// events, groups, snapshot uploads, device states) and both websockets: objects/ws, which
+// delivers action executions, and ws/v1/config_communication, which delivers
+// config messages. Point a client at Hub.URL, drive it with ExecuteAction and
+// SendConfig, and assert on what the hub recorded:
+//
+//	hub := drivertest.NewHub(t)
+//	client := client.NewNetsocsDriverClient("key", hub.URL(), false)
+//	defer client.Shutdown(context.Background())
+//	// ... register objects ...
+//	require.NoError(t, hub.WaitForDomain("switch", time.Second))
+//	id, _ := hub.ExecuteAction(drivertest.ActionRequest{Domain: "switch", Action: "switch.action.turn_on"})
+//	result, err := hub.WaitResult(id, time.Second)
+//	state, _ := hub.State("switch.1")
+package drivertest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+)
+
+// ErrTimeout is returned by the Wait helpers when the condition is not met in
+// time.
+var ErrTimeout = errors.New("drivertest: timed out")
+
+// Object is an object registered through POST /objects.
+type Object struct {
+	ID               string   `json:"id"`
+	Domain           string   `json:"domain"`
+	Name             string   `json:"name"`
+	Tags             []string `json:"tags"`
+	Type             string   `json:"type"`
+	DeviceID         int      `json:"device_id"`
+	Enabled          bool     `json:"enabled"`
+	StatesAvailable  []string `json:"states_available"`
+	EventsAvailable  []string `json:"events_available"`
+	ActionsAvailable []string `json:"actions_available"`
+}
+
+// ActionResult is one result update received for an action execution. Status
+// is empty for controllers that only send UpdateResultAttributes.
+type ActionResult struct {
+	Status    string         `json:"status"`
+	Progress  int            `json:"progress"`
+	Message   string         `json:"message"`
+	Error     string         `json:"error"`
+	ErrorCode string         `json:"error_code"`
+	Result    map[string]any `json:"result"`
+}
+
+// Final reports whether the update ends the execution.
+func (r ActionResult) Final() bool {
+	return r.Status != string(objects.ActionStatusRunning) && r.Status != string(objects.ActionStatusQueued)
+}
+
+// Event is an event received on POST /objects/events.
+type Event struct {
+	ID string
+	objects.NewEventRequestBodySchema
+}
+
+// Upload is a file received on POST /snapshots/upload.
+type Upload struct {
+	Filename string
+	Name     string
+	Data     []byte
+}
+
+// Hub is an in-memory DriverHub. It is safe for concurrent use.
+type Hub struct {
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu           sync.Mutex
+	changed      *sync.Cond
+	objects      map[string]Object
+	disabled     map[string]bool
+	actions      []objects.ObjectAction
+	states       map[string]objects.State
+	stateHistory map[string][]objects.State
+	results      map[string][]ActionResult
+	events       []Event
+	eventKeys    map[string]string // idempotency key -> event ID
+	eventTypes   []objects.EventType
+	groups       map[string]map[string]any
+	uploads      []Upload
+	deviceStates map[int][]string
+	actionConns  map[*websocket.Conn]*actionConn
+	configConns  map[*websocket.Conn]*sync.Mutex
+	configReply  map[string]chan string
+	nextID       int
+}
+
+type actionConn struct {
+	writeMu sync.Mutex
+	domains map[string]bool
+}
+
+// NewHub starts a Hub. It is closed when the test ends.
+func NewHub(tb testing.TB) *Hub {
+	tb.Helper()
+	h := &Hub{
+		upgrader:     websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+		objects:      make(map[string]Object),
+		disabled:     make(map[string]bool),
+		states:       make(map[string]objects.State),
+		stateHistory: make(map[string][]objects.State),
+		results:      make(map[string][]ActionResult),
+		eventKeys:    make(map[string]string),
+		groups:       make(map[string]map[string]any),
+		deviceStates: make(map[int][]string),
+		actionConns:  make(map[*websocket.Conn]*actionConn),
+		configConns:  make(map[*websocket.Conn]*sync.Mutex),
+		configReply:  make(map[string]chan string),
+	}
+	h.changed = sync.NewCond(&h.mu)
+	h.srv = httptest.NewServer(h.routes())
+	tb.Cleanup(h.Close)
+	return h
+}
+
+// URL is the hub address, for NewNetsocsDriverClient or NewObjectController.
+func (h *Hub) URL() string {
+	return h.srv.URL
+}
+
+// Close closes every websocket and stops the server.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	for conn := range h.actionConns {
+		conn.Close()
+	}
+	for conn := range h.configConns {
+		conn.Close()
+	}
+	h.mu.Unlock()
+	h.srv.CloseClientConnections()
+	h.srv.Close()
+}
+
+func (h *Hub) newID(prefix string) string {
+	h.nextID++
+	return prefix + "-" + strconv.Itoa(h.nextID)
+}
+
+// notify wakes the Wait helpers. h.mu must be held.
+func (h *Hub) notify() {
+	h.changed.Broadcast()
+}
+
+// waitFor blocks until cond, called with h.mu held, returns true.
+func (h *Hub) waitFor(timeout time.Duration, cond func() bool) error {
+	timer := time.AfterFunc(timeout, func() {
+		h.mu.Lock()
+		h.notify()
+		h.mu.Unlock()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for !cond() {
+		if !time.Now().Before(deadline) {
+			return ErrTimeout
+		}
+		h.changed.Wait()
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func readJSON(r *http.Request, v any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+func badRequest(w http.ResponseWriter, err error) {
+	http.Error(w, fmt.Sprintf("drivertest: %v", err), http.StatusBadRequest)
+}
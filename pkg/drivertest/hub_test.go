@@ -0,0 +1,321 @@
+package drivertest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/client"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T, hub *Hub) *client.NetsocsDriverClient {
+	t.Helper()
+	c := client.NewNetsocsDriverClient("driver-key", hub.URL(), false)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		c.Shutdown(ctx)
+	})
+	return c
+}
+
+func newSwitch(id string) objects.SwitchObject {
+	return objects.NewSwitchObject(objects.NewSwitchObjectParams{
+		Metadata: objects.ObjectMetadata{ObjectID: id, Domain: "switch", Name: "Relay"},
+		TurnOnMethod: func(this objects.RegistrableObject, oc objects.ObjectController) error {
+			return oc.SetState(this.GetMetadata().ObjectID, objects.SWITCH_STATE_ON)
+		},
+		TurnOffMethod: func(this objects.RegistrableObject, oc objects.ObjectController) error {
+			return oc.SetState(this.GetMetadata().ObjectID, objects.SWITCH_STATE_OFF)
+		},
+	})
+}
+
+func TestHub_ExecuteAction(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+	require.NoError(t, c.EnableStructuredActionResults())
+	require.NoError(t, c.RegisterObject(newSwitch("switch.1")))
+
+	obj, ok := hub.Object("switch.1")
+	require.True(t, ok)
+	assert.Equal(t, "switch", obj.Domain)
+	assert.True(t, hub.Enabled("switch.1"))
+
+	require.NoError(t, hub.WaitForDomain("switch", 5*time.Second))
+	id, err := hub.ExecuteAction(ActionRequest{Domain: "switch", Action: objects.SWITCH_ACTION_TURN_ON, ObjectIDs: []string{"switch.1"}})
+	require.NoError(t, err)
+
+	result, err := hub.WaitResult(id, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, string(objects.ActionStatusSucceeded), result.Status)
+	require.NoError(t, hub.WaitForState("switch.1", objects.SWITCH_STATE_ON, time.Second))
+	assert.Equal(t, objects.SWITCH_STATE_ON, hub.StateHistory("switch.1")[0].State)
+}
+
+// Each client subscribes only its own domains, on its own websocket, and
+// keeps doing so after another client in the process shuts down.
+func TestHub_twoClientsSubscribeOwnDomains(t *testing.T) {
+	hubA, hubB := NewHub(t), NewHub(t)
+	a, b := newClient(t, hubA), newClient(t, hubB)
+	inDomain := func(id, domain string) objects.SwitchObject {
+		return objects.NewSwitchObject(objects.NewSwitchObjectParams{
+			Metadata: objects.ObjectMetadata{ObjectID: id, Domain: domain, Name: "Relay"},
+		})
+	}
+
+	require.NoError(t, a.RegisterObject(inDomain("switch.a", "switch")))
+	require.NoError(t, b.RegisterObject(inDomain("relay.b", "relay")))
+	require.NoError(t, hubA.WaitForDomain("switch", 5*time.Second))
+	require.NoError(t, hubB.WaitForDomain("relay", 5*time.Second))
+
+	require.NoError(t, a.RegisterObject(inDomain("gate.a", "gate")))
+	require.NoError(t, hubA.WaitForDomain("gate", 5*time.Second))
+	assert.ErrorIs(t, hubB.WaitForDomain("gate", 100*time.Millisecond), ErrTimeout)
+	assert.ErrorIs(t, hubA.WaitForDomain("relay", 10*time.Millisecond), ErrTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, a.Shutdown(ctx))
+	require.NoError(t, b.RegisterObject(inDomain("lock.b", "lock")))
+	require.NoError(t, hubB.WaitForDomain("lock", 5*time.Second))
+}
+
+func TestHub_ExecuteAction_notConnected(t *testing.T) {
+	hub := NewHub(t)
+	_, err := hub.ExecuteAction(ActionRequest{Domain: "switch", Action: objects.SWITCH_ACTION_TURN_ON})
+	assert.ErrorIs(t, err, ErrNotConnected)
+	assert.ErrorIs(t, hub.WaitForDomain("switch", 10*time.Millisecond), ErrTimeout)
+}
+
+func TestHub_States(t *testing.T) {
+	hub := NewHub(t)
+	oc := objects.NewObjectController(hub.URL(), "driver-key")
+	sw := newSwitch("switch.2")
+	require.NoError(t, oc.CreateObject(sw))
+
+	assert.Error(t, oc.SetState("unknown.1", "on"), "unregistered objects are rejected")
+	require.NoError(t, oc.UpdateStateAttributes("switch.2", map[string]string{"voltage": "12"}))
+	// ObjectStateChange sends every field, so the change carries the
+	// attributes to keep.
+	_, err := newClient(t, hub).SetObjectsBatchState([]objects.ObjectStateChange{{
+		ObjectID:                  "switch.2",
+		State:                     objects.SWITCH_STATE_OFF,
+		StateAdditionalProperties: map[string]string{"voltage": "12"},
+	}})
+	require.NoError(t, err)
+
+	record, err := oc.GetState("switch.2")
+	require.NoError(t, err)
+	assert.Equal(t, objects.SWITCH_STATE_OFF, record.State.State)
+	assert.Equal(t, "12", record.State.StateAdditionalProperties["voltage"])
+	assert.Len(t, hub.StateHistory("switch.2"), 2)
+
+	require.NoError(t, oc.DisabledObject("switch.2"))
+	assert.False(t, hub.Enabled("switch.2"))
+	assert.NoError(t, oc.SetState("switch.2", objects.SWITCH_STATE_ON), "disabled objects are ignored, as by the hub")
+	state, _ := hub.State("switch.2")
+	assert.Equal(t, objects.SWITCH_STATE_OFF, state.State)
+}
+
+func TestHub_EventsAndUploads(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+
+	require.NoError(t, c.AddEventTypes([]objects.EventType{{Domain: "switch", EventType: "switch.event.tamper"}}))
+	require.Len(t, hub.EventTypes(), 1)
+
+	id, err := c.DispatchEvent("switch", "event.tamper", objects.Event{ObjectIDs: []string{"switch.1"}, Properties: map[string]string{"zone": "3"}})
+	require.NoError(t, err)
+	events, err := hub.WaitForEvents(1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, id, events[0].ID)
+	assert.Equal(t, "switch.event.tamper", events[0].EventType)
+	assert.Equal(t, []string{"/objects/switch.1"}, events[0].Rels)
+
+	require.NoError(t, c.PatchEvent(id, objects.EventRecord{Images: []string{"/public/a.jpg"}}))
+	assert.Equal(t, []string{"/public/a.jpg"}, hub.Events()[0].Images)
+
+	resp, err := c.UploadSnapshot(bytes.NewReader([]byte("jpeg")), "snapshot.jpg", "door.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "/public/door.jpg", resp.Path)
+	uploads := hub.Uploads()
+	require.Len(t, uploads, 1)
+	assert.Equal(t, "snapshot.jpg", uploads[0].Filename)
+	assert.Equal(t, []byte("jpeg"), uploads[0].Data)
+}
+
+func TestHub_eventValidation(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+	c.SetEventValidation(client.EventValidationStrict)
+	require.NoError(t, c.AddEventTypes([]objects.EventType{{Domain: "switch", EventType: "switch.event.tamper"}}))
+	assert.Equal(t, []objects.EventType{{Domain: "switch", EventType: "switch.event.tamper"}}, c.EventTypes())
+	require.NoError(t, c.SetEventSchema("switch.event.tamper", objects.EventSchema{
+		Properties: map[string]objects.PropertySchema{"zone": {Type: objects.PropertyInt, Required: true}},
+	}))
+
+	_, err := c.DispatchEvent("switch", "event.tampr", objects.Event{})
+	assert.ErrorIs(t, err, objects.ErrUnknownEventType)
+	_, err = c.DispatchEventType("switch.event.tamper", objects.Event{Properties: map[string]string{"zone": "north"}})
+	assert.ErrorIs(t, err, objects.ErrInvalidEvent)
+	assert.Empty(t, hub.Events(), "invalid events are not sent")
+
+	resp, err := c.DispatchEventType("switch.event.tamper", objects.Event{Properties: map[string]string{"zone": "3"}})
+	require.NoError(t, err)
+	assert.Equal(t, hub.Events()[0].ID, resp.ID)
+
+	c.SetEventValidation(client.EventValidationWarn)
+	_, err = c.DispatchEvent("switch", "event.other", objects.Event{})
+	assert.NoError(t, err, "invalid events are only logged by default")
+	assert.Len(t, hub.Events(), 2)
+}
+
+// The convention of docs/first-driver.md: a type registered by its key,
+// dispatched by domain and key.
+func TestHub_eventValidation_domainAndKey(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+	c.SetEventValidation(client.EventValidationStrict)
+	require.NoError(t, c.AddEventTypes([]objects.EventType{{Domain: "temperature", EventType: "temperature_alert"}}))
+
+	_, err := c.DispatchEvent("temperature", "temperature_alert", objects.Event{Properties: map[string]string{"temperature": "31.5"}})
+	require.NoError(t, err)
+	require.Len(t, hub.Events(), 1)
+	assert.Equal(t, "temperature.temperature_alert", hub.Events()[0].EventType)
+}
+
+func TestHub_eventsAreIdempotent(t *testing.T) {
+	hub := NewHub(t)
+
+	key := httpx.NewIdempotencyKey()
+	var ids []string
+	for i := 0; i < 2; i++ {
+		resp, err := httpx.Resty().R().
+			SetHeader(httpx.IdempotencyKeyHeader, key).
+			SetBody(objects.NewEventRequestBodySchema{EventType: "switch.event.tamper"}).
+			Post(hub.URL() + "/objects/events")
+		require.NoError(t, err)
+		ids = append(ids, resp.String())
+	}
+	assert.Equal(t, ids[0], ids[1])
+	assert.Len(t, hub.Events(), 1)
+}
+
+func TestHub_Groups(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+
+	building, err := c.EnsureObjectGroup("Building A", "", client.CreateObjectGroupRequest{})
+	require.NoError(t, err)
+	again, err := c.EnsureObjectGroup("Building A", "", client.CreateObjectGroupRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, building, again)
+
+	floor, err := c.EnsureObjectGroup("Floor 1", building, client.CreateObjectGroupRequest{})
+	require.NoError(t, err)
+	children, err := c.GetObjectGroupChildren(building)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, floor, children[0].ID)
+
+	require.NoError(t, c.DeleteObjectGroup(floor))
+	assert.Len(t, hub.Groups(), 1)
+}
+
+func deviceSwitch(id, name string) objects.SwitchObject {
+	sw := newSwitch(id)
+	metadata := sw.GetMetadata()
+	metadata.Name = name
+	metadata.DeviceID = "7"
+	return objects.NewSwitchObject(objects.NewSwitchObjectParams{
+		Metadata: metadata,
+		TurnOnMethod: func(this objects.RegistrableObject, oc objects.ObjectController) error {
+			return oc.SetState(this.GetMetadata().ObjectID, objects.SWITCH_STATE_ON)
+		},
+	})
+}
+
+func TestHub_Reconcile(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+	for _, id := range []string{"switch.1", "switch.2", "switch.3", "switch.5"} {
+		require.NoError(t, c.RegisterObject(deviceSwitch(id, "Relay")))
+	}
+	require.NoError(t, c.RegisterObject(newSwitch("switch.other")))
+	require.NoError(t, objects.NewObjectController(hub.URL(), "driver-key").DisabledObject("switch.3"))
+
+	result, err := c.Reconcile("7", []objects.RegistrableObject{
+		deviceSwitch("switch.1", "Relay"),
+		deviceSwitch("switch.2", "Gate relay"),
+		deviceSwitch("switch.3", "Relay"),
+		deviceSwitch("switch.4", "Relay"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, objects.ReconcileResult{
+		Created:   []string{"switch.4"},
+		Updated:   []string{"switch.2"},
+		Enabled:   []string{"switch.3"},
+		Disabled:  []string{"switch.5"},
+		Unchanged: []string{"switch.1", "switch.3"},
+	}, result)
+
+	obj, _ := hub.Object("switch.2")
+	assert.Equal(t, "Gate relay", obj.Name)
+	assert.True(t, hub.Enabled("switch.3"))
+	assert.False(t, hub.Enabled("switch.5"))
+	assert.True(t, hub.Enabled("switch.other"), "objects of other devices are left alone")
+
+	// The updated object receives the actions.
+	require.NoError(t, hub.WaitForDomain("switch", 5*time.Second))
+	id, err := hub.ExecuteAction(ActionRequest{Domain: "switch", Action: objects.SWITCH_ACTION_TURN_ON, ObjectIDs: []string{"switch.2"}})
+	require.NoError(t, err)
+	result2, err := hub.WaitResult(id, 5*time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, result2.Result["error"])
+
+	result, err = c.Reconcile("7", []objects.RegistrableObject{deviceSwitch("switch.1", "Relay")}, objects.ReconcileOptions{DeleteStale: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"switch.2", "switch.3", "switch.4", "switch.5"}, result.Deleted)
+	assert.ElementsMatch(t, []string{"switch.1", "switch.other"}, hub.Objects())
+}
+
+func TestHub_UnregisterObject(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+	require.NoError(t, c.RegisterObject(newSwitch("switch.1")))
+	require.NoError(t, c.UnregisterObject("switch.1"))
+	_, ok := hub.Object("switch.1")
+	assert.False(t, ok)
+	assert.NoError(t, c.UnregisterObject("switch.1"), "unregistering twice is not an error")
+
+	require.NoError(t, hub.WaitForDomain("switch", 5*time.Second))
+	id, err := hub.ExecuteAction(ActionRequest{Domain: "switch", Action: objects.SWITCH_ACTION_TURN_ON, ObjectIDs: []string{"switch.1"}})
+	require.NoError(t, err)
+	_, err = hub.WaitResult(id, 200*time.Millisecond)
+	assert.ErrorIs(t, err, ErrTimeout, "an unregistered object runs no actions")
+}
+
+func TestHub_SendConfig(t *testing.T) {
+	hub := NewHub(t)
+	c := newClient(t, hub)
+	require.NoError(t, c.AddConfigHandler("drivertest_echo", func(value config.HandlerValue) (interface{}, error) {
+		return map[string]string{"echo": value.Value}, nil
+	}))
+	go c.ListenConfig()
+	require.NoError(t, hub.WaitConfigConnected(5*time.Second))
+
+	data, err := hub.SendConfig("drivertest_echo", "hello", nil, 5*time.Second)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"echo":"hello"}`, data)
+
+	data, err = hub.SendConfig(config.PING, "", nil, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", data)
+}
@@ -0,0 +1,163 @@
+package drivertest
+
+import (
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+)
+
+// Object returns an object registered by the driver.
+func (h *Hub) Object(id string) (Object, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	obj, ok := h.objects[id]
+	return obj, ok
+}
+
+// Objects returns the IDs of the registered objects.
+func (h *Hub) Objects() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.objects))
+	for id := range h.objects {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Enabled reports whether a registered object is enabled.
+func (h *Hub) Enabled(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.objects[id]
+	return ok && !h.disabled[id]
+}
+
+// Actions returns the actions registered through POST /objects/actions.
+func (h *Hub) Actions() []objects.ObjectAction {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]objects.ObjectAction(nil), h.actions...)
+}
+
+// SetState seeds the state of an object, as if another client had set it.
+// It is what GET /objects/states returns until the driver changes it.
+func (h *Hub) SetState(id string, state objects.State) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.states[id] = state
+	h.notify()
+}
+
+// State returns the current state of an object.
+func (h *Hub) State(id string) (objects.State, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state, ok := h.states[id]
+	return state, ok
+}
+
+// StateHistory returns every state the driver set on an object, oldest first.
+func (h *Hub) StateHistory(id string) []objects.State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]objects.State(nil), h.stateHistory[id]...)
+}
+
+// WaitForState waits until the state of an object is state.
+func (h *Hub) WaitForState(id, state string, timeout time.Duration) error {
+	return h.waitFor(timeout, func() bool { return h.states[id].State == state })
+}
+
+// Results returns the result updates received for an execution, oldest first.
+func (h *Hub) Results(executionID string) []ActionResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]ActionResult(nil), h.results[executionID]...)
+}
+
+// WaitResult waits for the final result of an execution, skipping the
+// running updates.
+func (h *Hub) WaitResult(executionID string, timeout time.Duration) (ActionResult, error) {
+	var result ActionResult
+	err := h.waitFor(timeout, func() bool {
+		for _, r := range h.results[executionID] {
+			if r.Final() {
+				result = r
+				return true
+			}
+		}
+		return false
+	})
+	return result, err
+}
+
+// Events returns the events dispatched by the driver, oldest first.
+func (h *Hub) Events() []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Event(nil), h.events...)
+}
+
+// WaitForEvents waits until the driver has dispatched at least n events.
+func (h *Hub) WaitForEvents(n int, timeout time.Duration) ([]Event, error) {
+	err := h.waitFor(timeout, func() bool { return len(h.events) >= n })
+	return h.Events(), err
+}
+
+// EventTypes returns the event types registered by the driver.
+func (h *Hub) EventTypes() []objects.EventType {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]objects.EventType(nil), h.eventTypes...)
+}
+
+// Groups returns the object groups, including the object-group relations
+// created by RegisterObject, as the JSON objects the driver sent plus an "id".
+func (h *Hub) Groups() []map[string]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	groups := make([]map[string]any, 0, len(h.groups))
+	for _, group := range h.groups {
+		copied := make(map[string]any, len(group))
+		for k, v := range group {
+			copied[k] = v
+		}
+		groups = append(groups, copied)
+	}
+	return groups
+}
+
+// Uploads returns the snapshots uploaded by the driver, oldest first.
+func (h *Hub) Uploads() []Upload {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Upload(nil), h.uploads...)
+}
+
+// DeviceState returns the last state the driver set on a device.
+func (h *Hub) DeviceState(id int) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	history := h.deviceStates[id]
+	if len(history) == 0 {
+		return "", false
+	}
+	return history[len(history)-1], true
+}
+
+// DeviceStateHistory returns every state the driver set on a device, oldest
+// first.
+func (h *Hub) DeviceStateHistory(id int) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.deviceStates[id]...)
+}
+
+// WaitForDeviceState waits until the last state set on a device is state.
+func (h *Hub) WaitForDeviceState(id int, state string, timeout time.Duration) error {
+	return h.waitFor(timeout, func() bool {
+		history := h.deviceStates[id]
+		return len(history) > 0 && history[len(history)-1] == state
+	})
+}
@@ -0,0 +1,570 @@
+package drivertest
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tools"
+	"github.com/goccy/go-json"
+)
+
+func (h *Hub) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /objects", h.createObject)
+	mux.HandleFunc("GET /objects", h.listObjects)
+	mux.HandleFunc("PUT /objects/{id}", h.updateObject)
+	mux.HandleFunc("DELETE /objects/{id}", h.deleteObject)
+	mux.HandleFunc("PUT /objects/{id}/{status}", h.setEnabled)
+	mux.HandleFunc("POST /objects/actions", h.createAction)
+	mux.HandleFunc("PUT /objects/actions/executions/{id}", h.reportResult)
+	mux.HandleFunc("GET /objects/states/{id}", h.getState)
+	mux.HandleFunc("PUT /objects/states/{id}", h.putState)
+	mux.HandleFunc("PUT /objects/states/{id}/increment", h.stepState(1))
+	mux.HandleFunc("PUT /objects/states/{id}/decrement", h.stepState(-1))
+	mux.HandleFunc("PUT /objects/states-batch", h.putStatesBatch)
+	mux.HandleFunc("PUT /objects/states_batch", h.putStatesBatch)
+	mux.HandleFunc("POST /objects/events/types/batch", h.createEventTypes)
+	mux.HandleFunc("POST /objects/events/types/{domain}/{type}", h.createEventType)
+	mux.HandleFunc("POST /objects/events", h.createEvent)
+	mux.HandleFunc("GET /objects/events/{id}", h.getEvent)
+	mux.HandleFunc("PUT /objects/events/{id}", h.putEvent)
+	mux.HandleFunc("POST /groups", h.createGroup)
+	mux.HandleFunc("GET /groups", h.listGroups)
+	mux.HandleFunc("GET /groups/tree", h.groupTree)
+	mux.HandleFunc("GET /groups/tree/children/{id}", h.groupChildren)
+	mux.HandleFunc("GET /groups/{id}", h.getGroup)
+	mux.HandleFunc("PUT /groups/{id}", h.putGroup)
+	mux.HandleFunc("DELETE /groups/{id}", h.deleteGroup)
+	mux.HandleFunc("POST /snapshots/upload", h.upload)
+	mux.HandleFunc("PUT /devices/states/{id}", h.putDeviceState)
+	mux.HandleFunc("GET /objects/ws", h.serveActions)
+	mux.HandleFunc("GET /ws/v1/config_communication", h.serveConfig)
+	return mux
+}
+
+func (h *Hub) createObject(w http.ResponseWriter, r *http.Request) {
+	var obj Object
+	if err := readJSON(r, &obj); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	h.objects[obj.ID] = obj
+	h.disabled[obj.ID] = !obj.Enabled
+	h.notify()
+	h.mu.Unlock()
+	writeJSON(w, http.StatusCreated, obj)
+}
+
+func (h *Hub) listObjects(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.URL.Query().Get("device_id")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	h.mu.Lock()
+	matching := []Object{}
+	for _, obj := range h.objects {
+		if deviceID == "" || strconv.Itoa(obj.DeviceID) == deviceID {
+			obj.Enabled = !h.disabled[obj.ID]
+			matching = append(matching, obj)
+		}
+	}
+	h.mu.Unlock()
+	slices.SortFunc(matching, func(a, b Object) int { return strings.Compare(a.ID, b.ID) })
+
+	var page objects.PaginatedObjectRecord
+	page.Metadata.TotalItems = len(matching)
+	page.Metadata.Limit = limit
+	page.Metadata.Offset = offset
+	page.Items = []objects.ObjectRecord{}
+	if offset < len(matching) {
+		matching = matching[offset:]
+		if limit > 0 && limit < len(matching) {
+			matching = matching[:limit]
+		}
+		for _, obj := range matching {
+			page.Items = append(page.Items, objects.ObjectRecord(obj))
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Hub) updateObject(w http.ResponseWriter, r *http.Request) {
+	var update Object
+	if err := readJSON(r, &update); err != nil {
+		badRequest(w, err)
+		return
+	}
+	id := r.PathValue("id")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	obj, ok := h.objects[id]
+	if !ok {
+		http.Error(w, "object not found", http.StatusNotFound)
+		return
+	}
+	obj.Domain = update.Domain
+	obj.Name = update.Name
+	obj.Tags = update.Tags
+	obj.Type = update.Type
+	obj.StatesAvailable = update.StatesAvailable
+	obj.ActionsAvailable = update.ActionsAvailable
+	h.objects[id] = obj
+	h.notify()
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func (h *Hub) deleteObject(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.objects[id]; !ok {
+		http.Error(w, "object not found", http.StatusNotFound)
+		return
+	}
+	delete(h.objects, id)
+	delete(h.disabled, id)
+	delete(h.states, id)
+	h.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Hub) setEnabled(w http.ResponseWriter, r *http.Request) {
+	status := r.PathValue("status")
+	if status != "enabled" && status != "disabled" {
+		http.NotFound(w, r)
+		return
+	}
+	id := r.PathValue("id")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.objects[id]; !ok {
+		http.Error(w, "object not found", http.StatusNotFound)
+		return
+	}
+	h.disabled[id] = status == "disabled"
+	h.notify()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Hub) createAction(w http.ResponseWriter, r *http.Request) {
+	var action objects.ObjectAction
+	if err := readJSON(r, &action); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	h.actions = append(h.actions, action)
+	h.mu.Unlock()
+	writeJSON(w, http.StatusCreated, action)
+}
+
+func (h *Hub) reportResult(w http.ResponseWriter, r *http.Request) {
+	var result ActionResult
+	if err := readJSON(r, &result); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	id := r.PathValue("id")
+	h.results[id] = append(h.results[id], result)
+	h.notify()
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Hub) getState(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	h.mu.Lock()
+	state, ok := h.states[id]
+	h.mu.Unlock()
+	var page objects.PaginatedStateRecord
+	page.Items = []objects.StateRecord{}
+	if ok {
+		page.Items = append(page.Items, objects.StateRecord{ObjectID: id, State: state})
+		page.Metadata.TotalItems = 1
+	}
+	page.Metadata.Limit = 1
+	writeJSON(w, http.StatusOK, page)
+}
+
+// stateChange is a state change as the hub reads it: a field left out keeps
+// its value, while a state sent empty sets it empty and attributes sent null
+// clear them.
+type stateChange struct {
+	ObjectID   string          `json:"object_id"`
+	State      *string         `json:"state"`
+	Attributes json.RawMessage `json:"state_additional_properties"`
+}
+
+func (h *Hub) putState(w http.ResponseWriter, r *http.Request) {
+	var change stateChange
+	if err := readJSON(r, &change); err != nil {
+		badRequest(w, err)
+		return
+	}
+	change.ObjectID = r.PathValue("id")
+	h.mu.Lock()
+	errMsg := h.applyState(change)
+	h.mu.Unlock()
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// applyState records a state change and returns the error the hub would
+// report for it. h.mu must be held.
+func (h *Hub) applyState(change stateChange) string {
+	if _, ok := h.objects[change.ObjectID]; !ok {
+		return "object not found"
+	}
+	if h.disabled[change.ObjectID] {
+		return "object is disabled"
+	}
+	var changed map[string]string
+	if len(change.Attributes) > 0 {
+		if err := json.Unmarshal(change.Attributes, &changed); err != nil {
+			return "invalid state_additional_properties"
+		}
+	}
+	state := h.states[change.ObjectID]
+	if change.State != nil {
+		state.State = *change.State
+	}
+	switch {
+	case string(change.Attributes) == "null":
+		state.StateAdditionalProperties = nil
+	case changed != nil:
+		attributes := make(map[string]string, len(state.StateAdditionalProperties)+len(changed))
+		for k, v := range state.StateAdditionalProperties {
+			attributes[k] = v
+		}
+		for k, v := range changed {
+			attributes[k] = v
+		}
+		state.StateAdditionalProperties = attributes
+	}
+	h.states[change.ObjectID] = state
+	h.stateHistory[change.ObjectID] = append(h.stateHistory[change.ObjectID], state)
+	h.notify()
+	return ""
+}
+
+func (h *Hub) stepState(delta int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		current := 0
+		if state, ok := h.states[id]; ok && state.State != "" {
+			n, err := strconv.Atoi(state.State)
+			if err != nil {
+				http.Error(w, "state is not a number", http.StatusBadRequest)
+				return
+			}
+			current = n
+		}
+		next := strconv.Itoa(current + delta)
+		if errMsg := h.applyState(stateChange{ObjectID: id, State: &next}); errMsg != "" {
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *Hub) putStatesBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Changes []stateChange `json:"changes"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	responses := make([]objects.ChangeStateBatchResponse, 0, len(req.Changes))
+	for _, change := range req.Changes {
+		errMsg := h.applyState(change)
+		responses = append(responses, objects.ChangeStateBatchResponse{
+			ID:       change.ObjectID,
+			Datetime: time.Now().UTC().Format(time.RFC3339Nano),
+			Error:    errMsg,
+			Changed:  errMsg == "",
+		})
+	}
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *Hub) createEventTypes(w http.ResponseWriter, r *http.Request) {
+	var eventTypes []objects.EventType
+	if err := readJSON(r, &eventTypes); err != nil {
+		badRequest(w, err)
+		return
+	}
+	var resp objects.EventTypesBatchResponse
+	h.mu.Lock()
+	for _, eventType := range eventTypes {
+		h.eventTypes = append(h.eventTypes, eventType)
+		resp.Successful = append(resp.Successful, objects.EventTypeResponse{Domain: eventType.Domain, EventType: eventType.EventType})
+	}
+	h.notify()
+	h.mu.Unlock()
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (h *Hub) createEventType(w http.ResponseWriter, r *http.Request) {
+	var eventType objects.EventType
+	if err := readJSON(r, &eventType); err != nil {
+		badRequest(w, err)
+		return
+	}
+	eventType.Domain = r.PathValue("domain")
+	eventType.EventType = r.PathValue("type")
+	h.mu.Lock()
+	h.eventTypes = append(h.eventTypes, eventType)
+	h.notify()
+	h.mu.Unlock()
+	writeJSON(w, http.StatusCreated, eventType)
+}
+
+func (h *Hub) createEvent(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := readJSON(r, &event.NewEventRequestBodySchema); err != nil {
+		badRequest(w, err)
+		return
+	}
+	key := r.Header.Get(httpx.IdempotencyKeyHeader)
+	h.mu.Lock()
+	if id, ok := h.eventKeys[key]; ok && key != "" {
+		h.mu.Unlock()
+		writeJSON(w, http.StatusCreated, objects.EventDispatchResponse{ID: id})
+		return
+	}
+	event.ID = h.newID("event")
+	h.events = append(h.events, event)
+	if key != "" {
+		h.eventKeys[key] = event.ID
+	}
+	h.notify()
+	h.mu.Unlock()
+	writeJSON(w, http.StatusCreated, objects.EventDispatchResponse{ID: event.ID})
+}
+
+func (h *Hub) findEvent(id string) (int, bool) {
+	for i, event := range h.events {
+		if event.ID == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (h *Hub) getEvent(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i, ok := h.findEvent(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	event := h.events[i]
+	writeJSON(w, http.StatusOK, objects.EventRecord{
+		ID:                        event.ID,
+		Rels:                      event.Rels,
+		EventAdditionalProperties: event.EventAdditionalProperties,
+		Images:                    event.Images,
+		VideoClips:                event.VideoClips,
+	})
+}
+
+func (h *Hub) putEvent(w http.ResponseWriter, r *http.Request) {
+	var record objects.EventRecord
+	if err := readJSON(r, &record); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i, ok := h.findEvent(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	h.events[i].Rels = record.Rels
+	h.events[i].EventAdditionalProperties = record.EventAdditionalProperties
+	h.events[i].Images = record.Images
+	h.events[i].VideoClips = record.VideoClips
+	h.notify()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Hub) createGroup(w http.ResponseWriter, r *http.Request) {
+	group := map[string]any{}
+	if err := readJSON(r, &group); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	group["id"] = h.newID("group")
+	h.groups[group["id"].(string)] = group
+	h.notify()
+	h.mu.Unlock()
+	writeJSON(w, http.StatusCreated, group)
+}
+
+func (h *Hub) listGroups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Groups())
+}
+
+func (h *Hub) getGroup(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	group, ok := h.groups[r.PathValue("id")]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "group not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (h *Hub) putGroup(w http.ResponseWriter, r *http.Request) {
+	update := map[string]any{}
+	if err := readJSON(r, &update); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	group, ok := h.groups[r.PathValue("id")]
+	if !ok {
+		http.Error(w, "group not found", http.StatusNotFound)
+		return
+	}
+	for k, v := range update {
+		if k != "id" {
+			group[k] = v
+		}
+	}
+	h.notify()
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (h *Hub) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := r.PathValue("id")
+	if _, ok := h.groups[id]; !ok {
+		http.Error(w, "group not found", http.StatusNotFound)
+		return
+	}
+	delete(h.groups, id)
+	h.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type groupTree struct {
+	Group    map[string]any `json:"group"`
+	Children []groupTree    `json:"children"`
+}
+
+// children returns the groups whose parent_id is parentID. h.mu must be held.
+func (h *Hub) children(parentID string) []map[string]any {
+	var children []map[string]any
+	for _, group := range h.groups {
+		if parent, _ := group["parent_id"].(string); parent == parentID {
+			children = append(children, group)
+		}
+	}
+	return children
+}
+
+func (h *Hub) subtree(parentID string) []groupTree {
+	tree := []groupTree{}
+	for _, group := range h.children(parentID) {
+		tree = append(tree, groupTree{Group: group, Children: h.subtree(group["id"].(string))})
+	}
+	return tree
+}
+
+func (h *Hub) groupTree(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	tree := h.subtree("")
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"tree": tree})
+}
+
+func (h *Hub) groupChildren(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	nodes := []map[string]any{}
+	for _, group := range h.children(r.PathValue("id")) {
+		id := group["id"].(string)
+		nodes = append(nodes, map[string]any{
+			"id":            id,
+			"name":          group["name"],
+			"type":          group["type"],
+			"item_id":       group["item_id"],
+			"icon":          group["icon"],
+			"children_link": "/groups/tree/children/" + id,
+			"group_link":    "/groups/" + id,
+		})
+	}
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"tree": nodes})
+}
+
+func (h *Hub) upload(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	upload := Upload{Filename: header.Filename, Name: r.FormValue("name"), Data: data}
+	stored := upload.Name
+	if stored == "" {
+		stored = upload.Filename
+	}
+	stored = strings.TrimPrefix(stored, "/")
+	h.mu.Lock()
+	h.uploads = append(h.uploads, upload)
+	h.notify()
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, tools.SnapshotUploadResponse{
+		Filename: stored,
+		URL:      h.srv.URL + "/public/" + stored,
+		Path:     "/public/" + stored,
+	})
+}
+
+func (h *Hub) putDeviceState(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	var body struct {
+		State string `json:"state"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		badRequest(w, err)
+		return
+	}
+	h.mu.Lock()
+	h.deviceStates[id] = append(h.deviceStates[id], body.State)
+	h.notify()
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
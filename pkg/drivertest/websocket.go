@@ -0,0 +1,215 @@
+package drivertest
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/config"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+)
+
+// ErrNotConnected is returned by ExecuteAction and SendConfig when no driver
+// is listening on the matching websocket.
+var ErrNotConnected = errors.New("drivertest: no driver connected")
+
+// ActionRequest is an action execution sent to the driver over objects/ws.
+type ActionRequest struct {
+	// ID is the execution ID; empty generates one.
+	ID        string
+	Domain    string
+	Action    string
+	ObjectIDs []string
+	Payload   map[string]any
+}
+
+type actionMessage struct {
+	EventType string `json:"event_type"`
+	Domain    string `json:"domain"`
+	Data      any    `json:"data"`
+}
+
+type actionExecution struct {
+	ID       string         `json:"id"`
+	Domain   string         `json:"domain"`
+	Action   string         `json:"action"`
+	ObjectID []string       `json:"object_id"`
+	Payload  map[string]any `json:"payload"`
+}
+
+func (h *Hub) serveActions(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	state := &actionConn{domains: make(map[string]bool)}
+	h.mu.Lock()
+	h.actionConns[conn] = state
+	h.notify()
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.actionConns, conn)
+		h.notify()
+		h.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		var msg actionMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.EventType == "REQUEST_SUBSCRIPTION_TO_DOMAIN" && msg.Domain != "" {
+			h.mu.Lock()
+			state.domains[msg.Domain] = true
+			h.notify()
+			h.mu.Unlock()
+		}
+	}
+}
+
+// WaitForDomain waits until a driver has subscribed to the action requests of
+// domain, i.e. until ExecuteAction can reach it.
+func (h *Hub) WaitForDomain(domain string, timeout time.Duration) error {
+	return h.waitFor(timeout, func() bool {
+		for _, state := range h.actionConns {
+			if state.domains[domain] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ExecuteAction sends an action execution to every driver subscribed to
+// req.Domain and returns its execution ID. Use WaitResult to get the outcome.
+func (h *Hub) ExecuteAction(req ActionRequest) (string, error) {
+	h.mu.Lock()
+	if req.ID == "" {
+		req.ID = h.newID("exec")
+	}
+	if req.Payload == nil {
+		req.Payload = map[string]any{}
+	}
+	type target struct {
+		conn *websocket.Conn
+		mu   *sync.Mutex
+	}
+	var targets []target
+	for conn, state := range h.actionConns {
+		if state.domains[req.Domain] {
+			targets = append(targets, target{conn: conn, mu: &state.writeMu})
+		}
+	}
+	h.mu.Unlock()
+	if len(targets) == 0 {
+		return "", ErrNotConnected
+	}
+
+	msg := actionMessage{
+		EventType: "REQUEST_ACTION_EXECUTION",
+		Data: actionExecution{
+			ID:       req.ID,
+			Domain:   req.Domain,
+			Action:   req.Action,
+			ObjectID: req.ObjectIDs,
+			Payload:  req.Payload,
+		},
+	}
+	var errs []error
+	for _, t := range targets {
+		t.mu.Lock()
+		errs = append(errs, t.conn.WriteJSON(msg))
+		t.mu.Unlock()
+	}
+	return req.ID, errors.Join(errs...)
+}
+
+type configResponse struct {
+	RequestID string `json:"requestId"`
+	Data      string `json:"data"`
+}
+
+func (h *Hub) serveConfig(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	writeMu := &sync.Mutex{}
+	h.mu.Lock()
+	h.configConns[conn] = writeMu
+	h.notify()
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.configConns, conn)
+		h.notify()
+		h.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var resp configResponse
+		if err := json.Unmarshal(message, &resp); err != nil {
+			continue
+		}
+		h.mu.Lock()
+		reply, ok := h.configReply[resp.RequestID]
+		delete(h.configReply, resp.RequestID)
+		h.mu.Unlock()
+		if ok {
+			reply <- resp.Data
+		}
+	}
+}
+
+// WaitConfigConnected waits until a driver has opened the config websocket,
+// i.e. until ListenConfig is serving.
+func (h *Hub) WaitConfigConnected(timeout time.Duration) error {
+	return h.waitFor(timeout, func() bool { return len(h.configConns) > 0 })
+}
+
+// SendConfig sends a config message to the connected driver and returns the
+// data of its reply, as the driver sent it. deviceData may be nil.
+func (h *Hub) SendConfig(key config.NetsocsConfigKey, value string, deviceData *config.ConfigMessageDeviceData, timeout time.Duration) (string, error) {
+	h.mu.Lock()
+	var (
+		conn    *websocket.Conn
+		writeMu *sync.Mutex
+	)
+	for c, mu := range h.configConns {
+		conn, writeMu = c, mu
+		break
+	}
+	if conn == nil {
+		h.mu.Unlock()
+		return "", ErrNotConnected
+	}
+	requestID := h.newID("config")
+	reply := make(chan string, 1)
+	h.configReply[requestID] = reply
+	h.mu.Unlock()
+
+	writeMu.Lock()
+	err := conn.WriteJSON(config.ConfigMessage{DeviceData: deviceData, ConfigKey: key, Value: value, RequestID: requestID})
+	writeMu.Unlock()
+	if err == nil {
+		select {
+		case data := <-reply:
+			return data, nil
+		case <-time.After(timeout):
+			err = ErrTimeout
+		}
+	}
+	h.mu.Lock()
+	delete(h.configReply, requestID)
+	h.mu.Unlock()
+	return "", err
+}
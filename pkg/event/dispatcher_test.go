@@ -0,0 +1,176 @@
+package event
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+)
+
+type received struct {
+	key  string
+	body map[string]any
+}
+
+// newTopology starts a topology service recording the misc events it gets.
+func newTopology(t *testing.T) (*httptest.Server, func() []received) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		events []received
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		events = append(events, received{key: strings.TrimPrefix(r.URL.Path, "/v1/topologia/misc/"), body: body})
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), events...)
+	}
+}
+
+func TestDispatcher_encodesFields(t *testing.T) {
+	srv, events := newTopology(t)
+	d := NewDispatcher("", "key", 7)
+	d.SetHost(srv.URL)
+
+	d.SetOriginalValue(`door "A" \ open`)
+	d.SetChannelNumber(2)
+	d.SetPersonId("em12")
+	d.SetTimestamp(1700000000)
+	if err := d.DispatchAndResetFields(MOTION_DETECTION); err != nil {
+		t.Fatalf("DispatchAndResetFields: %v", err)
+	}
+	if err := d.DispatchAndResetFields(TAMPERING); err != nil {
+		t.Fatalf("DispatchAndResetFields: %v", err)
+	}
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	want := map[string]any{
+		"deviceId":      float64(7),
+		"originalValue": `door "A" \ open`,
+		"deviceByProps": []any{`"parentDevice": 7`, `"channelNumber": 2`},
+		"userType":      float64(2),
+		"userId":        float64(12),
+		"timestamp":     float64(1700000000),
+	}
+	if got[0].key != MOTION_DETECTION || !reflect.DeepEqual(got[0].body, want) {
+		t.Errorf("first event = %s %v, want %s %v", got[0].key, got[0].body, MOTION_DETECTION, want)
+	}
+	want = map[string]any{"deviceId": float64(7), "timestamp": float64(1700000000)}
+	if !reflect.DeepEqual(got[1].body, want) {
+		t.Errorf("the fields were not reset: %v", got[1].body)
+	}
+}
+
+func TestDispatcher_concurrent(t *testing.T) {
+	srv, events := newTopology(t)
+	d := NewDispatcher("", "key", 7)
+	d.SetHost(srv.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(channel int) {
+			defer wg.Done()
+			if err := d.Dispatch(VIDEOLOSS, Fields{ChannelNumber: &channel}); err != nil {
+				t.Errorf("Dispatch: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := len(events()); got != 20 {
+		t.Errorf("got %d events, want 20", got)
+	}
+}
+
+func TestNewDispatcher_topologyHost(t *testing.T) {
+	for driverHubHost, want := range map[string]string{
+		"":                                     DEFAULT_EVENT_HOST,
+		"10.0.0.5":                             "10.0.0.5",
+		"10.0.0.5:3196":                        "10.0.0.5",
+		"https://site.example.com/api/netsocs": "site.example.com",
+		"http://[fd00::5]:3196":                "fd00::5",
+	} {
+		d := NewDispatcher(driverHubHost, "key", 7)
+		if d.Host != want || d.Port != DEFAULT_EVENT_PORT {
+			t.Errorf("NewDispatcher(%q) sends to %s:%d, want %s:%d", driverHubHost, d.Host, d.Port, want, DEFAULT_EVENT_PORT)
+		}
+	}
+}
+
+func TestDispatcher_rejectsInvalidPersonID(t *testing.T) {
+	d := NewDispatcher("", "key", 7)
+	if err := d.Dispatch(ADMIN_LOGIN, Fields{PersonID: "xx1"}); err == nil {
+		t.Error("expected an error for an invalid person ID")
+	}
+}
+
+type fakeSender struct {
+	mu     sync.Mutex
+	events map[string]objects.Event
+}
+
+func (s *fakeSender) DispatchEventType(eventType string, event objects.Event) (objects.EventDispatchResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[eventType] = event
+	return objects.EventDispatchResponse{ID: "ev-1"}, nil
+}
+
+func TestDispatcher_Forward(t *testing.T) {
+	srv, events := newTopology(t)
+	sender := &fakeSender{events: map[string]objects.Event{}}
+	d := NewDispatcher("", "key", 7)
+	d.SetHost(srv.URL)
+	err := d.Forward(Forwarding{
+		Sender:     sender,
+		EventTypes: map[string]string{MOTION_DETECTION: "video_channel.event.motion"},
+		ObjectIDs: func(deviceID int, fields Fields) []string {
+			return []string{"video_channel.7." + strconv.Itoa(*fields.ChannelNumber)}
+		},
+		Exclusive: true,
+	})
+	if err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+
+	channel := 3
+	if err := d.Dispatch(MOTION_DETECTION, Fields{ChannelNumber: &channel, ImageURL: "/public/a.jpg"}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if err := d.Dispatch(VIDEOLOSS, Fields{ChannelNumber: &channel}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	forwarded, ok := sender.events["video_channel.event.motion"]
+	if !ok || len(sender.events) != 1 {
+		t.Fatalf("forwarded %v, want only video_channel.event.motion", sender.events)
+	}
+	if !reflect.DeepEqual(forwarded.ObjectIDs, []string{"video_channel.7.3"}) ||
+		!reflect.DeepEqual(forwarded.ImageURLs, []string{"/public/a.jpg"}) ||
+		forwarded.Properties["channel_number"] != "3" || forwarded.Properties["legacy_event_key"] != MOTION_DETECTION {
+		t.Errorf("forwarded event = %+v", forwarded)
+	}
+	got := events()
+	if len(got) != 1 || got[0].key != VIDEOLOSS {
+		t.Errorf("the topology service got %v, want only the unmapped %s", got, VIDEOLOSS)
+	}
+}
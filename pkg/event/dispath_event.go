@@ -1,101 +1,180 @@
 package event
 
 import (
-	"bytes"
-	"fmt"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
 )
 
+// DEFAULT_EVENT_HOST is the host of the topology service that receives misc
+// events when NewDispatcher is given no DriverHub host.
+const DEFAULT_EVENT_HOST = "netsocs.local"
+
+// Fields are the optional fields of a misc event. Zero fields are left out.
+type Fields struct {
+	ImageURL      string
+	VideoURL      string
+	OriginalValue string
+	// ChannelNumber is the channel of the device the event is about; nil
+	// when it is about the device itself.
+	ChannelNumber *int
+	// PersonID identifies the person of the event: "nu", "em" or "vi"
+	// (Netsocs user, employee or visitor) followed by the person ID.
+	PersonID string
+	// Timestamp is when the event happened, in Unix seconds.
+	Timestamp int64
+}
+
+// Dispatcher sends the misc events of a device to the topology service, and
+// optionally to the DriverHub, see Forward. Dispatch is safe for concurrent
+// use.
 type Dispatcher struct {
 	DriverKey string
-	Host      string
-	Port      int
-
-	// event fields
-	imageURL      *string
-	deviceId      *int
-	originalValue *string
-	channelNumber *int
-	personId      *string
-	videoURL      *string
-	timestamp     int64
+	// Host is the topology service host, or its base URL when it carries a
+	// scheme, in which case Port is ignored. Once the Dispatcher is shared,
+	// change Host and Port with SetHost and SetPort.
+	Host string
+	Port int
+
+	mu       sync.Mutex
+	deviceId int
+	forward  *Forwarding
+
+	// fields staged by the setters for DispatchAndResetFields
+	fields Fields
 }
 
+// NewDispatcher returns a Dispatcher for the events of deviceId. The events go
+// to the topology service on the machine of driverHubHost, at
+// DEFAULT_EVENT_PORT, as with NewEventDispatcher; without driverHubHost, to
+// DEFAULT_EVENT_HOST. SetHost and SetPort reach another topology service.
 func NewDispatcher(driverHubHost string, driverKey string, deviceId int) *Dispatcher {
 	return &Dispatcher{
 		DriverKey: driverKey,
-		// Default values
-		Host:     "netsocs.local",
-		Port:     3070,
-		deviceId: &deviceId,
+		Host:      topologyHost(driverHubHost),
+		Port:      DEFAULT_EVENT_PORT,
+		deviceId:  deviceId,
+	}
+}
+
+// topologyHost returns the host name of driverHubHost, which may carry a
+// scheme, a port and a path, or DEFAULT_EVENT_HOST when it has none.
+func topologyHost(driverHubHost string) string {
+	raw := strings.TrimSpace(driverHubHost)
+	if raw == "" {
+		return DEFAULT_EVENT_HOST
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return DEFAULT_EVENT_HOST
+	}
+	return u.Hostname()
+}
+
+// SetHost sets Host, for a Dispatcher in use by other goroutines.
+func (e *Dispatcher) SetHost(host string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Host = host
+}
+
+// SetPort sets Port, for a Dispatcher in use by other goroutines.
+func (e *Dispatcher) SetPort(port int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Port = port
+}
+
+// Forward also sends the events whose key f maps to the DriverHub
+// /objects/events endpoint. It replaces any earlier forwarding.
+func (e *Dispatcher) Forward(f Forwarding) error {
+	if f.Sender == nil {
+		return errors.New("event: forwarding needs a Sender")
 	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.forward = &f
+	return nil
 }
 
+// Dispatch sends an event of the device. It does not touch the fields staged
+// by the setters.
+func (e *Dispatcher) Dispatch(eventKey string, fields Fields) error {
+	e.mu.Lock()
+	host, port, deviceID, forward := e.Host, e.Port, e.deviceId, e.forward
+	e.mu.Unlock()
+	return dispatch(host, port, eventKey, deviceID, fields, forward)
+}
+
+// The setters stage fields for the next DispatchAndResetFields. Goroutines
+// sharing a Dispatcher should call Dispatch instead, which takes the fields
+// of one event at once.
+
 func (e *Dispatcher) SetImageURL(imageURL string) {
-	e.imageURL = &imageURL
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fields.ImageURL = imageURL
 }
 
 func (e *Dispatcher) SetTimestamp(timestamp int64) {
-	e.timestamp = timestamp
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fields.Timestamp = timestamp
 }
 
 func (e *Dispatcher) SetOriginalValue(originalValue string) {
-	e.originalValue = &originalValue
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fields.OriginalValue = originalValue
 }
 
 func (e *Dispatcher) SetChannelNumber(channelNumber int) {
-	e.channelNumber = &channelNumber
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fields.ChannelNumber = &channelNumber
 }
 
 func (e *Dispatcher) SetPersonId(personId string) {
-	e.personId = &personId
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fields.PersonID = personId
 }
 
 func (e *Dispatcher) SetVideoURL(videoURL string) {
-	e.videoURL = &videoURL
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fields.VideoURL = videoURL
 }
 
+// DispatchAndResetFields sends an event with the staged fields, and clears
+// them, the timestamp excepted, for the next event.
 func (e *Dispatcher) DispatchAndResetFields(eventKey string) error {
-	buff := "{"
-	if e.imageURL != nil {
-		buff += "\"eventPhotoUrl\": \"" + *e.imageURL + "\","
-	}
-	if e.deviceId != nil {
-		buff += fmt.Sprintf("\"deviceId\": %d,", *e.deviceId)
-	}
-	if e.originalValue != nil {
-		buff += "\"originalValue\": \"" + *e.originalValue + "\","
-	}
-	if e.channelNumber != nil {
-		buff += fmt.Sprintf("\"deviceByProps\": [ \"\"parentDevice\": %d\", \"\"channelNumber\": %d\"],", *e.deviceId, *e.channelNumber)
-	}
-	if e.personId != nil {
-		userType, userId, err := getUserTypeAndUserIdFromPersonID(*e.personId)
-		if err != nil {
-			return err
-		}
-		buff += fmt.Sprintf("\"userType\": %d, \"userId\": %d,", userType, userId)
+	e.mu.Lock()
+	fields := e.fields
+	e.fields = Fields{Timestamp: fields.Timestamp}
+	host, port, deviceID, forward := e.Host, e.Port, e.deviceId, e.forward
+	e.mu.Unlock()
+	return dispatch(host, port, eventKey, deviceID, fields, forward)
+}
+
+// dispatch sends an event to the topology service and, when forward maps its
+// key, to the DriverHub.
+func dispatch(host string, port int, eventKey string, deviceID int, fields Fields, forward *Forwarding) error {
+	p, err := newPayload(deviceID, fields)
+	if err != nil {
+		return err
 	}
-	if e.videoURL != nil {
-		buff += "\"eventVideoUrl\": \"" + *e.videoURL + "\","
+	eventType, forwarded := forward.eventType(eventKey)
+	var errs []error
+	if !forwarded || !forward.Exclusive {
+		errs = append(errs, postPayload(host, port, eventKey, p))
 	}
-
-	if e.timestamp != 0 {
-		buff += fmt.Sprintf("\"timestamp\": %d,", e.timestamp)
+	if forwarded {
+		errs = append(errs, forward.send(eventType, eventKey, deviceID, fields))
 	}
-
-	buff = buff[:len(buff)-1] + "}"
-	body := []byte(buff)
-	err := postEvent(e.Host, e.Port, eventKey, bytes.NewReader(body))
-
-	// Reset fields
-	e.imageURL = nil
-	e.originalValue = nil
-	e.channelNumber = nil
-	e.personId = nil
-	e.videoURL = nil
-
-	return err
-
+	return errors.Join(errs...)
 }
-
-// Path: pkg/event/main.go
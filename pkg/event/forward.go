@@ -0,0 +1,79 @@
+package event
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/objects"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
+)
+
+// EventSender dispatches events to the DriverHub /objects/events endpoint.
+// *client.NetsocsDriverClient implements it.
+type EventSender interface {
+	DispatchEventType(eventType string, event objects.Event) (objects.EventDispatchResponse, error)
+}
+
+// Forwarding sends legacy misc events to the DriverHub as well, so a driver
+// can move to the objects event API one event key at a time. Each forwarded
+// event carries its fields as properties: device_id, channel_number,
+// original_value, person_id and legacy_event_key, and its image and video
+// URLs as media.
+type Forwarding struct {
+	Sender EventSender
+	// EventTypes maps legacy event keys, such as MOTION_DETECTION, to the
+	// event types registered with AddEventTypes, such as
+	// "video_channel.event.motion". Keys it does not map are not forwarded.
+	EventTypes map[string]string
+	// ObjectIDs returns the objects a forwarded event relates to. nil relates
+	// it to none.
+	ObjectIDs func(deviceID int, fields Fields) []string
+	// Exclusive stops sending the mapped keys to the topology service.
+	Exclusive bool
+}
+
+// eventType returns the modern event type of a legacy key. f may be nil.
+func (f *Forwarding) eventType(eventKey string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	eventType, ok := f.EventTypes[eventKey]
+	return eventType, ok && eventType != ""
+}
+
+func (f *Forwarding) send(eventType, eventKey string, deviceID int, fields Fields) error {
+	event := objects.Event{
+		Properties: map[string]string{
+			"device_id":        strconv.Itoa(deviceID),
+			"legacy_event_key": eventKey,
+		},
+	}
+	if f.ObjectIDs != nil {
+		event.ObjectIDs = f.ObjectIDs(deviceID, fields)
+	}
+	if fields.ChannelNumber != nil {
+		event.Properties["channel_number"] = strconv.Itoa(*fields.ChannelNumber)
+	}
+	if fields.OriginalValue != "" {
+		event.Properties["original_value"] = fields.OriginalValue
+	}
+	if fields.PersonID != "" {
+		event.Properties["person_id"] = fields.PersonID
+	}
+	if fields.ImageURL != "" {
+		event.ImageURLs = []string{fields.ImageURL}
+	}
+	if fields.VideoURL != "" {
+		event.VideoURLs = []string{fields.VideoURL}
+	}
+	if fields.Timestamp != 0 {
+		event.Timestamp = time.Unix(fields.Timestamp, 0)
+	}
+	_, err := f.Sender.DispatchEventType(eventType, event)
+	if errors.Is(err, outbox.ErrQueued) {
+		// Delivered once the hub is reachable again.
+		return nil
+	}
+	return err
+}
@@ -1,10 +1,11 @@
 package event
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"sync"
 )
 
 // Deprecated: Use `Dispatcher` instead.
@@ -12,6 +13,9 @@ type EventDispatcher struct {
 	host string
 	port int
 	key  string
+
+	mu      sync.Mutex
+	forward *Forwarding
 }
 
 const DEFAULT_EVENT_PORT = 3070
@@ -25,68 +29,61 @@ func NewEventDispatcher(host string, key string) *EventDispatcher {
 }
 
 func (e *EventDispatcher) SetPort(port int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.port = port
 }
 
-func (e *EventDispatcher) Dispatch(eventKey string, deviceID int) error {
-	bodyString := fmt.Sprintf(`{"deviceId": %d }`, deviceID)
-	body := []byte(bodyString)
-	bodyReader := bytes.NewReader(body)
+// Forward also sends the events whose key f maps to the DriverHub, see
+// Dispatcher.Forward.
+func (e *EventDispatcher) Forward(f Forwarding) error {
+	if f.Sender == nil {
+		return errors.New("event: forwarding needs a Sender")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.forward = &f
+	return nil
+}
 
-	err := postEvent(e.host, e.port, eventKey, bodyReader)
-	return err
+func (e *EventDispatcher) dispatch(eventKey string, deviceID int, fields Fields) error {
+	e.mu.Lock()
+	port, forward := e.port, e.forward
+	e.mu.Unlock()
+	return dispatch(e.host, port, eventKey, deviceID, fields, forward)
+}
+
+func (e *EventDispatcher) Dispatch(eventKey string, deviceID int) error {
+	return e.dispatch(eventKey, deviceID, Fields{})
 }
-func (e *EventDispatcher) DispatchWithOriginalValue(eventKey string, deviceID int, originalValue string) error {
-	bodyString := fmt.Sprintf(`{"deviceId": %d, "originalValue": "%s" }`, deviceID, originalValue)
-	body := []byte(bodyString)
-	bodyReader := bytes.NewReader(body)
 
-	err := postEvent(e.host, e.port, eventKey, bodyReader)
-	return err
+func (e *EventDispatcher) DispatchWithOriginalValue(eventKey string, deviceID int, originalValue string) error {
+	return e.dispatch(eventKey, deviceID, Fields{OriginalValue: originalValue})
 }
 
 func (e *EventDispatcher) DispatchWithChannels(eventKey string, deviceID int, channelNumber int) error {
-	bodyString := fmt.Sprintf(`{"deviceId": %d, "deviceByProps": [ "\"parentDevice\": %d", "\"channelNumber\": %d"]}`, deviceID, deviceID, channelNumber)
-	body := []byte(bodyString)
-	bodyReader := bytes.NewReader(body)
-	err := postEvent(e.host, e.port, eventKey, bodyReader)
-	return err
+	return e.dispatch(eventKey, deviceID, Fields{ChannelNumber: &channelNumber})
 }
+
 func (e *EventDispatcher) DispatchWithChannelsAndOriginalValue(eventKey string, deviceID int, channelNumber int, originalValue string) error {
-	bodyString := fmt.Sprintf(`{"deviceId": %d, "deviceByProps": [ "\"parentDevice\": %d", "\"channelNumber\": %d"], "originalValue": "%s"}`, deviceID, deviceID, channelNumber, originalValue)
-	body := []byte(bodyString)
-	bodyReader := bytes.NewReader(body)
-	err := postEvent(e.host, e.port, eventKey, bodyReader)
-	return err
+	return e.dispatch(eventKey, deviceID, Fields{ChannelNumber: &channelNumber, OriginalValue: originalValue})
 }
 
 func (e *EventDispatcher) DispatchWithPersonIDAndOriginalValue(eventKey string, deviceID int, personId string, originalValue string) error {
-	userType, userId, err := getUserTypeAndUserIdFromPersonID(personId)
-	if err != nil {
-		return err
-	}
-	bodyString := fmt.Sprintf(`{"deviceId": %d, "originalValue": "%s", "userType": %d, "userId": %d}`, deviceID, originalValue, userType, userId)
-	body := []byte(bodyString)
-	bodyReader := bytes.NewReader(body)
-	err = postEvent(e.host, e.port, eventKey, bodyReader)
-	return err
+	return e.dispatch(eventKey, deviceID, Fields{PersonID: personId, OriginalValue: originalValue})
 }
 
 func (e *EventDispatcher) DispatchWithPersonID(eventKey string, deviceID int, personId string) error {
-	userType, userId, err := getUserTypeAndUserIdFromPersonID(personId)
-	if err != nil {
-		return err
-	}
-	bodyString := fmt.Sprintf(`{"deviceId": %d, "userType": %d, "userId": %d}`, deviceID, userType, userId)
-	body := []byte(bodyString)
-	bodyReader := bytes.NewReader(body)
-	err = postEvent(e.host, e.port, eventKey, bodyReader)
-	return err
+	return e.dispatch(eventKey, deviceID, Fields{PersonID: personId})
 }
 
+// DispatchWithFile sends the content of file as the event body, unchanged.
+// It is not forwarded.
 func (e *EventDispatcher) DispatchWithFile(eventKey string, deviceID int, file os.File) error {
-	err := postEvent(e.host, e.port, eventKey, &file)
-	return err
+	e.mu.Lock()
+	port := e.port
+	e.mu.Unlock()
+	return postEvent(e.host, port, eventKey, &file)
 }
 
 func getUserTypeAndUserIdFromPersonID(personId string) (userType int, userId int, err error) {
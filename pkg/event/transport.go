@@ -1,8 +1,11 @@
 package event
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
@@ -15,21 +18,77 @@ import (
 // assembled as it always was.
 func eventURL(host string, port int, eventKey string) string {
 	host = strings.TrimSuffix(strings.TrimSpace(host), "/")
+	eventKey = url.PathEscape(eventKey)
 	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
 		return fmt.Sprintf("%s/v1/topologia/misc/%s", host, eventKey)
 	}
 	return fmt.Sprintf("http://%s:%d/v1/topologia/misc/%s", host, port, eventKey)
 }
 
+// payload is the body of a misc event. Fields are written in the order the
+// topology service always received them.
+type payload struct {
+	EventPhotoURL string   `json:"eventPhotoUrl,omitempty"`
+	DeviceID      int      `json:"deviceId"`
+	OriginalValue *string  `json:"originalValue,omitempty"`
+	DeviceByProps []string `json:"deviceByProps,omitempty"`
+	UserType      *int     `json:"userType,omitempty"`
+	UserID        *int     `json:"userId,omitempty"`
+	EventVideoURL string   `json:"eventVideoUrl,omitempty"`
+	Timestamp     int64    `json:"timestamp,omitempty"`
+}
+
+// newPayload encodes the fields of an event of deviceID.
+func newPayload(deviceID int, fields Fields) (payload, error) {
+	p := payload{
+		EventPhotoURL: fields.ImageURL,
+		DeviceID:      deviceID,
+		EventVideoURL: fields.VideoURL,
+		Timestamp:     fields.Timestamp,
+	}
+	if fields.OriginalValue != "" {
+		p.OriginalValue = &fields.OriginalValue
+	}
+	if fields.ChannelNumber != nil {
+		// The topology service reads each entry as a "key": value pair.
+		p.DeviceByProps = []string{
+			fmt.Sprintf("%q: %d", "parentDevice", deviceID),
+			fmt.Sprintf("%q: %d", "channelNumber", *fields.ChannelNumber),
+		}
+	}
+	if fields.PersonID != "" {
+		userType, userID, err := getUserTypeAndUserIdFromPersonID(fields.PersonID)
+		if err != nil {
+			return payload{}, err
+		}
+		p.UserType, p.UserID = &userType, &userID
+	}
+	return p, nil
+}
+
+// postPayload encodes p and sends it to the misc-event endpoint.
+func postPayload(host string, port int, eventKey string, p payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return postEvent(host, port, eventKey, bytes.NewReader(body))
+}
+
 // postEvent sends the body through the shared SDK client, so HTTPS hosts honour
 // the TLS configuration in pkg/httpx.
 func postEvent(host string, port int, eventKey string, body io.Reader) error {
-	res, err := httpx.Client().Post(eventURL(host, port, eventKey), "application/json", body)
+	target := eventURL(host, port, eventKey)
+	res, err := httpx.Client().Post(target, "application/json", body)
 	if err != nil {
 		return err
 	}
 	// Drain and close so the connection returns to the pool.
 	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+		return httpx.ParseHubError(res.StatusCode, "POST", res.Request.URL.Path, data)
+	}
 	_, _ = io.Copy(io.Discard, res.Body)
 	return nil
 }
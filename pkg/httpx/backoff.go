@@ -0,0 +1,52 @@
+package httpx
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff describes a jittered exponential backoff. The zero value is not
+// useful; start from DefaultBackoff and adjust the fields that matter.
+type Backoff struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+	// Max caps the delay, whatever the attempt number.
+	Max time.Duration
+	// Multiplier grows the delay on every attempt (2 doubles it).
+	Multiplier float64
+	// Jitter is the fraction (0..1) of the delay that is randomized, so a
+	// fleet of drivers does not reconnect to a restarted hub in lockstep.
+	Jitter float64
+}
+
+// DefaultBackoff returns the policy the SDK uses to reconnect its websockets:
+// 500ms, doubling up to 30s, with 20% jitter.
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:    500 * time.Millisecond,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// Delay returns how long to wait before the given attempt (0-based).
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		delay = delay * (1 - jitter + 2*jitter*rand.Float64())
+	}
+	return time.Duration(delay)
+}
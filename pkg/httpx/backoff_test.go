@@ -0,0 +1,30 @@
+package httpx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	b := httpx.Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for attempt, w := range want {
+		if got := b.Delay(attempt); got != w*time.Millisecond {
+			t.Fatalf("attempt %d: got %s, want %s", attempt, got, w*time.Millisecond)
+		}
+	}
+}
+
+func TestBackoffJitterStaysInRange(t *testing.T) {
+	b := httpx.Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+
+	for i := 0; i < 100; i++ {
+		got := b.Delay(0)
+		if got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("jittered delay %s out of [800ms, 1.2s]", got)
+		}
+	}
+}
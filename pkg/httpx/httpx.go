@@ -8,44 +8,91 @@
 //
 //	tls: failed to verify certificate: x509: certificate signed by unknown authority
 //
-// The websocket connections of the SDK already accepted those certificates;
-// every REST call, upload and event dispatch now goes through the transport
-// built here, so all of them behave the same way and work out of the box, with
-// no configuration.
+// By default every REST call, upload, event dispatch and websocket of the SDK
+// accepts those certificates, with no configuration. Installations that need
+// real verification configure it with ConfigureTLS, the NETSOCS_TLS_*
+// environment variables or the tls_* fields of driver.netsocs.json: a custom
+// CA bundle, a client certificate for mutual TLS, public key pinning and a
+// strict mode without the accept-any-certificate fallback (see TLSOptions).
 package httpx
 
 import (
 	"crypto/tls"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
 )
 
 var (
-	once      sync.Once
-	transport *http.Transport
-	client    *http.Client
+	once sync.Once
+	// shared is replaced, not mutated, when ConfigureTLS changes the settings.
+	shared       atomic.Pointer[http.Transport]
+	roundTripper http.RoundTripper
+	client       *http.Client
 )
 
 // TLSConfig returns the TLS configuration used by the SDK. The returned value
 // is a fresh copy, safe to mutate by the caller.
 func TLSConfig() *tls.Config {
-	return &tls.Config{
-		// The DriverHub is commonly served with a self-signed certificate or
-		// one issued by a private CA that the host does not trust.
-		//nolint:gosec // deliberate: on-premise DriverHubs use private CAs
-		InsecureSkipVerify: true,
-	}
+	initShared()
+	return currentTLSConfig()
 }
 
 func initShared() {
 	once.Do(func() {
-		transport = newTransport()
-		client = &http.Client{Transport: transport}
+		configureFromEnv()
+		shared.Store(newTransport())
+		// Retries wrap the traffic controls and the instrumentation, so every
+		// attempt is limited, counted and traced.
+		roundTripper = retryTransport{next: trafficTransport{next: instrumentedTransport{next: sharedTransport{}}}}
+		client = &http.Client{Transport: roundTripper}
 	})
 }
 
+// configureFromEnv applies the NETSOCS_TLS_* variables, if any. A setting
+// that cannot be applied must not silently fall back to accepting any
+// certificate, so every TLS handshake then fails with its error.
+func configureFromEnv() {
+	set := false
+	for _, name := range []string{EnvTLSCAFile, EnvTLSCertFile, EnvTLSKeyFile, EnvTLSPinnedSPKI, EnvTLSStrict} {
+		if os.Getenv(name) != "" {
+			set = true
+		}
+	}
+	if !set {
+		return
+	}
+	opts, err := TLSOptionsFromEnv(TLSOptions{})
+	var config *tls.Config
+	if err == nil {
+		config, err = buildTLSConfig(opts)
+	}
+	if err != nil {
+		logger.Logger().Errorw("invalid TLS settings in the environment, TLS connections will fail", "error", err)
+		err = fmt.Errorf("invalid TLS settings in the environment: %w", err)
+		config = &tls.Config{
+			VerifyConnection: func(tls.ConnectionState) error { return err },
+		}
+	}
+	tlsMu.Lock()
+	tlsBase = config
+	tlsMu.Unlock()
+}
+
+// sharedTransport forwards to the current shared transport, so clients
+// handed out before ConfigureTLS pick up the new settings.
+type sharedTransport struct{}
+
+func (sharedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return shared.Load().RoundTrip(req)
+}
+
 func newTransport() *http.Transport {
 	var t *http.Transport
 	if def, ok := http.DefaultTransport.(*http.Transport); ok {
@@ -60,21 +107,23 @@ func newTransport() *http.Transport {
 			ExpectContinueTimeout: time.Second,
 		}
 	}
-	t.TLSClientConfig = TLSConfig()
+	t.TLSClientConfig = currentTLSConfig()
 	t.TLSHandshakeTimeout = 15 * time.Second
 	return t
 }
 
 // Transport returns the shared *http.Transport. Do not mutate it; use
-// CloneTransport when a customized copy is needed.
+// CloneTransport when a customized copy is needed. ConfigureTLS replaces it,
+// so do not keep it either.
 func Transport() *http.Transport {
 	initShared()
-	return transport
+	return shared.Load()
 }
 
 // CloneTransport returns a private copy of the SDK transport, ready to be
 // customized without affecting other callers.
 func CloneTransport() *http.Transport {
+	initShared()
 	return newTransport()
 }
 
@@ -88,7 +137,8 @@ func Client() *http.Client {
 // NewClient returns a new *http.Client sharing the SDK transport, with the
 // given timeout (zero means no timeout).
 func NewClient(timeout time.Duration) *http.Client {
-	return &http.Client{Transport: Transport(), Timeout: timeout}
+	initShared()
+	return &http.Client{Transport: roundTripper, Timeout: timeout}
 }
 
 // Do performs the request with the shared client.
@@ -0,0 +1,133 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Sentinels matched by a *HubError with errors.Is, whatever the wording of
+// the hub response.
+var (
+	// ErrAlreadyExists matches the hub refusing to create something that
+	// exists: ERR_ITEM_ALREADY_EXIST, a "Duplicate entry" or a 409.
+	ErrAlreadyExists = errors.New("driverhub: already exists")
+	// ErrObjectDisabled matches a write to an object disabled on the hub.
+	ErrObjectDisabled = errors.New("driverhub: object is disabled")
+	// ErrNotFound matches a 404, or a hub message reporting a missing item.
+	ErrNotFound = errors.New("driverhub: not found")
+)
+
+// HubError is a request the DriverHub answered with an error. Match it with
+// errors.As, or its kind with errors.Is and the sentinels above.
+type HubError struct {
+	// StatusCode is the HTTP status of the response. For a per-item error in
+	// a batch response, it is the status of the batch.
+	StatusCode int
+	// Code is the hub error code, such as ERR_ITEM_ALREADY_EXIST, when the
+	// response carries one.
+	Code string
+	// Message is the hub error message, or the response body when it has no
+	// recognisable structure.
+	Message string
+	Method  string
+	Path    string
+}
+
+// Error implements error.
+func (e *HubError) Error() string {
+	var b strings.Builder
+	b.WriteString("driverhub")
+	if e.Method != "" || e.Path != "" {
+		fmt.Fprintf(&b, " %s %s", e.Method, e.Path)
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, ": %d", e.StatusCode)
+	}
+	if e.Code != "" {
+		fmt.Fprintf(&b, " %s", e.Code)
+	}
+	if e.Message != "" && e.Message != e.Code {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	return b.String()
+}
+
+// Is reports whether target is the sentinel matching e.
+func (e *HubError) Is(target error) bool {
+	switch target {
+	case ErrObjectDisabled:
+		return e.hasCode("DISABLED") || e.says("is disabled")
+	case ErrAlreadyExists:
+		return e.StatusCode == http.StatusConflict || e.hasCode("ALREADY_EXIST") ||
+			e.says("duplicate entry") || e.says("already exist")
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.hasCode("NOT_FOUND") || e.says("not found")
+	}
+	return false
+}
+
+func (e *HubError) hasCode(part string) bool {
+	return strings.Contains(strings.ToUpper(e.Code), part)
+}
+
+func (e *HubError) says(phrase string) bool {
+	return strings.Contains(strings.ToLower(e.Message), phrase)
+}
+
+// errorCodePattern finds a hub error code in a plain-text body.
+var errorCodePattern = regexp.MustCompile(`\bERR_[A-Z0-9_]+\b`)
+
+// ParseHubError builds the HubError of an error response from its status,
+// request and body. The body may be JSON, with the code in "code" or
+// "error_code" and the message in "message", "error" or "detail", or plain
+// text, in which an ERR_ code is looked for.
+func ParseHubError(statusCode int, method, path string, body []byte) *HubError {
+	e := &HubError{StatusCode: statusCode, Method: method, Path: path}
+	text := strings.TrimSpace(string(body))
+
+	var fields map[string]any
+	if json.Unmarshal(body, &fields) == nil {
+		e.Code = firstString(fields, "code", "error_code", "errorCode")
+		e.Message = firstString(fields, "message", "error", "detail", "msg")
+		if e.Code == "" && errorCodePattern.MatchString(e.Message) {
+			e.Code = errorCodePattern.FindString(e.Message)
+		}
+		if e.Code != "" || e.Message != "" {
+			return e
+		}
+	}
+
+	e.Message = text
+	e.Code = errorCodePattern.FindString(text)
+	if e.Message == "" {
+		e.Message = http.StatusText(statusCode)
+	}
+	return e
+}
+
+func firstString(fields map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := fields[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// NewHubError returns the HubError of a resty response with an error status.
+func NewHubError(resp *resty.Response) *HubError {
+	method, path := "", ""
+	if resp.Request != nil {
+		method = resp.Request.Method
+		if raw := resp.Request.RawRequest; raw != nil {
+			path = raw.URL.Path
+		}
+	}
+	return ParseHubError(resp.StatusCode(), method, path, resp.Body())
+}
@@ -0,0 +1,75 @@
+package httpx_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
+
+func TestParseHubError(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		status  int
+		body    string
+		code    string
+		message string
+		is      error
+	}{
+		{"json code and message", 400, `{"code":"ERR_ITEM_ALREADY_EXIST","message":"item relay.1 exists"}`, "ERR_ITEM_ALREADY_EXIST", "item relay.1 exists", httpx.ErrAlreadyExists},
+		{"json error with code", 400, `{"error":"ERR_ITEM_ALREADY_EXIST"}`, "ERR_ITEM_ALREADY_EXIST", "ERR_ITEM_ALREADY_EXIST", httpx.ErrAlreadyExists},
+		{"plain text with code", 500, "insert failed: ERR_ITEM_ALREADY_EXIST", "ERR_ITEM_ALREADY_EXIST", "insert failed: ERR_ITEM_ALREADY_EXIST", httpx.ErrAlreadyExists},
+		{"duplicate entry", 400, "Error 1062: Duplicate entry 'x' for key 'PRIMARY'", "", "Error 1062: Duplicate entry 'x' for key 'PRIMARY'", httpx.ErrAlreadyExists},
+		{"conflict", 409, "", "", "Conflict", httpx.ErrAlreadyExists},
+		{"disabled", 400, "object is disabled\n", "", "object is disabled", httpx.ErrObjectDisabled},
+		{"json not found", 400, `{"message":"object not found"}`, "", "object not found", httpx.ErrNotFound},
+		{"404", 404, "404 page not found", "", "404 page not found", httpx.ErrNotFound},
+		{"other", 400, "all event types failed to create", "", "all event types failed to create", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := httpx.ParseHubError(tt.status, http.MethodPost, "/objects", []byte(tt.body))
+			if err.StatusCode != tt.status || err.Code != tt.code || err.Message != tt.message {
+				t.Fatalf("got %+v", err)
+			}
+			for _, sentinel := range []error{httpx.ErrAlreadyExists, httpx.ErrObjectDisabled, httpx.ErrNotFound} {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.is) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHubError_Error(t *testing.T) {
+	err := httpx.ParseHubError(400, http.MethodPut, "/objects/states/relay.1", []byte(`{"code":"ERR_OBJECT_DISABLED","message":"object is disabled"}`))
+	if got, want := err.Error(), "driverhub PUT /objects/states/relay.1: 400 ERR_OBJECT_DISABLED: object is disabled"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestNewHubError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "object not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	resp, err := httpx.Resty().R().Get(srv.URL + "/objects/states/relay.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := fmt.Errorf("error setting state: %w", httpx.NewHubError(resp))
+
+	var hubErr *httpx.HubError
+	if !errors.As(wrapped, &hubErr) {
+		t.Fatalf("%v is not a HubError", wrapped)
+	}
+	if hubErr.StatusCode != 404 || hubErr.Method != http.MethodGet || hubErr.Path != "/objects/states/relay.1" || hubErr.Message != "object not found" {
+		t.Fatalf("got %+v", hubErr)
+	}
+	if !errors.Is(wrapped, httpx.ErrNotFound) {
+		t.Fatal("a wrapped 404 does not match ErrNotFound")
+	}
+}
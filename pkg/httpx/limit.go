@@ -0,0 +1,409 @@
+package httpx
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+)
+
+var (
+	// ErrRateLimited is returned, wrapped, for a request the client-side rate
+	// limit drops.
+	ErrRateLimited = errors.New("httpx: client-side rate limit exceeded")
+	// ErrCircuitOpen is returned, wrapped, for a request not sent because the
+	// hub failed too many requests in a row.
+	ErrCircuitOpen = errors.New("httpx: circuit open, the driverhub is considered unhealthy")
+)
+
+// EndpointClass groups DriverHub endpoints that share a rate limit.
+type EndpointClass string
+
+const (
+	// ClassStates covers object state writes and reads (/objects/states...).
+	ClassStates EndpointClass = "states"
+	// ClassEvents covers event dispatches and updates (/objects/events...).
+	ClassEvents EndpointClass = "events"
+	// ClassUploads covers file and snapshot uploads (.../upload).
+	ClassUploads EndpointClass = "uploads"
+	// ClassOther is every other request.
+	ClassOther EndpointClass = "other"
+)
+
+// ClassOf returns the endpoint class of a request URL path. The hub may be
+// mounted under a prefix, so the path is matched anywhere.
+func ClassOf(path string) EndpointClass {
+	switch {
+	case strings.Contains(path, "/objects/states"):
+		return ClassStates
+	case strings.Contains(path, "/objects/events"):
+		return ClassEvents
+	case strings.HasSuffix(path, "/upload"):
+		return ClassUploads
+	}
+	return ClassOther
+}
+
+// LimitMode decides what happens to a request over the rate limit.
+type LimitMode string
+
+const (
+	// LimitQueue makes the request wait for its turn, up to MaxWait and
+	// while its context allows.
+	LimitQueue LimitMode = "queue"
+	// LimitDrop fails the request at once with ErrRateLimited.
+	LimitDrop LimitMode = "drop"
+)
+
+// RateLimit is a token bucket: Burst requests may be sent at once, then Rate
+// per second. The zero value does not limit.
+type RateLimit struct {
+	// Rate is the sustained number of requests per second. 0 disables the
+	// limit.
+	Rate float64
+	// Burst is the number of requests that may be sent back to back. Values
+	// below 1 mean 1.
+	Burst int
+	// Mode is LimitQueue (the default) or LimitDrop.
+	Mode LimitMode
+	// MaxWait bounds the wait in LimitQueue mode: a request that would wait
+	// longer is dropped. 0 waits as long as the request context allows.
+	MaxWait time.Duration
+}
+
+// CircuitBreakerPolicy opens the circuit of a host after FailureThreshold
+// consecutive failures (no response, or a 429 or 5xx): requests to it then
+// fail at once with ErrCircuitOpen for OpenFor, after which a single request
+// probes the hub and closes the circuit if it succeeds. The zero value
+// disables the breaker.
+type CircuitBreakerPolicy struct {
+	FailureThreshold int
+	OpenFor          time.Duration
+}
+
+var (
+	rateLimited = metrics.NewCounterVec("driver_hub_rate_limited_total",
+		"Requests held back by the client-side rate limit, by endpoint class and outcome (delayed or dropped).",
+		"class", "outcome")
+	rateLimitWaiting = metrics.NewGaugeVec("driver_hub_rate_limit_waiting",
+		"Requests waiting for the client-side rate limit, by endpoint class.", "class")
+	circuitOpen = metrics.NewGaugeVec("driver_hub_circuit_open",
+		"1 while the circuit breaker of a host is open or half-open, 0 when closed.", "host")
+	circuitRejected = metrics.NewCounterVec("driver_hub_circuit_rejected_total",
+		"Requests not sent because the circuit breaker of the host was open.", "host")
+)
+
+// SetRateLimit sets the rate limit of an endpoint class, for every client of
+// this package. The bucket starts full.
+func SetRateLimit(class EndpointClass, limit RateLimit) {
+	traffic.mu.Lock()
+	defer traffic.mu.Unlock()
+	traffic.buckets[class] = newBucket(class, limit)
+}
+
+// SetCircuitBreaker sets the circuit breaker policy, for every client of this
+// package. Circuits already open are closed.
+func SetCircuitBreaker(policy CircuitBreakerPolicy) {
+	traffic.mu.Lock()
+	defer traffic.mu.Unlock()
+	traffic.breaker = policy
+	for host := range traffic.circuits {
+		circuitOpen.Add(-circuitOpen.Value(host), host)
+	}
+	traffic.circuits = make(map[string]*circuit)
+}
+
+// LimiterState is a snapshot of the rate limit of an endpoint class.
+type LimiterState struct {
+	Limit RateLimit
+	// Tokens is the number of requests that may be sent right now. It is
+	// negative while requests are waiting.
+	Tokens  float64
+	Waiting int
+	// Allowed, Delayed and Dropped count requests since the limit was set.
+	Allowed uint64
+	Delayed uint64
+	Dropped uint64
+}
+
+// CircuitState is a snapshot of the circuit breaker of a host.
+type CircuitState struct {
+	State               string // "closed", "open" or "half-open"
+	ConsecutiveFailures int
+	OpenedAt            time.Time // zero while closed
+}
+
+// TrafficState is a snapshot of the outbound traffic controls, for
+// diagnostics.
+type TrafficState struct {
+	Limits   map[EndpointClass]LimiterState
+	Breaker  CircuitBreakerPolicy
+	Circuits map[string]CircuitState // by host
+}
+
+// Traffic returns the current state of the rate limits and circuit breakers.
+func Traffic() TrafficState {
+	traffic.mu.Lock()
+	defer traffic.mu.Unlock()
+	state := TrafficState{
+		Limits:   make(map[EndpointClass]LimiterState, len(traffic.buckets)),
+		Breaker:  traffic.breaker,
+		Circuits: make(map[string]CircuitState, len(traffic.circuits)),
+	}
+	for class, b := range traffic.buckets {
+		state.Limits[class] = b.state()
+	}
+	for host, c := range traffic.circuits {
+		state.Circuits[host] = c.state()
+	}
+	return state
+}
+
+var traffic = struct {
+	mu       sync.Mutex
+	buckets  map[EndpointClass]*bucket
+	breaker  CircuitBreakerPolicy
+	circuits map[string]*circuit
+}{
+	buckets:  make(map[EndpointClass]*bucket),
+	circuits: make(map[string]*circuit),
+}
+
+// trafficTransport applies the rate limits and the circuit breaker before
+// next. It sits below the retries, so every attempt is limited and counted.
+type trafficTransport struct {
+	next http.RoundTripper
+}
+
+func (t trafficTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	traffic.mu.Lock()
+	b := traffic.buckets[ClassOf(req.URL.Path)]
+	var c *circuit
+	if traffic.breaker.FailureThreshold > 0 {
+		c = traffic.circuits[req.URL.Host]
+		if c == nil {
+			c = &circuit{host: req.URL.Host, policy: traffic.breaker, status: circuitClosed}
+			traffic.circuits[req.URL.Host] = c
+		}
+	}
+	traffic.mu.Unlock()
+
+	var probe bool
+	if c != nil {
+		var err error
+		if probe, err = c.allow(); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	if b != nil {
+		if err := b.take(req); err != nil {
+			c.done(probe, nil, nil)
+			closeBody(req)
+			return nil, err
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	c.done(probe, resp, err)
+	return resp, err
+}
+
+// closeBody honours the RoundTripper contract for requests not sent.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// bucket is a token bucket. Requests reserve a token, possibly in the future,
+// and wait until then.
+type bucket struct {
+	class EndpointClass
+	limit RateLimit
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	waiting int
+	allowed uint64
+	delayed uint64
+	dropped uint64
+}
+
+func newBucket(class EndpointClass, limit RateLimit) *bucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	if limit.Mode == "" {
+		limit.Mode = LimitQueue
+	}
+	return &bucket{class: class, limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+func (b *bucket) take(req *http.Request) error {
+	if b.limit.Rate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	b.refill(time.Now())
+	b.tokens--
+	if b.tokens >= 0 {
+		b.allowed++
+		b.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+	if b.limit.Mode == LimitDrop || (b.limit.MaxWait > 0 && delay > b.limit.MaxWait) {
+		b.tokens++
+		b.dropped++
+		b.mu.Unlock()
+		rateLimited.Inc(string(b.class), "dropped")
+		return ErrRateLimited
+	}
+	b.waiting++
+	b.delayed++
+	b.mu.Unlock()
+	rateLimited.Inc(string(b.class), "delayed")
+	rateLimitWaiting.Inc(string(b.class))
+	defer rateLimitWaiting.Dec(string(b.class))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		b.mu.Lock()
+		b.waiting--
+		b.allowed++
+		b.mu.Unlock()
+		return nil
+	case <-req.Context().Done():
+		// Give the reserved token back.
+		b.mu.Lock()
+		b.waiting--
+		b.tokens++
+		b.mu.Unlock()
+		return req.Context().Err()
+	}
+}
+
+func (b *bucket) state() LimiterState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	return LimiterState{
+		Limit:   b.limit,
+		Tokens:  b.tokens,
+		Waiting: b.waiting,
+		Allowed: b.allowed,
+		Delayed: b.delayed,
+		Dropped: b.dropped,
+	}
+}
+
+const (
+	circuitClosed   = "closed"
+	circuitOpenName = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// circuit is the breaker of one host. A nil *circuit lets everything through.
+type circuit struct {
+	host   string
+	policy CircuitBreakerPolicy
+
+	mu       sync.Mutex
+	status   string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow lets a request through, or refuses it with ErrCircuitOpen. probe
+// reports whether the request is the one probing a half-open circuit; it is
+// passed back to done with the outcome.
+func (c *circuit) allow() (probe bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.status {
+	case circuitOpenName:
+		if time.Since(c.openedAt) < c.policy.OpenFor {
+			circuitRejected.Inc(c.host)
+			return false, ErrCircuitOpen
+		}
+		c.status = circuitHalfOpen
+		c.probing = true
+		return true, nil
+	case circuitHalfOpen:
+		if c.probing {
+			circuitRejected.Inc(c.host)
+			return false, ErrCircuitOpen
+		}
+		c.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records the outcome of a request allow let through. A nil resp and err
+// means the request was not sent. Only the probe moves a circuit out of open
+// or half-open: other requests completing then were sent before it opened.
+func (c *circuit) done(probe bool, resp *http.Response, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if probe {
+		c.probing = false
+	} else if c.status != circuitClosed {
+		return
+	}
+	if resp == nil && err == nil {
+		return
+	}
+	if err != nil && !retryableError(err) {
+		// Cancelled by the caller, or a TLS failure: says nothing about the
+		// health of the hub.
+		return
+	}
+	failed := err != nil || (resp != nil && retryableStatus(resp.StatusCode))
+	if !failed {
+		if c.status != circuitClosed {
+			logger.Logger().Infow("driverhub circuit closed", "host", c.host)
+			circuitOpen.Add(-1, c.host)
+		}
+		c.status = circuitClosed
+		c.failures = 0
+		c.openedAt = time.Time{}
+		return
+	}
+	c.failures++
+	if probe || c.failures >= c.policy.FailureThreshold {
+		if c.status == circuitClosed {
+			circuitOpen.Add(1, c.host)
+			logger.Logger().Warnw("driverhub circuit opened", "host", c.host, "failures", c.failures, "open_for", c.policy.OpenFor.String())
+		}
+		c.status = circuitOpenName
+		c.openedAt = time.Now()
+	}
+}
+
+func (c *circuit) state() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.status
+	if status == circuitOpenName && time.Since(c.openedAt) >= c.policy.OpenFor {
+		status = circuitHalfOpen
+	}
+	return CircuitState{State: status, ConsecutiveFailures: c.failures, OpenedAt: c.openedAt}
+}
@@ -0,0 +1,229 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
+
+func TestClassOf(t *testing.T) {
+	for path, want := range map[string]httpx.EndpointClass{
+		"/api/netsocs/dh/objects/states/relay.1": httpx.ClassStates,
+		"/objects/states-batch":                  httpx.ClassStates,
+		"/objects/events":                        httpx.ClassEvents,
+		"/objects/events/types/batch":            httpx.ClassEvents,
+		"/snapshots/upload":                      httpx.ClassUploads,
+		"/api/v1/upload":                         httpx.ClassUploads,
+		"/objects":                               httpx.ClassOther,
+	} {
+		if got := httpx.ClassOf(path); got != want {
+			t.Errorf("ClassOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func okServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getPath(ctx context.Context, rawURL string) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	res, err := httpx.Do(req)
+	if err == nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+func TestRateLimit_drop(t *testing.T) {
+	srv := okServer(t)
+	httpx.SetRateLimit(httpx.ClassEvents, httpx.RateLimit{Rate: 0.001, Burst: 2, Mode: httpx.LimitDrop})
+	t.Cleanup(func() { httpx.SetRateLimit(httpx.ClassEvents, httpx.RateLimit{}) })
+
+	for i := 0; i < 2; i++ {
+		if err := getPath(context.Background(), srv.URL+"/objects/events/e1"); err != nil {
+			t.Fatalf("request %d within the burst failed: %v", i, err)
+		}
+	}
+	if err := getPath(context.Background(), srv.URL+"/objects/events/e1"); !errors.Is(err, httpx.ErrRateLimited) {
+		t.Fatalf("got %v, want ErrRateLimited", err)
+	}
+	// Other classes are not limited.
+	if err := getPath(context.Background(), srv.URL+"/objects/states/relay.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	state := httpx.Traffic().Limits[httpx.ClassEvents]
+	if state.Allowed != 2 || state.Dropped != 1 || state.Limit.Burst != 2 {
+		t.Fatalf("unexpected limiter state: %+v", state)
+	}
+}
+
+func TestRateLimit_queue(t *testing.T) {
+	srv := okServer(t)
+	httpx.SetRateLimit(httpx.ClassStates, httpx.RateLimit{Rate: 50, Burst: 1})
+	t.Cleanup(func() { httpx.SetRateLimit(httpx.ClassStates, httpx.RateLimit{}) })
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := getPath(context.Background(), srv.URL+"/objects/states/relay.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One request at once, then one every 20ms.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("3 requests took %s, want at least 40ms", elapsed)
+	}
+	if state := httpx.Traffic().Limits[httpx.ClassStates]; state.Delayed != 2 || state.Allowed != 3 {
+		t.Fatalf("unexpected limiter state: %+v", state)
+	}
+
+	// A request that cannot wait long enough gives up.
+	httpx.SetRateLimit(httpx.ClassStates, httpx.RateLimit{Rate: 0.001, Burst: 1})
+	getPath(context.Background(), srv.URL+"/objects/states/relay.1")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := getPath(ctx, srv.URL+"/objects/states/relay.1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context error", err)
+	}
+	httpx.SetRateLimit(httpx.ClassStates, httpx.RateLimit{Rate: 0.001, Burst: 1, MaxWait: time.Second})
+	getPath(context.Background(), srv.URL+"/objects/states/relay.1")
+	if err := getPath(context.Background(), srv.URL+"/objects/states/relay.1"); !errors.Is(err, httpx.ErrRateLimited) {
+		t.Fatalf("got %v, want ErrRateLimited past MaxWait", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	httpx.SetRetryPolicy(httpx.RetryPolicy{MaxAttempts: 1})
+	t.Cleanup(func() { httpx.SetRetryPolicy(httpx.DefaultRetryPolicy()) })
+	httpx.SetCircuitBreaker(httpx.CircuitBreakerPolicy{FailureThreshold: 2, OpenFor: 50 * time.Millisecond})
+	t.Cleanup(func() { httpx.SetCircuitBreaker(httpx.CircuitBreakerPolicy{}) })
+
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	host := mustHost(t, srv.URL)
+
+	for i := 0; i < 2; i++ {
+		if err := getPath(context.Background(), srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := getPath(context.Background(), srv.URL); !errors.Is(err, httpx.ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("the open circuit let a request through: %d calls", calls.Load())
+	}
+	if state := httpx.Traffic().Circuits[host]; state.State != "open" || state.ConsecutiveFailures != 2 {
+		t.Fatalf("unexpected circuit state: %+v", state)
+	}
+	// An unreachable hub is what the outbox queues writes for.
+	if !httpx.Unreachable(0, httpx.ErrCircuitOpen) {
+		t.Fatal("an open circuit is not reported as unreachable")
+	}
+
+	// After OpenFor, a failed probe opens the circuit again...
+	time.Sleep(60 * time.Millisecond)
+	if err := getPath(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := getPath(context.Background(), srv.URL); !errors.Is(err, httpx.ErrCircuitOpen) {
+		t.Fatalf("got %v after a failed probe, want ErrCircuitOpen", err)
+	}
+
+	// ...and a successful one closes it.
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := getPath(context.Background(), srv.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state := httpx.Traffic().Circuits[host]; state.State != "closed" || state.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected circuit state: %+v", state)
+	}
+}
+
+// A request sent before the circuit opened does not end the probe when it
+// completes.
+func TestCircuitBreaker_onlyTheProbeEndsHalfOpen(t *testing.T) {
+	httpx.SetRetryPolicy(httpx.RetryPolicy{MaxAttempts: 1})
+	t.Cleanup(func() { httpx.SetRetryPolicy(httpx.DefaultRetryPolicy()) })
+	httpx.SetCircuitBreaker(httpx.CircuitBreakerPolicy{FailureThreshold: 1, OpenFor: 50 * time.Millisecond})
+	t.Cleanup(func() { httpx.SetCircuitBreaker(httpx.CircuitBreakerPolicy{}) })
+
+	arrived := make(chan string, 2)
+	release := map[string]chan struct{}{"/slow": make(chan struct{}), "/probe": make(chan struct{})}
+	finished := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wait, ok := release[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		arrived <- r.URL.Path
+		select {
+		case <-wait:
+		case <-finished:
+		}
+	}))
+	defer srv.Close()
+	defer close(finished) // unblocks the handlers when the test fails
+	host := mustHost(t, srv.URL)
+
+	slow := make(chan error, 1)
+	go func() { slow <- getPath(context.Background(), srv.URL+"/slow") }()
+	<-arrived
+	if err := getPath(context.Background(), srv.URL+"/fail"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	probe := make(chan error, 1)
+	go func() { probe <- getPath(context.Background(), srv.URL+"/probe") }()
+	<-arrived
+
+	close(release["/slow"])
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+	if state := httpx.Traffic().Circuits[host]; state.State != "half-open" {
+		t.Fatalf("a request sent before the circuit opened ended the probe: %+v", state)
+	}
+	if err := getPath(context.Background(), srv.URL+"/fail"); !errors.Is(err, httpx.ErrCircuitOpen) {
+		t.Fatalf("got %v while the probe is in flight, want ErrCircuitOpen", err)
+	}
+
+	close(release["/probe"])
+	if err := <-probe; err != nil {
+		t.Fatal(err)
+	}
+	if state := httpx.Traffic().Circuits[host]; state.State != "closed" {
+		t.Fatalf("the successful probe did not close the circuit: %+v", state)
+	}
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
+)
+
+var (
+	hubRequests = metrics.NewCounterVec("driver_hub_http_requests_total",
+		"HTTP requests sent through the SDK clients, by method and status class (2xx, 4xx, 5xx, or error when no response arrived).",
+		"method", "code")
+	hubRequestDuration = metrics.NewHistogramVec("driver_hub_http_request_duration_seconds",
+		"Duration of the HTTP requests sent through the SDK clients.", nil, "method")
+)
+
+// instrumentedTransport records the request metrics of every call made with
+// Client, NewClient and Resty and, when tracing is on, a client span whose
+// context is sent in the traceparent header.
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Start(req.Context(), req.Method)
+	defer span.End()
+	if span != nil {
+		span.SetAttribute("http.request.method", req.Method)
+		span.SetAttribute("server.address", req.URL.Host)
+		span.SetAttribute("url.path", req.URL.Path)
+		// A RoundTripper must not modify the caller's request.
+		req = req.Clone(ctx)
+		tracing.Inject(ctx, req.Header)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	hubRequestDuration.ObserveSince(start, req.Method)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode/100) + "xx"
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 400 {
+			span.SetStatus(tracing.StatusError, resp.Status)
+		}
+	} else {
+		span.RecordError(err)
+	}
+	hubRequests.Inc(req.Method, code)
+	return resp, err
+}
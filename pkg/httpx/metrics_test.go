@@ -0,0 +1,84 @@
+package httpx_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
+)
+
+func TestClientsRecordRequestMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	// An unusual method keeps these series apart from the other tests.
+	if _, err := httpx.Resty().R().Execute("REPORT", srv.URL); err != nil {
+		t.Fatalf("resty request failed: %v", err)
+	}
+	req, _ := http.NewRequest("REPORT", srv.URL+"/missing", nil)
+	res, err := httpx.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`driver_hub_http_requests_total{method="REPORT",code="2xx"} 1`,
+		`driver_hub_http_requests_total{method="REPORT",code="4xx"} 1`,
+		`driver_hub_http_request_duration_seconds_count{method="REPORT"} 2`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(line+"\n")) {
+			t.Errorf("missing %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestClientsRecordChildSpans(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.TraceparentHeader)
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer srv.Close()
+
+	ctx, parent := tracing.Start(context.Background(), "action.run")
+	if _, err := httpx.Resty().R().SetContext(ctx).Put(srv.URL + "/objects/states/relay.1"); err != nil {
+		t.Fatalf("resty request failed: %v", err)
+	}
+	parent.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	call := spans[0]
+	if call.Name != "PUT" || call.ParentSpanID != parent.SpanContext().SpanID || call.TraceID != parent.SpanContext().TraceID {
+		t.Errorf("call span is not a child of the action span: %+v", call)
+	}
+	if call.Attributes["url.path"] != "/objects/states/relay.1" || call.Attributes["http.response.status_code"] != http.StatusConflict {
+		t.Errorf("unexpected attributes: %v", call.Attributes)
+	}
+	if call.Status != tracing.StatusError {
+		t.Errorf("status = %q, want error", call.Status)
+	}
+	if want := tracing.FormatTraceparent(tracing.SpanContext{TraceID: call.TraceID, SpanID: call.SpanID}); traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
@@ -7,5 +7,6 @@ import "github.com/go-resty/resty/v2"
 //
 // Use it instead of resty.New() anywhere the SDK talks to the DriverHub.
 func Resty() *resty.Client {
-	return resty.New().SetTransport(Transport())
+	initShared()
+	return resty.New().SetTransport(roundTripper)
 }
@@ -0,0 +1,246 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+)
+
+// IdempotencyKeyHeader identifies the operation of a POST or PATCH, so that a
+// hub applying a request only once per key can tell a retry from a new
+// request. Set it with NewIdempotencyKey. Such requests are retried only with
+// RetryPolicy.RetryIdempotencyKeys, or when sent with WithRetry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy decides how the clients of this package retry a failed request.
+// A request is retried when no response arrived (connection refused or reset,
+// timeout other than the caller's context) or when the response is a 429,
+// 500, 502, 503 or 504, and only if:
+//
+//   - its method is idempotent (GET, HEAD, OPTIONS, PUT, DELETE), its
+//     context comes from WithRetry, or it carries an Idempotency-Key header
+//     and RetryIdempotencyKeys is set;
+//   - its body can be sent again (http.NewRequest and resty do this for
+//     in-memory bodies);
+//   - its context is not done.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, the first included. 1 or less
+	// disables retries.
+	MaxAttempts int
+	// Backoff paces the attempts when the hub gives no Retry-After.
+	Backoff Backoff
+	// MaxRetryAfter caps the Retry-After the SDK honours: when the hub asks
+	// to wait longer, its response is returned instead of retrying.
+	MaxRetryAfter time.Duration
+	// RetryIdempotencyKeys also retries POST and PATCH requests that carry
+	// an Idempotency-Key header. Set it only for a DriverHub known to apply
+	// a request once per key; otherwise a retry after a lost response can
+	// create a duplicate.
+	RetryIdempotencyKeys bool
+}
+
+// DefaultRetryPolicy returns the policy the SDK starts with: 4 attempts,
+// 200ms doubling up to 5s with 20% jitter, Retry-After honoured up to 30s,
+// and no retry of POST or PATCH requests unless sent with WithRetry.
+// That rides over a DriverHub rolling upgrade without holding a caller for
+// more than a few seconds.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   4,
+		Backoff:       Backoff{Initial: 200 * time.Millisecond, Max: 5 * time.Second, Multiplier: 2, Jitter: 0.2},
+		MaxRetryAfter: 30 * time.Second,
+	}
+}
+
+var retryPolicy atomic.Pointer[RetryPolicy]
+
+func init() {
+	p := DefaultRetryPolicy()
+	retryPolicy.Store(&p)
+}
+
+// SetRetryPolicy replaces the retry policy of every client of this package,
+// including the clients already handed out.
+func SetRetryPolicy(p RetryPolicy) {
+	retryPolicy.Store(&p)
+}
+
+// CurrentRetryPolicy returns the retry policy in use.
+func CurrentRetryPolicy() RetryPolicy {
+	return *retryPolicy.Load()
+}
+
+type noRetryKey struct{}
+
+// WithoutRetry returns a copy of ctx whose requests are sent once, for
+// callers that retry on their own.
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+type retryKey struct{}
+
+// WithRetry returns a copy of ctx whose requests are retried whatever their
+// method, for a POST or PATCH the caller can safely send twice, e.g. because
+// it takes the hub's "already exists" answer to a repeat as success.
+// WithoutRetry still wins.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+// NewIdempotencyKey returns a random key for IdempotencyKeyHeader. Generate
+// one per logical operation, not per attempt.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var hubRetries = metrics.NewCounterVec("driver_hub_http_retries_total",
+	"HTTP requests sent again by the SDK clients after a failed attempt, by method.",
+	"method")
+
+// retryTransport applies the retry policy on top of next.
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := CurrentRetryPolicy()
+	if policy.MaxAttempts <= 1 || !retryable(req, policy) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			req = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+		resp, err := t.next.RoundTrip(req)
+		if attempt+1 >= policy.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := policy.Backoff.Delay(attempt)
+		if err == nil {
+			if !retryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				if after > policy.MaxRetryAfter {
+					return resp, nil
+				}
+				delay = after
+			}
+			// Drain a little so the connection can be reused.
+			io.CopyN(io.Discard, resp.Body, 4<<10)
+			resp.Body.Close()
+		} else if !retryableError(err) {
+			return resp, err
+		}
+
+		logger.FromContext(ctx).Debugw("retrying hub request",
+			"method", req.Method, "path", req.URL.Path, "attempt", attempt+1, "retry_in", delay.String(), "status", statusOf(resp, err), "error", err)
+		hubRetries.Inc(req.Method)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryable reports whether req may be sent more than once under policy.
+func retryable(req *http.Request, policy RetryPolicy) bool {
+	if v, _ := req.Context().Value(noRetryKey{}).(bool); v {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if v, _ := req.Context().Value(retryKey{}).(bool); v {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return policy.RetryIdempotencyKeys && req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// retryableError reports whether a transport error may go away on its own.
+// TLS failures (an untrusted certificate, a handshake refused by the hub)
+// will not, and requests held back by the rate limit or the circuit breaker
+// must not be sent again right away.
+func retryableError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded), errors.Is(err, errPinMismatch),
+		errors.Is(err, ErrRateLimited), errors.Is(err, ErrCircuitOpen),
+		errors.As(err, &verifyErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return false
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		// A TLS alert sent by the hub.
+		return false
+	}
+	return true
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func statusOf(resp *http.Response, err error) int {
+	if err != nil || resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,220 @@
+package httpx_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
+
+// fastRetries makes the retries of the test quick.
+func fastRetries(t *testing.T) {
+	t.Helper()
+	previous := httpx.CurrentRetryPolicy()
+	httpx.SetRetryPolicy(httpx.RetryPolicy{
+		MaxAttempts:   3,
+		Backoff:       httpx.Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2},
+		MaxRetryAfter: time.Second,
+	})
+	t.Cleanup(func() { httpx.SetRetryPolicy(previous) })
+}
+
+// flakyServer fails the first failures requests with status, then answers
+// 200. It records the body and idempotency key of every request.
+type flakyServer struct {
+	*httptest.Server
+	calls atomic.Int32
+
+	mu     sync.Mutex
+	bodies []string
+	keys   []string
+}
+
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header) *flakyServer {
+	t.Helper()
+	s := &flakyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.keys = append(s.keys, r.Header.Get(httpx.IdempotencyKeyHeader))
+		s.mu.Unlock()
+		if s.calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestRetry_idempotentRequests(t *testing.T) {
+	fastRetries(t)
+	srv := newFlakyServer(t, 2, http.StatusBadGateway, nil)
+
+	resp, err := httpx.Resty().R().SetBody(`{"state":"on"}`).Put(srv.URL + "/objects/states/relay.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || srv.calls.Load() != 3 {
+		t.Fatalf("status %d after %d calls, want 200 after 3", resp.StatusCode(), srv.calls.Load())
+	}
+	for _, body := range srv.bodies {
+		if body != `{"state":"on"}` {
+			t.Errorf("retried with body %q", body)
+		}
+	}
+}
+
+func TestRetry_givesUpAfterMaxAttempts(t *testing.T) {
+	fastRetries(t)
+	srv := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+
+	resp, err := httpx.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || srv.calls.Load() != 3 {
+		t.Fatalf("status %d after %d calls, want 503 after 3", resp.StatusCode, srv.calls.Load())
+	}
+}
+
+func TestRetry_postNeedsIdempotencyKey(t *testing.T) {
+	fastRetries(t)
+	srv := newFlakyServer(t, 1, http.StatusBadGateway, nil)
+
+	resp, err := httpx.Resty().R().SetBody(`{}`).Post(srv.URL + "/objects/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusBadGateway || srv.calls.Load() != 1 {
+		t.Fatalf("POST without key: status %d after %d calls, want 502 after 1", resp.StatusCode(), srv.calls.Load())
+	}
+
+	srv = newFlakyServer(t, 1, http.StatusBadGateway, nil)
+	key := httpx.NewIdempotencyKey()
+	resp, err = httpx.Resty().R().SetHeader(httpx.IdempotencyKeyHeader, key).SetBody(`{}`).Post(srv.URL + "/objects/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusBadGateway || srv.calls.Load() != 1 {
+		t.Fatalf("POST with key, by default: status %d after %d calls, want 502 after 1", resp.StatusCode(), srv.calls.Load())
+	}
+
+	policy := httpx.CurrentRetryPolicy()
+	policy.RetryIdempotencyKeys = true
+	httpx.SetRetryPolicy(policy)
+	srv = newFlakyServer(t, 1, http.StatusBadGateway, nil)
+	resp, err = httpx.Resty().R().SetHeader(httpx.IdempotencyKeyHeader, key).SetBody(`{}`).Post(srv.URL + "/objects/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || srv.calls.Load() != 2 {
+		t.Fatalf("POST with key: status %d after %d calls, want 200 after 2", resp.StatusCode(), srv.calls.Load())
+	}
+	if srv.keys[0] != key || srv.keys[1] != key {
+		t.Errorf("keys sent %v, want %q twice", srv.keys, key)
+	}
+}
+
+func TestRetry_withRetry(t *testing.T) {
+	fastRetries(t)
+	srv := newFlakyServer(t, 1, http.StatusBadGateway, nil)
+
+	resp, err := httpx.Resty().R().SetContext(httpx.WithRetry(context.Background())).SetBody(`{}`).Post(srv.URL + "/objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || srv.calls.Load() != 2 {
+		t.Fatalf("POST WithRetry: status %d after %d calls, want 200 after 2", resp.StatusCode(), srv.calls.Load())
+	}
+
+	srv = newFlakyServer(t, 1, http.StatusBadGateway, nil)
+	ctx := httpx.WithoutRetry(httpx.WithRetry(context.Background()))
+	resp, err = httpx.Resty().R().SetContext(ctx).SetBody(`{}`).Post(srv.URL + "/objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusBadGateway || srv.calls.Load() != 1 {
+		t.Fatalf("POST WithRetry and WithoutRetry: status %d after %d calls, want 502 after 1", resp.StatusCode(), srv.calls.Load())
+	}
+}
+
+func TestRetry_retryAfter(t *testing.T) {
+	fastRetries(t)
+	srv := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}})
+	resp, err := httpx.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || srv.calls.Load() != 2 {
+		t.Fatalf("status %d after %d calls, want 200 after 2", resp.StatusCode, srv.calls.Load())
+	}
+
+	// Waiting longer than MaxRetryAfter is left to the caller.
+	srv = newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"120"}})
+	resp, err = httpx.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || srv.calls.Load() != 1 {
+		t.Fatalf("status %d after %d calls, want 429 after 1", resp.StatusCode, srv.calls.Load())
+	}
+}
+
+func TestRetry_connectionErrors(t *testing.T) {
+	fastRetries(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Reset the connection without answering.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := httpx.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 2 {
+		t.Fatalf("%d calls, want 2", calls.Load())
+	}
+}
+
+func TestRetry_withoutRetryAndCancellation(t *testing.T) {
+	fastRetries(t)
+	srv := newFlakyServer(t, 10, http.StatusBadGateway, nil)
+
+	req, _ := http.NewRequestWithContext(httpx.WithoutRetry(context.Background()), http.MethodGet, srv.URL, nil)
+	resp, err := httpx.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if srv.calls.Load() != 1 {
+		t.Fatalf("WithoutRetry: %d calls, want 1", srv.calls.Load())
+	}
+
+	httpx.SetRetryPolicy(httpx.RetryPolicy{MaxAttempts: 5, Backoff: httpx.Backoff{Initial: time.Hour}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := httpx.Do(req); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Fatalf("got %v, want the context error while waiting to retry", err)
+	}
+}
@@ -0,0 +1,217 @@
+package httpx
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TLSOptions configures how the SDK trusts the DriverHub (and any other HTTPS
+// or WSS endpoint reached through this package). The zero value keeps the
+// historical behaviour: any certificate is accepted.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system roots.
+	// Setting it turns certificate verification on.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and its key, sent
+	// when the server asks for one (mutual TLS). Both or neither must be set.
+	CertFile string
+	KeyFile  string
+	// PinnedSPKI are SHA-256 fingerprints of the SubjectPublicKeyInfo of
+	// trusted certificates, base64 ("sha256/" prefix optional) or hex. When
+	// set, a connection is accepted only if one of them matches a certificate
+	// of a verified chain or, without verification, the server certificate
+	// itself. Pins apply with or without verification, so they also secure
+	// self-signed hubs.
+	PinnedSPKI []string
+	// Strict always verifies the certificate chain, against the system roots
+	// and CAFile, and disables the accept-any-certificate fallback.
+	Strict bool
+}
+
+// Environment variables read by TLSOptionsFromEnv.
+const (
+	EnvTLSCAFile     = "NETSOCS_TLS_CA_FILE"
+	EnvTLSCertFile   = "NETSOCS_TLS_CERT_FILE"
+	EnvTLSKeyFile    = "NETSOCS_TLS_KEY_FILE"
+	EnvTLSPinnedSPKI = "NETSOCS_TLS_PINNED_SPKI" // comma-separated
+	EnvTLSStrict     = "NETSOCS_TLS_STRICT"
+)
+
+// TLSOptionsFromEnv returns base with the fields set in the environment
+// overridden.
+func TLSOptionsFromEnv(base TLSOptions) (TLSOptions, error) {
+	opts := base
+	if v := os.Getenv(EnvTLSCAFile); v != "" {
+		opts.CAFile = v
+	}
+	if v := os.Getenv(EnvTLSCertFile); v != "" {
+		opts.CertFile = v
+	}
+	if v := os.Getenv(EnvTLSKeyFile); v != "" {
+		opts.KeyFile = v
+	}
+	if v := os.Getenv(EnvTLSPinnedSPKI); v != "" {
+		opts.PinnedSPKI = nil
+		for _, pin := range strings.Split(v, ",") {
+			if pin = strings.TrimSpace(pin); pin != "" {
+				opts.PinnedSPKI = append(opts.PinnedSPKI, pin)
+			}
+		}
+	}
+	if v := os.Getenv(EnvTLSStrict); v != "" {
+		strict, err := strconv.ParseBool(v)
+		if err != nil {
+			return base, fmt.Errorf("%s: %w", EnvTLSStrict, err)
+		}
+		opts.Strict = strict
+	}
+	return opts, nil
+}
+
+var (
+	tlsMu sync.Mutex
+	// tlsBase is the configuration set by ConfigureTLS or the environment;
+	// nil until then.
+	tlsBase *tls.Config
+)
+
+var errPinMismatch = errors.New("tls: no certificate matches a pinned public key")
+
+// defaultTLSConfig is the configuration used when TLS is not configured.
+func defaultTLSConfig() *tls.Config {
+	return &tls.Config{
+		// The DriverHub is commonly served with a self-signed certificate or
+		// one issued by a private CA that the host does not trust.
+		//nolint:gosec // deliberate: on-premise DriverHubs use private CAs
+		InsecureSkipVerify: true,
+	}
+}
+
+// ConfigureTLS applies opts to every client of this package: Transport,
+// Client, NewClient, Resty and WebsocketDialer, including the clients already
+// handed out. Idle connections made with the previous settings are closed.
+// It fails, leaving the current settings in place, when a file cannot be
+// loaded or a pin is malformed.
+func ConfigureTLS(opts TLSOptions) error {
+	config, err := buildTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+	// Apply the environment first, so that it cannot override opts later.
+	initShared()
+	tlsMu.Lock()
+	tlsBase = config
+	tlsMu.Unlock()
+
+	old := shared.Swap(newTransport())
+	old.CloseIdleConnections()
+	return nil
+}
+
+func buildTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	verify := opts.Strict || opts.CAFile != ""
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls ca file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls ca file %s: no PEM certificate found", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	//nolint:gosec // only without strict mode or a CA bundle: the historical default
+	config.InsecureSkipVerify = !verify
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("tls client certificate: both the certificate and the key files are required")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(opts.PinnedSPKI) > 0 {
+		pins := make(map[string]struct{}, len(opts.PinnedSPKI))
+		for _, pin := range opts.PinnedSPKI {
+			sum, err := parsePin(pin)
+			if err != nil {
+				return nil, err
+			}
+			pins[string(sum)] = struct{}{}
+		}
+		pinned := func(cert *x509.Certificate) bool {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			_, ok := pins[string(sum[:])]
+			return ok
+		}
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			if !verify {
+				// The rest of an unverified chain proves nothing: anyone can
+				// append the pinned certificate behind their own.
+				if len(cs.PeerCertificates) > 0 && pinned(cs.PeerCertificates[0]) {
+					return nil
+				}
+				return fmt.Errorf("%w: %s", errPinMismatch, cs.ServerName)
+			}
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					if pinned(cert) {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("%w: %s", errPinMismatch, cs.ServerName)
+		}
+	}
+	return config, nil
+}
+
+// parsePin decodes a base64 or hex SHA-256 fingerprint.
+func parsePin(pin string) ([]byte, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+	if sum, err := base64.StdEncoding.DecodeString(s); err == nil && len(sum) == sha256.Size {
+		return sum, nil
+	}
+	if sum, err := hex.DecodeString(strings.ReplaceAll(s, ":", "")); err == nil && len(sum) == sha256.Size {
+		return sum, nil
+	}
+	return nil, fmt.Errorf("tls pin %q: not a base64 or hex SHA-256 fingerprint", pin)
+}
+
+// SPKIFingerprint returns the pin of cert in the format PinnedSPKI accepts:
+// the base64 SHA-256 of its SubjectPublicKeyInfo, as printed by
+//
+//	openssl x509 -in hub.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// currentTLSConfig is TLSConfig without initShared, for newTransport.
+func currentTLSConfig() *tls.Config {
+	tlsMu.Lock()
+	defer tlsMu.Unlock()
+	if tlsBase == nil {
+		return defaultTLSConfig()
+	}
+	return tlsBase.Clone()
+}
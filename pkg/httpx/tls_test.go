@@ -0,0 +1,208 @@
+package httpx_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
+
+// configureTLS applies opts for the rest of the test.
+func configureTLS(t *testing.T, opts httpx.TLSOptions) {
+	t.Helper()
+	if err := httpx.ConfigureTLS(opts); err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	t.Cleanup(func() { httpx.ConfigureTLS(httpx.TLSOptions{}) })
+}
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func get(url string) error {
+	res, err := httpx.Client().Get(url)
+	if err == nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+func TestConfigureTLS_caFile(t *testing.T) {
+	srv := newTLSServer(t)
+
+	configureTLS(t, httpx.TLSOptions{Strict: true})
+	if err := get(srv.URL); err == nil || !strings.Contains(err.Error(), "unknown authority") {
+		t.Fatalf("strict mode accepted an untrusted certificate: %v", err)
+	}
+
+	configureTLS(t, httpx.TLSOptions{CAFile: writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)})
+	if err := get(srv.URL); err != nil {
+		t.Fatalf("request with the CA bundle failed: %v", err)
+	}
+	if _, err := httpx.Resty().R().Get(srv.URL); err != nil {
+		t.Fatalf("resty request with the CA bundle failed: %v", err)
+	}
+	if httpx.WebsocketDialer().TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("websocket dialer does not verify certificates with a CA bundle")
+	}
+}
+
+func TestConfigureTLS_pinning(t *testing.T) {
+	srv := newTLSServer(t)
+	pin := httpx.SPKIFingerprint(srv.Certificate())
+
+	configureTLS(t, httpx.TLSOptions{PinnedSPKI: []string{"sha256/" + pin}})
+	if err := get(srv.URL); err != nil {
+		t.Fatalf("request to the pinned server failed: %v", err)
+	}
+
+	configureTLS(t, httpx.TLSOptions{PinnedSPKI: []string{strings.Repeat("ab", 32)}})
+	if err := get(srv.URL); err == nil || !strings.Contains(err.Error(), "pinned public key") {
+		t.Fatalf("request to an unpinned server did not fail on the pin: %v", err)
+	}
+}
+
+// A server cannot pass the pin check by appending the pinned certificate
+// behind its own.
+func TestConfigureTLS_pinningIgnoresAppendedCertificates(t *testing.T) {
+	pinnedSrv := newTLSServer(t)
+	pin := httpx.SPKIFingerprint(pinnedSrv.Certificate())
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "foreign"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	foreignDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{foreignDER, pinnedSrv.Certificate().Raw},
+		PrivateKey:  key,
+	}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	configureTLS(t, httpx.TLSOptions{PinnedSPKI: []string{pin}})
+	if err := get(srv.URL); err == nil || !strings.Contains(err.Error(), "pinned public key") {
+		t.Fatalf("pin-only mode accepted a pin behind a foreign leaf: %v", err)
+	}
+
+	configureTLS(t, httpx.TLSOptions{
+		CAFile:     writePEM(t, "foreign.pem", "CERTIFICATE", foreignDER),
+		PinnedSPKI: []string{pin},
+	})
+	if err := get(srv.URL); err == nil || !strings.Contains(err.Error(), "pinned public key") {
+		t.Fatalf("a pin outside the verified chain was accepted: %v", err)
+	}
+}
+
+func TestConfigureTLS_clientCertificate(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "driver"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configureTLS(t, httpx.TLSOptions{})
+	if err := get(srv.URL); err == nil {
+		t.Fatal("server accepted a client without certificate")
+	}
+
+	configureTLS(t, httpx.TLSOptions{
+		CertFile: writePEM(t, "driver.pem", "CERTIFICATE", certDER),
+		KeyFile:  writePEM(t, "driver.key", "PRIVATE KEY", keyDER),
+	})
+	if err := get(srv.URL); err != nil {
+		t.Fatalf("request with a client certificate failed: %v", err)
+	}
+}
+
+func TestConfigureTLS_invalidOptionsKeepTheSettings(t *testing.T) {
+	srv := newTLSServer(t)
+	configureTLS(t, httpx.TLSOptions{})
+
+	for _, opts := range []httpx.TLSOptions{
+		{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{CertFile: "driver.pem"},
+		{PinnedSPKI: []string{"not-a-pin"}},
+	} {
+		if err := httpx.ConfigureTLS(opts); err == nil {
+			t.Errorf("ConfigureTLS(%+v) succeeded", opts)
+		}
+	}
+	if err := get(srv.URL); err != nil {
+		t.Fatalf("settings changed after a failed ConfigureTLS: %v", err)
+	}
+}
+
+func TestTLSOptionsFromEnv(t *testing.T) {
+	t.Setenv(httpx.EnvTLSCAFile, "/etc/netsocs/ca.pem")
+	t.Setenv(httpx.EnvTLSPinnedSPKI, "pin-a, pin-b")
+	t.Setenv(httpx.EnvTLSStrict, "true")
+
+	opts, err := httpx.TLSOptionsFromEnv(httpx.TLSOptions{CAFile: "ca-from-file.pem", CertFile: "driver.pem"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.CAFile != "/etc/netsocs/ca.pem" || opts.CertFile != "driver.pem" || !opts.Strict ||
+		len(opts.PinnedSPKI) != 2 || opts.PinnedSPKI[1] != "pin-b" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	t.Setenv(httpx.EnvTLSStrict, "maybe")
+	if _, err := httpx.TLSOptionsFromEnv(httpx.TLSOptions{}); err == nil {
+		t.Fatal("invalid NETSOCS_TLS_STRICT accepted")
+	}
+}
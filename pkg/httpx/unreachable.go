@@ -0,0 +1,22 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Unreachable reports whether a request to the DriverHub failed because the
+// hub could not be reached or is temporarily unavailable: a transport error
+// (other than a cancelled or expired context) or a 502/503/504 from a proxy in
+// front of it. Such writes are worth retrying later; others are not.
+func Unreachable(statusCode int, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
+
+func TestUnreachable(t *testing.T) {
+	cases := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{0, errors.New("dial tcp: connection refused"), true},
+		{0, fmt.Errorf("put: %w", context.DeadlineExceeded), false},
+		{0, context.Canceled, false},
+		{http.StatusServiceUnavailable, nil, true},
+		{http.StatusBadGateway, nil, true},
+		{http.StatusBadRequest, nil, false},
+		{http.StatusOK, nil, false},
+	}
+	for _, c := range cases {
+		if got := httpx.Unreachable(c.status, c.err); got != c.want {
+			t.Errorf("Unreachable(%d, %v) = %v, want %v", c.status, c.err, got, c.want)
+		}
+	}
+}
@@ -17,3 +17,21 @@ func WebsocketDialer() *websocket.Dialer {
 		TLSClientConfig:  TLSConfig(),
 	}
 }
+
+// ConnectionState is the state of a long-lived websocket to the DriverHub.
+type ConnectionState string
+
+const (
+	ConnectionStateConnecting   ConnectionState = "connecting"
+	ConnectionStateConnected    ConnectionState = "connected"
+	ConnectionStateDisconnected ConnectionState = "disconnected"
+)
+
+// ConnectionStateChange is reported every time a supervised websocket changes
+// state. Err carries the dial or read error that caused a disconnection and
+// Attempt counts the consecutive failed dials (0 once connected).
+type ConnectionStateChange struct {
+	State   ConnectionState
+	Err     error
+	Attempt int
+}
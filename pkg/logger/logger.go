@@ -1,9 +1,185 @@
+// Package logger holds the logger shared by every SDK package.
+//
+// By default it is a zap production logger (JSON to stderr) at info level.
+// Drivers can replace it with their own zap logger (Set) or any slog.Handler
+// (SetHandler), change the level at runtime (SetLevel) and attach fields to
+// every entry (SetField). NetsocsDriverClient exposes the same options.
 package logger
 
-import "go.uber.org/zap"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	mu     sync.Mutex
+	base   *zap.Logger
+	fields = map[string]string{}
+
+	current atomic.Pointer[zap.SugaredLogger]
+)
+
+func init() {
+	base = defaultLogger()
+	rebuild()
+}
+
+func defaultLogger() *zap.Logger {
+	config := zap.NewProductionConfig()
+	config.Level = level
+	l, err := config.Build()
+	if err != nil {
+		return zap.NewNop()
+	}
+	return l
+}
+
+// rebuild publishes base with the level gate and the shared fields. mu must
+// be held, except from init.
+func rebuild() {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	zapFields := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		zapFields = append(zapFields, zap.String(k, fields[k]))
+	}
+	l := base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return levelCore{Core: core}
+	})).With(zapFields...)
+	current.Store(l.Sugar())
+}
+
+// Logger returns the shared logger. It is cheap: callers do not need to keep
+// it around.
 func Logger() *zap.SugaredLogger {
-	logger, _ := zap.NewProduction()
-	defer logger.Sync() // flushes buffer, if any
-	return logger.Sugar()
+	return current.Load()
+}
+
+// Set makes l the shared logger. SetLevel still applies on top of l's own
+// level. A nil l restores the default logger.
+func Set(l *zap.Logger) {
+	if l == nil {
+		l = defaultLogger()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	old := base
+	base = l
+	rebuild()
+	old.Sync()
+}
+
+// SetHandler makes the shared logger write to h, e.g. a slog.JSONHandler or
+// the handler of the driver's own slog.Logger. SetLevel still applies on top
+// of h.Enabled.
+func SetHandler(h slog.Handler) {
+	if h == nil {
+		Set(nil)
+		return
+	}
+	Set(zap.New(&slogCore{handler: h}))
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error", case-insensitively.
+func ParseLevel(s string) (zapcore.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return zap.DebugLevel, nil
+	case "info", "":
+		return zap.InfoLevel, nil
+	case "warn", "warning":
+		return zap.WarnLevel, nil
+	case "error":
+		return zap.ErrorLevel, nil
+	}
+	return zap.InfoLevel, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+}
+
+// SetLevel sets the minimum level logged, e.g. from the log_level of
+// driver.netsocs.json. It takes effect immediately, for every package.
+func SetLevel(s string) error {
+	l, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// Level returns the minimum level logged.
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// SetField adds a field, such as driver_id, to every entry. An empty value
+// removes it.
+func SetField(key, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if value == "" {
+		delete(fields, key)
+	} else {
+		fields[key] = value
+	}
+	rebuild()
+}
+
+// Sync flushes the shared logger.
+func Sync() error {
+	return Logger().Sync()
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying l, so code running under ctx logs
+// with l's fields. The SDK uses it to attach object_id and
+// action_execution_id to the context of an action execution.
+func WithLogger(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the shared logger. Action
+// handlers can use it to log with the fields of their execution:
+//
+//	logger.FromContext(ctx).Infow("door opened", "duration", d)
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*zap.SugaredLogger); ok {
+			return l
+		}
+	}
+	return Logger()
+}
+
+// levelCore applies the shared level on top of the wrapped core.
+type levelCore struct {
+	zapcore.Core
+}
+
+func (c levelCore) Enabled(l zapcore.Level) bool {
+	return level.Enabled(l) && c.Core.Enabled(l)
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{Core: c.Core.With(fields)}
+}
+
+func (c levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !level.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
 }
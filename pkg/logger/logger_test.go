@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// entries decodes the JSON lines written so far.
+func (b *syncBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// restore puts the shared logger back to its defaults when the test ends.
+func restore(t *testing.T) {
+	t.Cleanup(func() {
+		Set(nil)
+		SetLevel("info")
+		SetField("driver_id", "")
+	})
+}
+
+func TestSetHandler_writesEntriesWithFields(t *testing.T) {
+	restore(t)
+	out := &syncBuffer{}
+	SetHandler(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	SetField("driver_id", "drv-1")
+
+	Logger().With("object_id", "relay.1").Warnw("device slow", "attempt", 2)
+
+	entries := out.entries(t)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "WARN", entries[0]["level"])
+	assert.Equal(t, "device slow", entries[0]["msg"])
+	assert.Equal(t, "drv-1", entries[0]["driver_id"])
+	assert.Equal(t, "relay.1", entries[0]["object_id"])
+	assert.Equal(t, float64(2), entries[0]["attempt"])
+}
+
+func TestSetLevel_filtersEveryLogger(t *testing.T) {
+	restore(t)
+	out := &syncBuffer{}
+	SetHandler(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	child := Logger().With("object_id", "relay.1")
+
+	Logger().Debug("hidden at info")
+	require.NoError(t, SetLevel("debug"))
+	child.Debug("shown at debug")
+	require.NoError(t, SetLevel("ERROR"))
+	child.Warn("hidden at error")
+
+	entries := out.entries(t)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "shown at debug", entries[0]["msg"])
+	assert.Equal(t, zapcore.ErrorLevel, Level())
+}
+
+func TestSetLevel_rejectsUnknownLevels(t *testing.T) {
+	restore(t)
+	assert.ErrorContains(t, SetLevel("verbose"), `unknown log level "verbose"`)
+	assert.Equal(t, zapcore.InfoLevel, Level())
+}
+
+func TestSet_usesZapLogger(t *testing.T) {
+	restore(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	Set(zap.New(core))
+	SetField("driver_id", "drv-2")
+
+	Logger().Infow("hello", "config_key", "PING")
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]any{"driver_id": "drv-2", "config_key": "PING"}, logs.All()[0].ContextMap())
+}
+
+func TestFromContext(t *testing.T) {
+	restore(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	Set(zap.New(core))
+
+	FromContext(context.Background()).Info("shared")
+	ctx := WithLogger(context.Background(), Logger().With("action_execution_id", "exec-1"))
+	FromContext(ctx).Info("scoped")
+
+	require.Equal(t, 2, logs.Len())
+	assert.Empty(t, logs.All()[0].ContextMap())
+	assert.Equal(t, map[string]any{"action_execution_id": "exec-1"}, logs.All()[1].ContextMap())
+}
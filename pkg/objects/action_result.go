@@ -0,0 +1,210 @@
+package objects
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+// ActionStatus is the lifecycle status of an action execution.
+type ActionStatus string
+
+const (
+	// ActionStatusQueued: the execution waits for its execution policy.
+	ActionStatusQueued    ActionStatus = "queued"
+	ActionStatusRunning   ActionStatus = "running"
+	ActionStatusSucceeded ActionStatus = "succeeded"
+	ActionStatusFailed    ActionStatus = "failed"
+	ActionStatusCancelled ActionStatus = "cancelled"
+)
+
+// ActionResult is a structured action execution result. Unlike the
+// map[string]string returned by RunAction, Data keeps its JSON types (numbers,
+// arrays, nested objects). The runner sends one with StatusQueued while the
+// execution waits for its execution policy, one with StatusRunning for every
+// progress update and a final one when the execution ends.
+type ActionResult struct {
+	Status ActionStatus
+	// Data is the result payload.
+	Data map[string]any
+	// Error and ErrorCode describe a failure; see the ActionErrorCode constants.
+	Error     string
+	ErrorCode string
+	// Progress is the percentage completed, 0-100, of a running execution.
+	Progress int
+	// Message is a human-readable progress or status note.
+	Message string
+}
+
+// ActionResultReporter is implemented by controllers that can send
+// structured results to the DriverHub. Controllers that do not (e.g. test
+// doubles) receive the flattened attributes through UpdateResultAttributes.
+type ActionResultReporter interface {
+	ReportActionResult(executionID string, result ActionResult) error
+	ReportActionResultContext(ctx context.Context, executionID string, result ActionResult) error
+}
+
+// structuredResultsSwitch is implemented by reporters that send structured
+// results only once enabled; see objectController.EnableStructuredActionResults.
+type structuredResultsSwitch interface {
+	structuredActionResults() bool
+}
+
+// reportsStructuredResults reports whether oc sends the status and the typed
+// data of results, rather than the flattened attributes only.
+func reportsStructuredResults(oc ObjectController) bool {
+	oc = UnwrapController(oc)
+	if _, ok := oc.(ActionResultReporter); !ok {
+		return false
+	}
+	if s, ok := oc.(structuredResultsSwitch); ok {
+		return s.structuredActionResults()
+	}
+	return true
+}
+
+// body is the structured JSON sent to the DriverHub. "result" keeps the shape of
+// UpdateResultAttributes, error included, so existing consumers still work.
+func (r ActionResult) body() map[string]any {
+	result := make(map[string]any, len(r.Data)+2)
+	for k, v := range r.Data {
+		result[k] = v
+	}
+	if r.Error != "" {
+		result["error"] = r.Error
+	}
+	if r.ErrorCode != "" {
+		result["error_code"] = r.ErrorCode
+	}
+
+	body := map[string]any{"status": r.Status, "result": result}
+	if r.Status == ActionStatusRunning {
+		body["progress"] = r.Progress
+	}
+	if r.Message != "" {
+		body["message"] = r.Message
+	}
+	if r.Error != "" {
+		body["error"] = r.Error
+	}
+	if r.ErrorCode != "" {
+		body["error_code"] = r.ErrorCode
+	}
+	return body
+}
+
+// attributes flattens the result for UpdateResultAttributes: strings are kept
+// as they are and any other value is encoded as JSON.
+func (r ActionResult) attributes() map[string]string {
+	attributes := make(map[string]string, len(r.Data)+2)
+	for k, v := range r.Data {
+		attributes[k] = stringifyResultValue(v)
+	}
+	if r.Status == ActionStatusRunning && (r.Progress > 0 || r.Message != "") {
+		attributes["progress"] = strconv.Itoa(r.Progress)
+	}
+	if r.Message != "" {
+		attributes["message"] = r.Message
+	}
+	if r.Error != "" {
+		attributes["error"] = r.Error
+	}
+	if r.ErrorCode != "" {
+		attributes["error_code"] = r.ErrorCode
+	}
+	return attributes
+}
+
+func stringifyResultValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+func stringsToData(m map[string]string) map[string]any {
+	if m == nil {
+		return nil
+	}
+	data := make(map[string]any, len(m))
+	for k, v := range m {
+		data[k] = v
+	}
+	return data
+}
+
+func dataToStrings(data map[string]any) map[string]string {
+	if data == nil {
+		return nil
+	}
+	return ActionResult{Data: data}.attributes()
+}
+
+// reportActionResult sends result through oc, structured when oc supports it.
+func reportActionResult(ctx context.Context, oc ObjectController, executionID string, result ActionResult) error {
+	oc = UnwrapController(oc)
+	if reporter, ok := oc.(ActionResultReporter); ok {
+		return reporter.ReportActionResultContext(ctx, executionID, result)
+	}
+	return WithContext(oc).UpdateResultAttributesContext(ctx, executionID, result.attributes())
+}
+
+// executionState is shared, through the context, between the runner and the
+// code running one action execution.
+type executionState struct {
+	executionID string
+	// structured is set when the result is reported with its typed data.
+	structured bool
+
+	mu   sync.Mutex
+	data map[string]any
+}
+
+type executionStateKey struct{}
+
+func withExecutionState(ctx context.Context, executionID string, structured bool) (context.Context, *executionState) {
+	state := &executionState{executionID: executionID, structured: structured}
+	return context.WithValue(ctx, executionStateKey{}, state), state
+}
+
+// setResultData hands typed result data to the runner, which reports it in
+// place of the flattened map returned by RunAction. It is a no-op outside a
+// runner execution, and when the controller reports flattened results, which
+// keep the shape RunAction returns.
+func setResultData(ctx context.Context, data map[string]any) {
+	state, ok := ctx.Value(executionStateKey{}).(*executionState)
+	if !ok || !state.structured || data == nil {
+		return
+	}
+	state.mu.Lock()
+	state.data = data
+	state.mu.Unlock()
+}
+
+func (s *executionState) resultData() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data
+}
+
+// ReportProgress sends an intermediate update, e.g. the percentage of a clip
+// download, for the execution oc was handed to. Use it from the callbacks of
+// the built-in objects; custom action handlers use
+// CustomActionContext.ReportProgress.
+func ReportProgress(oc ObjectController, percent int, message string) error {
+	ec, ok := execution(oc)
+	if !ok {
+		return errNotInExecution
+	}
+	return reportActionResult(ec.ctx, ec.inner, ec.executionID, ActionResult{
+		Status:   ActionStatusRunning,
+		Progress: percent,
+		Message:  message,
+	})
+}
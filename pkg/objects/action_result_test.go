@@ -0,0 +1,210 @@
+package objects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/eventbus"
+	"github.com/go-resty/resty/v2"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// structuredRecorder records every structured result it receives.
+type structuredRecorder struct {
+	*mockMicController
+	results chan ActionResult
+}
+
+func (r *structuredRecorder) ReportActionResult(executionID string, result ActionResult) error {
+	return r.ReportActionResultContext(context.Background(), executionID, result)
+}
+
+func (r *structuredRecorder) ReportActionResultContext(ctx context.Context, executionID string, result ActionResult) error {
+	r.results <- result
+	return nil
+}
+
+func nextResult(t *testing.T, results chan ActionResult) ActionResult {
+	t.Helper()
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatal("no action result reported")
+		return ActionResult{}
+	}
+}
+
+func TestObjectRunner_reportsProgressAndTypedResult(t *testing.T) {
+	controller := &structuredRecorder{mockMicController: newMockMicController(""), results: make(chan ActionResult, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.typed.1", Domain: "test.typed"},
+	})
+	require.NoError(t, sw.(CustomActionResultRegistrar).RegisterCustomActionResult("test.typed.action.wait", func(ctx CustomActionContext) (map[string]any, error) {
+		require.NoError(t, ctx.ReportProgress(50, "half way"))
+		return map[string]any{"count": 2, "files": []string{"a.mp4", "b.mp4"}}, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	publishAction("test.typed")
+
+	assert.Equal(t, ActionResult{Status: ActionStatusRunning, Progress: 50, Message: "half way"}, nextResult(t, controller.results))
+	final := nextResult(t, controller.results)
+	assert.Equal(t, ActionStatusSucceeded, final.Status)
+	assert.Equal(t, 2, final.Data["count"])
+	assert.Equal(t, []string{"a.mp4", "b.mp4"}, final.Data["files"])
+}
+
+func TestObjectRunner_reportsErrorCodeOnTimeout(t *testing.T) {
+	controller := &structuredRecorder{mockMicController: newMockMicController(""), results: make(chan ActionResult, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+	runner.SetActionTimeout("test.structured_timeout.action.wait", 20*time.Millisecond)
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.structured_timeout.1", Domain: "test.structured_timeout"},
+	})
+	require.NoError(t, sw.RegisterCustomAction("test.structured_timeout.action.wait", func(ctx CustomActionContext) (map[string]string, error) {
+		<-ctx.Context.Done()
+		return nil, ctx.Context.Err()
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	publishAction("test.structured_timeout")
+
+	result := nextResult(t, controller.results)
+	assert.Equal(t, ActionStatusFailed, result.Status)
+	assert.Equal(t, ActionErrorCodeTimeout, result.ErrorCode)
+	assert.NotEmpty(t, result.Error)
+}
+
+func TestReportActionResult_flattensForPlainControllers(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 1)}
+
+	require.NoError(t, reportActionResult(context.Background(), controller, "exec-1", ActionResult{
+		Status:    ActionStatusFailed,
+		Data:      map[string]any{"name": "clip", "files": []int{1, 2}},
+		Error:     "boom",
+		ErrorCode: "device_offline",
+	}))
+
+	assert.Equal(t, map[string]string{
+		"name":       "clip",
+		"files":      "[1,2]",
+		"error":      "boom",
+		"error_code": "device_offline",
+	}, <-controller.results)
+}
+
+func TestReportActionResultContext_sendsStatusAndProgress(t *testing.T) {
+	bodies := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/objects/actions/executions/exec-1", r.URL.Path)
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.EnableStructuredActionResults()
+	require.NoError(t, controller.ReportActionResult("exec-1", ActionResult{
+		Status:   ActionStatusRunning,
+		Progress: 40,
+		Message:  "downloading",
+		Data:     map[string]any{"bytes": 1024},
+	}))
+
+	body := <-bodies
+	assert.Equal(t, "running", body["status"])
+	assert.Equal(t, float64(40), body["progress"])
+	assert.Equal(t, "downloading", body["message"])
+	assert.Equal(t, map[string]any{"bytes": float64(1024)}, body["result"])
+}
+
+func TestReportActionResultContext_flattensUntilEnabled(t *testing.T) {
+	bodies := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	require.NoError(t, controller.ReportActionResult("exec-1", ActionResult{
+		Status: ActionStatusSucceeded,
+		Data:   map[string]any{"bytes": 1024},
+	}))
+	assert.Equal(t, map[string]any{"result": map[string]any{"bytes": "1024"}}, <-bodies)
+}
+
+func TestObjectRunner_keepsIndexKeyedMediaFilesWhenFlattened(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 1)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	files := []MediaFileItem{{Channel: "1", FilePath: "/a.dav"}, {Channel: "1", FilePath: "/b.dav"}}
+	require.NoError(t, runner.RegisterObject(NewVideoChannelObject(NewVideoChannelObjectProps{
+		Metadata: ObjectMetadata{ObjectID: "video.1", Domain: "test.media"},
+		RequestDahuaPlaybackMediaFiles: func(VideoChannelObject, ObjectController, RequestDahuaPlaybackMediaFilesPayload) (RequestDahuaPlaybackMediaFilesResponse, error) {
+			return RequestDahuaPlaybackMediaFilesResponse{MediaFiles: files}, nil
+		},
+	})))
+	eventbus.Pubsub.Publish("REQUEST_ACTION_EXECUTION", map[string]interface{}{
+		"id":      "exec-media",
+		"domain":  "test.media",
+		"action":  VIDEO_CHANNEL_ACTION_REQUEST_DAHUA_PLAYBACK_MEDIA_FILES,
+		"payload": map[string]interface{}{},
+	})
+
+	select {
+	case result := <-controller.results:
+		require.Len(t, result, 2)
+		first, err := json.Marshal(files[0])
+		require.NoError(t, err)
+		assert.Equal(t, string(first), result["0"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("no action result reported")
+	}
+}
+
+func TestObjectRunner_reportsQueuedStatus(t *testing.T) {
+	controller := &structuredRecorder{mockMicController: newMockMicController(""), results: make(chan ActionResult, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	sw := NewSwitchObject(NewSwitchObjectParams{Metadata: ObjectMetadata{ObjectID: "queued.1", Domain: "test.queued"}})
+	require.NoError(t, sw.RegisterCustomAction("test.queued.action.wait", func(CustomActionContext) (map[string]string, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+	require.NoError(t, runner.SetDomainExecutionPolicy("test.queued", ExecutionPolicy{Mode: ExecutionSerial}))
+
+	publishAction("test.queued")
+	<-started
+	publishAction("test.queued")
+	queued := nextResult(t, controller.results)
+	assert.Equal(t, ActionStatusQueued, queued.Status)
+	assert.Equal(t, 1, queued.Data["queue_depth"])
+	close(release)
+	assert.Equal(t, ActionStatusSucceeded, nextResult(t, controller.results).Status)
+	assert.Equal(t, ActionStatusSucceeded, nextResult(t, controller.results).Status)
+}
+
+func TestReportProgress_outsideAnExecution(t *testing.T) {
+	assert.ErrorIs(t, ReportProgress(newMockMicController(""), 10, ""), errNotInExecution)
+}
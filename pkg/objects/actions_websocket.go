@@ -0,0 +1,181 @@
+package objects
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/eventbus"
+	"github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tools"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+)
+
+// ConnectionStateNotifier is implemented by controllers that keep a supervised
+// websocket to the DriverHub, so drivers can log outages or mark their devices
+// as degraded while action requests cannot be received.
+type ConnectionStateNotifier interface {
+	// OnConnectionStateChange registers fn to be called on every state change.
+	// fn runs on the websocket supervisor goroutine and must not block.
+	OnConnectionStateChange(fn func(httpx.ConnectionStateChange))
+}
+
+// domainResubscriber is implemented by controllers that subscribe the runner's
+// domains on their own objects websocket, again after every reconnection. The
+// runner calls the controller directly rather than through the global event
+// bus, so several clients in one process never subscribe each other's domains.
+type domainResubscriber interface {
+	setRegisteredDomains(func() []string)
+	subscribeToDomain(domain string) error
+}
+
+type wsMessage struct {
+	EventType string `json:"event_type"`
+	Data      any    `json:"data"`
+	Domain    string `json:"domain"`
+}
+
+// OnConnectionStateChange implements ConnectionStateNotifier.
+func (o *objectController) OnConnectionStateChange(fn func(httpx.ConnectionStateChange)) {
+	if fn == nil {
+		return
+	}
+	o.wsMu.Lock()
+	defer o.wsMu.Unlock()
+	o.stateListeners = append(o.stateListeners, fn)
+}
+
+func (o *objectController) setRegisteredDomains(fn func() []string) {
+	o.wsMu.Lock()
+	defer o.wsMu.Unlock()
+	o.registeredDomains = fn
+}
+
+func (o *objectController) notifyConnectionState(change httpx.ConnectionStateChange) {
+	o.wsMu.Lock()
+	listeners := append([]func(httpx.ConnectionStateChange){}, o.stateListeners...)
+	o.wsMu.Unlock()
+	for _, fn := range listeners {
+		fn(change)
+	}
+}
+
+// subscribeToDomain asks the DriverHub to forward the action requests of a
+// domain. It is a no-op while disconnected: every registered domain is
+// subscribed again as soon as the websocket reconnects.
+func (o *objectController) subscribeToDomain(domain string) error {
+	o.wsMu.Lock()
+	defer o.wsMu.Unlock()
+	if o.wsConn == nil {
+		return nil
+	}
+	return o.wsConn.WriteJSON(wsMessage{EventType: "REQUEST_SUBSCRIPTION_TO_DOMAIN", Domain: domain})
+}
+
+// ListenActionRequestsContext implements ObjectControllerCtx. It keeps the
+// objects websocket open until ctx is cancelled: when the connection cannot be
+// established or drops (e.g. the DriverHub restarts), it is dialed again with a
+// jittered exponential backoff, and every domain already registered in the
+// runner is subscribed again once connected. Cancelling ctx closes the
+// websocket with a close frame and returns nil.
+func (o *objectController) ListenActionRequestsContext(ctx context.Context) error {
+	backoff := httpx.DefaultBackoff()
+	attempt := 0
+	for first := true; ; first = false {
+		if !first {
+			metrics.WebsocketReconnects.Inc("objects")
+		}
+		o.notifyConnectionState(httpx.ConnectionStateChange{State: httpx.ConnectionStateConnecting, Attempt: attempt})
+		connected, err := o.serveActionRequests(ctx)
+		if connected {
+			attempt = 0
+		}
+		if ctx.Err() != nil {
+			o.notifyConnectionState(httpx.ConnectionStateChange{State: httpx.ConnectionStateDisconnected})
+			return nil
+		}
+		o.notifyConnectionState(httpx.ConnectionStateChange{State: httpx.ConnectionStateDisconnected, Err: err, Attempt: attempt})
+
+		delay := backoff.Delay(attempt)
+		logger.Logger().Warnw("objects websocket disconnected, reconnecting", "error", err, "attempt", attempt, "retry_in", delay.String())
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			o.notifyConnectionState(httpx.ConnectionStateChange{State: httpx.ConnectionStateDisconnected})
+			return nil
+		}
+		attempt++
+	}
+}
+
+// serveActionRequests dials the objects websocket once and forwards action
+// requests to the runner until the connection fails or ctx is cancelled.
+// connected reports whether the dial succeeded, so the caller can reset its
+// backoff.
+func (o *objectController) serveActionRequests(ctx context.Context) (connected bool, err error) {
+	// Convert the URL using the utility function
+	url := tools.ConvertToWebSocketURL(o.driverhub_host, "objects/ws")
+
+	// Shared dialer: honours the SDK TLS configuration (custom CA bundle or
+	// verification opt-out). See pkg/httpx.
+	dialer := httpx.WebsocketDialer()
+
+	c, _, err := dialer.DialContext(ctx, url, http.Header{
+		"X-Auth-Token": []string{o.token},
+	})
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	o.wsMu.Lock()
+	o.wsConn = c
+	registeredDomains := o.registeredDomains
+	o.wsMu.Unlock()
+	defer func() {
+		o.wsMu.Lock()
+		o.wsConn = nil
+		o.wsMu.Unlock()
+	}()
+
+	o.notifyConnectionState(httpx.ConnectionStateChange{State: httpx.ConnectionStateConnected})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			o.wsMu.Lock()
+			_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			o.wsMu.Unlock()
+			c.Close()
+		case <-stop:
+		}
+	}()
+
+	if registeredDomains != nil {
+		for _, domain := range registeredDomains() {
+			if err := o.subscribeToDomain(domain); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	for {
+		_, message, err := c.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		msg := wsMessage{}
+		json.Unmarshal(message, &msg)
+		if msg.EventType == "REQUEST_ACTION_EXECUTION" {
+			go recovery.Guard(recovery.BoundaryEventBus, func() {
+				eventbus.Pubsub.Publish("REQUEST_ACTION_EXECUTION", msg.Data)
+			})
+		}
+	}
+}
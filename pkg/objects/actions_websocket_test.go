@@ -0,0 +1,87 @@
+package objects
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/go-resty/resty/v2"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newObjectsWSServer accepts websockets on /objects/ws and hands every
+// connection to connCh.
+func newObjectsWSServer(t *testing.T, connCh chan *websocket.Conn) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/objects/ws" {
+			http.NotFound(w, r)
+			return
+		}
+		ws, err := wsUpgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		connCh <- ws
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func readSubscription(t *testing.T, ws *websocket.Conn) wsMessage {
+	t.Helper()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var msg wsMessage
+	require.NoError(t, ws.ReadJSON(&msg))
+	return msg
+}
+
+func TestListenActionRequests_reconnectsAndResubscribes(t *testing.T) {
+	connCh := make(chan *websocket.Conn, 2)
+	srv := newObjectsWSServer(t, connCh)
+
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	runner := NewObjectRunner(controller).(*objectRunner)
+	runner.objectsMap.Store("test.domain", []RegistrableObject{})
+
+	var mu sync.Mutex
+	var states []httpx.ConnectionState
+	controller.OnConnectionStateChange(func(change httpx.ConnectionStateChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, change.State)
+	})
+
+	go controller.ListenActionRequests()
+
+	var first *websocket.Conn
+	select {
+	case first = <-connCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("controller did not connect")
+	}
+	msg := readSubscription(t, first)
+	assert.Equal(t, "REQUEST_SUBSCRIPTION_TO_DOMAIN", msg.EventType)
+	assert.Equal(t, "test.domain", msg.Domain)
+
+	// Simulate a DriverHub restart.
+	first.Close()
+
+	var second *websocket.Conn
+	select {
+	case second = <-connCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not reconnect")
+	}
+	defer second.Close()
+	msg = readSubscription(t, second)
+	assert.Equal(t, "test.domain", msg.Domain)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, states, httpx.ConnectionStateDisconnected)
+	assert.Equal(t, httpx.ConnectionStateConnected, states[len(states)-1])
+}
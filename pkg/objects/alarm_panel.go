@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"strings"
 
 	"github.com/goccy/go-json"
@@ -114,6 +115,11 @@ func (a *alarmPanelObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements AlarmPanelObject.
 func (a *alarmPanelObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return a.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (a *alarmPanelObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 
 	var p actionPayload
 	switch action {
@@ -126,34 +132,34 @@ func (a *alarmPanelObject) RunAction(id, action string, payload []byte) (map[str
 			return nil, err
 		}
 	default:
-		return a.dispatchCustom(a, a.controller, id, action, payload)
+		return a.dispatchCustom(ctx, a, a.controller, id, action, payload)
 	}
 
 	switch action {
 	case ALARM_PANEL_ACTION_ARM:
-		if err := a.armFn(a, a.controller, p.ArmMode, p.Code); err != nil {
+		if err := a.armFn(a, withExecutionContext(ctx, id, a.controller), p.ArmMode, p.Code); err != nil {
 			return nil, err
 		}
 		return nil, a.controller.SetState(a.GetMetadata().ObjectID, ALARM_PANEL_STATE_AWAY_ARMED)
 	case ALARM_PANEL_ACTION_DISARM:
-		if err := a.disarmFn(a, a.controller, p.Code); err != nil {
+		if err := a.disarmFn(a, withExecutionContext(ctx, id, a.controller), p.Code); err != nil {
 			return nil, err
 		}
 		return nil, a.controller.SetState(a.GetMetadata().ObjectID, ALARM_PANEL_STATE_DISARMED)
 	case ALARM_PANEL_ACTION_FIRE:
-		return nil, a.fireFn(a, a.controller, p.Code)
+		return nil, a.fireFn(a, withExecutionContext(ctx, id, a.controller), p.Code)
 	case ALARM_PANEL_ACTION_PANIC:
-		return nil, a.panicFn(a, a.controller, p.Code)
+		return nil, a.panicFn(a, withExecutionContext(ctx, id, a.controller), p.Code)
 	case ALARM_PANEL_ACTION_AUXILIARY:
-		return nil, a.auxiliaryFn(a, a.controller, p.Code)
+		return nil, a.auxiliaryFn(a, withExecutionContext(ctx, id, a.controller), p.Code)
 	case ALARM_GENERIC_ACTION_BYPASS:
-		return nil, a.bypassFn(a, a.controller, p.Code, p.Zone)
+		return nil, a.bypassFn(a, withExecutionContext(ctx, id, a.controller), p.Code, p.Zone)
 	case ALARM_GENERIC_ACTION_RESTORE_ALARM:
-		return nil, a.restoreAlarmFn(a, a.controller, p.Code)
+		return nil, a.restoreAlarmFn(a, withExecutionContext(ctx, id, a.controller), p.Code)
 	case ALARM_GENERIC_ACTION_BYPASS_REST:
-		return nil, a.bypassRestFn(a, a.controller, p.Code, p.Zone)
+		return nil, a.bypassRestFn(a, withExecutionContext(ctx, id, a.controller), p.Code, p.Zone)
 	}
-	return a.dispatchCustom(a, a.controller, id, action, payload)
+	return a.dispatchCustom(ctx, a, a.controller, id, action, payload)
 }
 
 // Setup implements AlarmPanelObject.
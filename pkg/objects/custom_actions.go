@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -9,6 +10,9 @@ import (
 // triggers a driver-defined action. It carries everything the handler needs to
 // process the request and report results back to the platform.
 type CustomActionContext struct {
+	// Context is cancelled when the client shuts down. Long-running handlers
+	// should watch Context.Done() and return early.
+	Context     context.Context
 	ExecutionID string            // action execution id from the platform
 	Action      string            // the custom action name being invoked
 	Payload     []byte            // raw JSON payload sent by DriversHub
@@ -20,6 +24,26 @@ type CustomActionContext struct {
 // map is sent back to the platform as the action execution result.
 type CustomActionHandler func(ctx CustomActionContext) (map[string]string, error)
 
+// CustomActionResultHandler is a CustomActionHandler whose result keeps its
+// JSON types (numbers, arrays, nested objects) instead of being flattened to
+// strings, when structured results are enabled. Register it with
+// CustomActionResultRegistrar.RegisterCustomActionResult.
+type CustomActionResultHandler func(ctx CustomActionContext) (map[string]any, error)
+
+// ReportProgress sends an intermediate "running" update for this execution,
+// e.g. the percentage of a download or a database sync completed so far.
+func (c CustomActionContext) ReportProgress(percent int, message string) error {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return reportActionResult(ctx, c.Controller, c.ExecutionID, ActionResult{
+		Status:   ActionStatusRunning,
+		Progress: percent,
+		Message:  message,
+	})
+}
+
 // CustomActionRegistrar is implemented by every built-in object so drivers can
 // attach their own actions without modifying the SDK. Register actions before
 // calling ObjectRunner.RegisterObject so they are advertised to the platform.
@@ -27,6 +51,15 @@ type CustomActionRegistrar interface {
 	RegisterCustomAction(action string, handler CustomActionHandler) error
 }
 
+// CustomActionResultRegistrar is implemented by every built-in object, for
+// handlers returning a typed result:
+//
+//	registrar, _ := obj.(objects.CustomActionResultRegistrar)
+//	registrar.RegisterCustomActionResult("acme.action.sync_cards", handler)
+type CustomActionResultRegistrar interface {
+	RegisterCustomActionResult(action string, handler CustomActionResultHandler) error
+}
+
 // customActions is an embeddable registry of driver-defined custom actions.
 // Built-in object types embed it (anonymously) to gain RegisterCustomAction and
 // the dispatch/advertise helpers. The zero value is ready to use; the map is
@@ -34,7 +67,7 @@ type CustomActionRegistrar interface {
 // mutex by value is safe.
 type customActions struct {
 	mu       sync.RWMutex
-	handlers map[string]CustomActionHandler
+	handlers map[string]CustomActionResultHandler
 }
 
 // RegisterCustomAction registers a driver-defined action handler under the given
@@ -42,6 +75,18 @@ type customActions struct {
 // action is published to the platform. It returns an error if the name is empty,
 // the handler is nil, or the action is already registered.
 func (c *customActions) RegisterCustomAction(action string, handler CustomActionHandler) error {
+	if handler == nil {
+		return fmt.Errorf("custom action handler cannot be nil")
+	}
+	return c.RegisterCustomActionResult(action, func(ctx CustomActionContext) (map[string]any, error) {
+		resp, err := handler(ctx)
+		return stringsToData(resp), err
+	})
+}
+
+// RegisterCustomActionResult is RegisterCustomAction for handlers returning a
+// typed result.
+func (c *customActions) RegisterCustomActionResult(action string, handler CustomActionResultHandler) error {
 	if action == "" {
 		return fmt.Errorf("custom action name cannot be empty")
 	}
@@ -51,7 +96,7 @@ func (c *customActions) RegisterCustomAction(action string, handler CustomAction
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.handlers == nil {
-		c.handlers = make(map[string]CustomActionHandler)
+		c.handlers = make(map[string]CustomActionResultHandler)
 	}
 	if _, exists := c.handlers[action]; exists {
 		return fmt.Errorf("custom action %q already registered", action)
@@ -76,7 +121,7 @@ func (c *customActions) customActionList(domain string) []ObjectAction {
 // runCustomAction dispatches to a registered custom handler. The boolean reports
 // whether a handler was found, so callers can fall back to their usual
 // "action not found" handling.
-func (c *customActions) runCustomAction(ctx CustomActionContext) (map[string]string, bool, error) {
+func (c *customActions) runCustomAction(ctx CustomActionContext) (map[string]any, bool, error) {
 	c.mu.RLock()
 	handler, ok := c.handlers[ctx.Action]
 	c.mu.RUnlock()
@@ -89,16 +134,24 @@ func (c *customActions) runCustomAction(ctx CustomActionContext) (map[string]str
 
 // dispatchCustom is the standard tail for an object's RunAction default case: it
 // tries a registered custom handler and otherwise returns the canonical
-// "action not found" error.
-func (c *customActions) dispatchCustom(this RegistrableObject, oc ObjectController, id, action string, payload []byte) (map[string]string, error) {
+// "action not found" error. The typed result is handed to the runner, the
+// returned map is its flattened form for RunAction callers.
+func (c *customActions) dispatchCustom(ctx context.Context, this RegistrableObject, oc ObjectController, id, action string, payload []byte) (map[string]string, error) {
 	if resp, ok, err := c.runCustomAction(CustomActionContext{
+		Context:     ctx,
 		ExecutionID: id,
 		Action:      action,
 		Payload:     payload,
 		Object:      this,
 		Controller:  oc,
 	}); ok {
-		return resp, err
+		if err != nil {
+			return nil, err
+		}
+		setResultData(ctx, resp)
+		return dataToStrings(resp), nil
 	}
 	return nil, fmt.Errorf("action %s not found", action)
 }
+
+var _ CustomActionResultRegistrar = (*customActions)(nil)
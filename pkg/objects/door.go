@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 type DoorObject interface {
 	RegistrableObject
 	CustomActionRegistrar
@@ -63,19 +65,24 @@ func (d *doorObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements DoorObject.
 func (d *doorObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return d.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (d *doorObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case DOOR_ACTION_OPEN:
-		if err := d.openDoorMethod(d, d.controller); err != nil {
+		if err := d.openDoorMethod(d, withExecutionContext(ctx, id, d.controller)); err != nil {
 			return nil, err
 		}
 		return nil, d.controller.SetState(d.metadata.ObjectID, DOOR_STATE_OPEN)
 	case DOOR_ACTION_CLOSE:
-		if err := d.closeDoorMethod(d, d.controller); err != nil {
+		if err := d.closeDoorMethod(d, withExecutionContext(ctx, id, d.controller)); err != nil {
 			return nil, err
 		}
 		return nil, d.controller.SetState(d.metadata.ObjectID, DOOR_STATE_CLOSE)
 	}
-	return d.dispatchCustom(d, d.controller, id, action, payload)
+	return d.dispatchCustom(ctx, d, d.controller, id, action, payload)
 }
 
 // Setup implements DoorObject.
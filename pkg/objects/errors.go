@@ -1,11 +1,23 @@
 package objects
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+)
 
 var ErrMethodNotImplemented = errors.New("method not implemented")
 var ErrAttributeNotFound = errors.New("attribute not found")
-var ErrObjectNotFound = errors.New("object not found")
-var ErrObjectAlreadyExists = errors.New("object already exists")
+
+// The DriverHub errors returned by the controller are *httpx.HubError values;
+// these match them with errors.Is.
+var ErrObjectNotFound = httpx.ErrNotFound
+var ErrObjectAlreadyExists = httpx.ErrAlreadyExists
+var ErrObjectDisabled = httpx.ErrObjectDisabled
+
+// ErrLifecycleUnsupported is returned by the ObjectLifecycleController
+// methods when the DriverHub does not serve the object listing.
+var ErrLifecycleUnsupported = errors.New("the driverhub cannot list, update or delete objects")
 
 var ErrDomainMandatory = errors.New("domain is mandatory")
 var ErrObjectIdMandatory = errors.New("object_id is mandatory")
@@ -1,12 +1,27 @@
 package objects
 
+import (
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
 type Event struct {
 	ObjectIDs  []string
 	ImageURLs  []string
 	VideoURLs  []string
 	Properties map[string]string
+	// Timestamp is when the event happened, sent as the EventPropertyOccurredAt
+	// property. Zero leaves it out, unless an outbox is enabled: the event is
+	// then stamped on dispatch so a replay keeps its original time.
+	Timestamp time.Time
 }
 
+// EventPropertyOccurredAt is the event property carrying Event.Timestamp, in
+// RFC 3339. The hub stamps events with the time it receives them.
+const EventPropertyOccurredAt = "occurred_at"
+
 type NewEventRequestBodySchema struct {
 	EventType                 string            `json:"event_type"`
 	Rels                      []string          `json:"rels"`
@@ -28,10 +43,26 @@ type EventRecord struct {
 	Domain                    string            `json:"domain"`
 }
 
+// EventDispatchResponse is the answer of the DriverHub to a dispatched
+// event.
 type EventDispatchResponse struct {
 	ID string `json:"id"`
 }
 
+// ParseEventDispatchResponse reads the answer to POST /objects/events: a
+// JSON object with the event ID, a JSON string, or the bare ID.
+func ParseEventDispatchResponse(body []byte) EventDispatchResponse {
+	var resp EventDispatchResponse
+	if json.Unmarshal(body, &resp) == nil && resp.ID != "" {
+		return resp
+	}
+	var id string
+	if json.Unmarshal(body, &id) == nil {
+		return EventDispatchResponse{ID: id}
+	}
+	return EventDispatchResponse{ID: strings.TrimSpace(string(body))}
+}
+
 type ReportRecord struct {
 	WrittenBy string `json:"written_by"`
 	WrittenAt string `json:"written_at"`
@@ -0,0 +1,214 @@
+package objects
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownEventType is returned, wrapped, for an event whose type was
+	// not registered through AddEventTypes.
+	ErrUnknownEventType = errors.New("unknown event type")
+	// ErrInvalidEvent is returned, wrapped, for an event whose properties do
+	// not match the schema of its type.
+	ErrInvalidEvent = errors.New("event does not match the schema of its type")
+)
+
+// EventRegistryController is implemented by controllers that remember the
+// event types they registered with AddEventTypes. The controller returned by
+// NewObjectController implements it; the client checks dispatched events
+// against it.
+type EventRegistryController interface {
+	EventRegistry() *EventRegistry
+}
+
+// PropertyType is the type of an event property. Properties travel as
+// strings; the type says how the string must parse.
+type PropertyType string
+
+const (
+	PropertyString PropertyType = "string"
+	// PropertyInt is a base 10 integer.
+	PropertyInt PropertyType = "int"
+	// PropertyFloat is a decimal number.
+	PropertyFloat PropertyType = "float"
+	// PropertyBool is "true" or "false".
+	PropertyBool PropertyType = "bool"
+	// PropertyTime is an RFC 3339 timestamp.
+	PropertyTime PropertyType = "time"
+)
+
+// PropertySchema describes one property of an event type.
+type PropertySchema struct {
+	// Type defaults to PropertyString.
+	Type     PropertyType
+	Required bool
+	// Enum, when set, lists the values the property may take.
+	Enum []string
+}
+
+// EventSchema describes the properties of an event type.
+type EventSchema struct {
+	Properties map[string]PropertySchema
+	// AllowUnknown accepts properties the schema does not list.
+	AllowUnknown bool
+}
+
+// validate returns the problems of properties, sorted.
+func (s EventSchema) validate(properties map[string]string) []string {
+	var problems []string
+	for name, property := range s.Properties {
+		value, ok := properties[name]
+		if !ok {
+			if property.Required {
+				problems = append(problems, fmt.Sprintf("missing property %q", name))
+			}
+			continue
+		}
+		if !property.Type.accepts(value) {
+			problems = append(problems, fmt.Sprintf("property %q: %q is not a %s", name, value, property.Type))
+		} else if len(property.Enum) > 0 && !slices.Contains(property.Enum, value) {
+			problems = append(problems, fmt.Sprintf("property %q: %q is not one of %s", name, value, strings.Join(property.Enum, ", ")))
+		}
+	}
+	if !s.AllowUnknown {
+		for name := range properties {
+			if _, ok := s.Properties[name]; !ok {
+				problems = append(problems, fmt.Sprintf("unknown property %q", name))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func (t PropertyType) accepts(value string) bool {
+	var err error
+	switch t {
+	case PropertyInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case PropertyFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case PropertyBool:
+		_, err = strconv.ParseBool(value)
+	case PropertyTime:
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	return err == nil
+}
+
+// EventRegistry holds the event types registered with the DriverHub, by
+// EventType, and the property schemas declared for them. A type is found by
+// its EventType, and also by its Domain and EventType joined with a dot, the
+// name DispatchEvent gives it: "temperature_alert" of domain "temperature"
+// is also "temperature.temperature_alert". The zero value is empty and ready
+// to use; it is safe for concurrent use.
+type EventRegistry struct {
+	mu      sync.RWMutex
+	types   map[string]EventType
+	schemas map[string]EventSchema
+	// qualified maps Domain+"."+EventType to EventType
+	qualified map[string]string
+}
+
+// qualifiedName is the name DispatchEvent gives eventType, or "" when it is
+// the EventType itself.
+func qualifiedName(eventType EventType) string {
+	if eventType.Domain == "" || strings.HasPrefix(eventType.EventType, eventType.Domain+".") {
+		return ""
+	}
+	return eventType.Domain + "." + eventType.EventType
+}
+
+// Add records event types the hub accepted.
+func (r *EventRegistry) Add(eventTypes ...EventType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.types == nil {
+		r.types = make(map[string]EventType)
+		r.qualified = make(map[string]string)
+	}
+	for _, eventType := range eventTypes {
+		r.types[eventType.EventType] = eventType
+		if name := qualifiedName(eventType); name != "" {
+			r.qualified[name] = eventType.EventType
+		}
+	}
+}
+
+// lookup resolves eventType, by EventType first. r.mu must be held.
+func (r *EventRegistry) lookup(eventType string) (EventType, bool) {
+	if registered, ok := r.types[eventType]; ok {
+		return registered, true
+	}
+	registered, ok := r.types[r.qualified[eventType]]
+	return registered, ok
+}
+
+// Lookup returns a registered event type.
+func (r *EventRegistry) Lookup(eventType string) (EventType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookup(eventType)
+}
+
+// Types returns the registered event types, by EventType.
+func (r *EventRegistry) Types() []EventType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]EventType, 0, len(r.types))
+	for _, eventType := range r.types {
+		types = append(types, eventType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].EventType < types[j].EventType })
+	return types
+}
+
+// SetSchema declares the properties of an event type, by either of its
+// names, replacing any schema declared before under that name. The type may
+// be registered before or after.
+func (r *EventRegistry) SetSchema(eventType string, schema EventSchema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.schemas == nil {
+		r.schemas = make(map[string]EventSchema)
+	}
+	r.schemas[eventType] = schema
+}
+
+// Validate checks an event against the registry: its type must be
+// registered, and its properties must match the schema of the type, if one
+// was declared. The error wraps ErrUnknownEventType or ErrInvalidEvent.
+func (r *EventRegistry) Validate(eventType string, properties map[string]string) error {
+	r.mu.RLock()
+	registered, known := r.lookup(eventType)
+	schema, hasSchema := r.schemas[eventType]
+	if known && !hasSchema {
+		schema, hasSchema = r.schemas[registered.EventType]
+	}
+	if known && !hasSchema {
+		schema, hasSchema = r.schemas[qualifiedName(registered)]
+	}
+	r.mu.RUnlock()
+	if !known {
+		return fmt.Errorf("%w %q", ErrUnknownEventType, eventType)
+	}
+	if !hasSchema {
+		return nil
+	}
+	if problems := schema.validate(properties); len(problems) > 0 {
+		return fmt.Errorf("%w %q: %s", ErrInvalidEvent, eventType, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// EventRegistry implements EventRegistryController.
+func (o *objectController) EventRegistry() *EventRegistry {
+	return &o.eventTypes
+}
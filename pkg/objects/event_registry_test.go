@@ -0,0 +1,69 @@
+package objects
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRegistry_Validate(t *testing.T) {
+	var registry EventRegistry
+	assert.ErrorIs(t, registry.Validate("door.event.forced", nil), ErrUnknownEventType)
+
+	registry.Add(EventType{Domain: "door", EventType: "door.event.forced"})
+	assert.NoError(t, registry.Validate("door.event.forced", map[string]string{"anything": "goes"}),
+		"a type without schema takes any property")
+	assert.ErrorIs(t, registry.Validate("door.event.forcd", nil), ErrUnknownEventType)
+
+	registry.SetSchema("door.event.forced", EventSchema{Properties: map[string]PropertySchema{
+		"zone":  {Type: PropertyInt, Required: true},
+		"level": {Enum: []string{"low", "high"}},
+		"at":    {Type: PropertyTime},
+	}})
+	assert.NoError(t, registry.Validate("door.event.forced", map[string]string{"zone": "3", "level": "high", "at": "2026-01-02T03:04:05Z"}))
+
+	err := registry.Validate("door.event.forced", map[string]string{"zone": "three", "level": "medium", "user": "x"})
+	require.ErrorIs(t, err, ErrInvalidEvent)
+	assert.Contains(t, err.Error(), `property "zone": "three" is not a int`)
+	assert.Contains(t, err.Error(), `property "level": "medium" is not one of low, high`)
+	assert.Contains(t, err.Error(), `unknown property "user"`)
+
+	err = registry.Validate("door.event.forced", map[string]string{})
+	assert.ErrorContains(t, err, `missing property "zone"`)
+
+	assert.Equal(t, []EventType{{Domain: "door", EventType: "door.event.forced"}}, registry.Types())
+}
+
+// DispatchEvent names a type after its domain and key, as in
+// docs/first-driver.md.
+func TestEventRegistry_domainQualifiedNames(t *testing.T) {
+	var registry EventRegistry
+	alert := EventType{Domain: "temperature", EventType: "temperature_alert"}
+	registry.Add(alert)
+
+	for _, name := range []string{"temperature_alert", "temperature.temperature_alert"} {
+		registered, ok := registry.Lookup(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, alert, registered, name)
+		assert.NoError(t, registry.Validate(name, nil), name)
+	}
+	assert.ErrorIs(t, registry.Validate("humidity.temperature_alert", nil), ErrUnknownEventType)
+
+	registry.SetSchema("temperature_alert", EventSchema{Properties: map[string]PropertySchema{
+		"temperature": {Type: PropertyFloat, Required: true},
+	}})
+	assert.NoError(t, registry.Validate("temperature.temperature_alert", map[string]string{"temperature": "31.5"}))
+	assert.ErrorIs(t, registry.Validate("temperature.temperature_alert", map[string]string{"temperature": "hot"}), ErrInvalidEvent)
+	assert.Equal(t, []EventType{alert}, registry.Types(), "a type is listed once")
+}
+
+func TestParseEventDispatchResponse(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"id":"ev-1"}`: "ev-1",
+		`"ev-2"`:        "ev-2",
+		"ev-3\n":        "ev-3",
+	} {
+		assert.Equal(t, want, ParseEventDispatchResponse([]byte(body)).ID, body)
+	}
+}
@@ -0,0 +1,212 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+)
+
+// executionController is the ObjectController handed to the callbacks of the
+// built-in objects while an action executes. It carries the execution context
+// so callbacks whose signature predates contexts can still honour the action
+// deadline: the calls without a context, such as SetState, run with it, and
+// ExecutionContext returns it. It implements ObjectControllerCtx.
+type executionController struct {
+	ObjectControllerCtx
+	inner       ObjectController
+	ctx         context.Context
+	executionID string
+}
+
+// capableController is the set of optional interfaces of the controller
+// returned by NewObjectController.
+type capableController interface {
+	StateCacheController
+	ConnectionStateNotifier
+	OutboxController
+	StateBatchController
+	EventRegistryController
+	ObjectLifecycleController
+	ActionResultReporter
+}
+
+// capableExecutionController is the executionController of a controller with
+// every optional interface, so the type assertions of callbacks keep working.
+type capableExecutionController struct {
+	*executionController
+	capableController
+}
+
+// ReportActionResult implements ActionResultReporter with the execution
+// context.
+func (c *capableExecutionController) ReportActionResult(executionID string, result ActionResult) error {
+	return c.capableController.ReportActionResultContext(c.ctx, executionID, result)
+}
+
+var errNotInExecution = errors.New("controller was not handed to an action execution")
+
+func withExecutionContext(ctx context.Context, executionID string, oc ObjectController) ObjectController {
+	if oc == nil {
+		return nil
+	}
+	oc = UnwrapController(oc)
+	ec := &executionController{ObjectControllerCtx: WithContext(oc), inner: oc, ctx: ctx, executionID: executionID}
+	if capable, ok := oc.(capableController); ok {
+		return &capableExecutionController{executionController: ec, capableController: capable}
+	}
+	return ec
+}
+
+// execution returns the executionController of oc, if it is one.
+func execution(oc ObjectController) (*executionController, bool) {
+	switch ec := oc.(type) {
+	case *executionController:
+		return ec, true
+	case *capableExecutionController:
+		return ec.executionController, true
+	}
+	return nil, false
+}
+
+// UnwrapController returns the controller an action execution handed to a
+// callback was made from, or oc itself outside an execution. The controller
+// handed to a callback implements the optional interfaces of the controller
+// returned by NewObjectController; UnwrapController reaches those of other
+// controllers.
+func UnwrapController(oc ObjectController) ObjectController {
+	if ec, ok := execution(oc); ok {
+		return ec.inner
+	}
+	return oc
+}
+
+// ExecutionContext returns the context of the action execution that handed oc
+// to a callback (e.g. a video channel SnapshotFn or a door OpenDoorMethod). It
+// is cancelled when the action times out or the client shuts down. Outside an
+// action execution it returns context.Background().
+//
+//	PtzFn: func(v objects.VideoChannelObject, oc objects.ObjectController, p objects.VideoChannelActionPtzControlPayload) error {
+//		return camera.Move(objects.ExecutionContext(oc), p.Command, p.Value)
+//	},
+func ExecutionContext(oc ObjectController) context.Context {
+	if ec, ok := execution(oc); ok {
+		return ec.ctx
+	}
+	return context.Background()
+}
+
+// The ObjectController methods of an executionController run with the
+// execution context.
+
+func (ec *executionController) SetState(objectId, state string) error {
+	return ec.SetStateContext(ec.ctx, objectId, state)
+}
+
+func (ec *executionController) UpdateStateAttributes(objectId string, attributes map[string]string) error {
+	return ec.UpdateStateAttributesContext(ec.ctx, objectId, attributes)
+}
+
+func (ec *executionController) UpdateResultAttributes(executionID string, attributes map[string]string) error {
+	return ec.UpdateResultAttributesContext(ec.ctx, executionID, attributes)
+}
+
+func (ec *executionController) NewAction(action ObjectAction) error {
+	return ec.NewActionContext(ec.ctx, action)
+}
+
+func (ec *executionController) CreateObject(obj RegistrableObject) error {
+	return ec.CreateObjectContext(ec.ctx, obj)
+}
+
+func (ec *executionController) GetState(objectId string) (StateRecord, error) {
+	return ec.GetStateContext(ec.ctx, objectId)
+}
+
+func (ec *executionController) DisabledObject(objectId string) error {
+	return ec.DisabledObjectContext(ec.ctx, objectId)
+}
+
+func (ec *executionController) EnabledObject(objectId string) error {
+	return ec.EnabledObjectContext(ec.ctx, objectId)
+}
+
+func (ec *executionController) AddEventTypes(eventTypes []EventType) error {
+	return ec.AddEventTypesContext(ec.ctx, eventTypes)
+}
+
+func (ec *executionController) Increment(objectId string) error {
+	return ec.IncrementContext(ec.ctx, objectId)
+}
+
+func (ec *executionController) Decrement(objectId string) error {
+	return ec.DecrementContext(ec.ctx, objectId)
+}
+
+// Values of the "error_code" result attribute set by the runner.
+const (
+	// ActionErrorCodeTimeout: the execution exceeded its deadline.
+	ActionErrorCodeTimeout = "timeout"
+	// ActionErrorCodeCancelled: the execution was cancelled, e.g. on shutdown.
+	ActionErrorCodeCancelled = "cancelled"
+	// ActionErrorCodeBusy: rejected by ExecutionDropWhenBusy.
+	ActionErrorCodeBusy = "busy"
+	// ActionErrorCodeSuperseded: replaced by a newer request under
+	// ExecutionLatestWins.
+	ActionErrorCodeSuperseded = "superseded"
+	// ActionErrorCodeQueueFull: the execution policy queue was full.
+	ActionErrorCodeQueueFull = "queue_full"
+	// ActionErrorCodePanic: the action panicked; the panic was recovered.
+	ActionErrorCodePanic = "panic"
+)
+
+// payloadTimeout reads the "timeout" field, in seconds, that DriversHub sends
+// with long-running actions (video clips, downloads, audio clips).
+func payloadTimeout(payload map[string]interface{}) time.Duration {
+	var seconds float64
+	switch v := payload["timeout"].(type) {
+	case float64:
+		seconds = v
+	case string:
+		seconds, _ = strconv.ParseFloat(v, 64)
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// errorResult is the result reported for a failed execution. Failures the
+// runner recognizes carry a machine-readable error code.
+func errorResult(action string, err error, timeout time.Duration) ActionResult {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ActionResult{
+			Status:    ActionStatusFailed,
+			Error:     fmt.Sprintf("action %s timed out after %s", action, timeout),
+			ErrorCode: ActionErrorCodeTimeout,
+		}
+	case errors.Is(err, context.Canceled):
+		return ActionResult{
+			Status:    ActionStatusCancelled,
+			Error:     fmt.Sprintf("action %s cancelled", action),
+			ErrorCode: ActionErrorCodeCancelled,
+		}
+	case errors.Is(err, ErrExecutionBusy):
+		return ActionResult{Status: ActionStatusFailed, Error: err.Error(), ErrorCode: ActionErrorCodeBusy}
+	case errors.Is(err, ErrExecutionSuperseded):
+		return ActionResult{Status: ActionStatusCancelled, Error: err.Error(), ErrorCode: ActionErrorCodeSuperseded}
+	case errors.Is(err, ErrExecutionQueueFull):
+		return ActionResult{Status: ActionStatusFailed, Error: err.Error(), ErrorCode: ActionErrorCodeQueueFull}
+	}
+	var panicErr *recovery.PanicError
+	if errors.As(err, &panicErr) {
+		return ActionResult{Status: ActionStatusFailed, Error: err.Error(), ErrorCode: ActionErrorCodePanic}
+	}
+	return ActionResult{Status: ActionStatusFailed, Error: err.Error()}
+}
+
+var _ capableController = (*objectController)(nil)
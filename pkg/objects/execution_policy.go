@@ -0,0 +1,168 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ExecutionMode selects how the runner schedules concurrent action executions
+// on the same object or domain.
+type ExecutionMode string
+
+const (
+	// ExecutionParallel runs every request right away. It is the default.
+	ExecutionParallel ExecutionMode = "parallel"
+	// ExecutionSerial runs one request at a time, in arrival order.
+	ExecutionSerial ExecutionMode = "serial"
+	// ExecutionBounded runs up to MaxConcurrent requests at a time, in arrival
+	// order.
+	ExecutionBounded ExecutionMode = "bounded"
+	// ExecutionLatestWins runs one request at a time and keeps only the newest
+	// waiting one: a request arriving while another waits supersedes it. Suits
+	// PTZ moves and set-points, where only the last command matters.
+	ExecutionLatestWins ExecutionMode = "latest_wins"
+	// ExecutionDropWhenBusy rejects requests while one is running.
+	ExecutionDropWhenBusy ExecutionMode = "drop_when_busy"
+)
+
+var (
+	// ErrExecutionBusy is reported when ExecutionDropWhenBusy rejects a request.
+	ErrExecutionBusy = errors.New("object is busy with another action execution")
+	// ErrExecutionSuperseded is reported for a waiting request replaced by a
+	// newer one under ExecutionLatestWins.
+	ErrExecutionSuperseded = errors.New("action execution superseded by a newer request")
+	// ErrExecutionQueueFull is reported when MaxQueue requests are already waiting.
+	ErrExecutionQueueFull = errors.New("action execution queue is full")
+)
+
+// ExecutionPolicy limits how many action executions run at the same time on an
+// object, or across all objects of a domain. Requests over the limit wait in a
+// queue; their queue depth and wait time are reported to the DriverHub through
+// UpdateResultAttributes ("queue_depth", "queue_wait_ms").
+type ExecutionPolicy struct {
+	Mode ExecutionMode
+	// MaxConcurrent is the limit for ExecutionBounded.
+	MaxConcurrent int
+	// MaxQueue caps the waiting requests for ExecutionSerial and
+	// ExecutionBounded; 0 means unbounded.
+	MaxQueue int
+}
+
+func (p ExecutionPolicy) validate() error {
+	switch p.Mode {
+	case "", ExecutionParallel, ExecutionSerial, ExecutionLatestWins, ExecutionDropWhenBusy:
+	case ExecutionBounded:
+		if p.MaxConcurrent < 1 {
+			return errors.New("bounded execution policy requires MaxConcurrent >= 1")
+		}
+	default:
+		return fmt.Errorf("unknown execution mode %q", p.Mode)
+	}
+	if p.MaxQueue < 0 {
+		return errors.New("MaxQueue cannot be negative")
+	}
+	return nil
+}
+
+func (p ExecutionPolicy) limit() int {
+	switch p.Mode {
+	case ExecutionBounded:
+		return p.MaxConcurrent
+	case ExecutionSerial, ExecutionLatestWins, ExecutionDropWhenBusy:
+		return 1
+	}
+	return 0
+}
+
+// executionGate enforces one ExecutionPolicy. Waiters are granted the slot of
+// the execution that releases it, so arrival order is kept.
+type executionGate struct {
+	policy ExecutionPolicy
+
+	mu      sync.Mutex
+	running int
+	queue   []chan error
+}
+
+func newExecutionGate(policy ExecutionPolicy) *executionGate {
+	return &executionGate{policy: policy}
+}
+
+// acquire blocks until the execution may run. onQueued is called with the
+// queue depth when the request has to wait. The returned release must be
+// called once the execution finishes; waited is the time spent in the queue.
+func (g *executionGate) acquire(ctx context.Context, onQueued func(depth int)) (release func(), waited time.Duration, err error) {
+	limit := g.policy.limit()
+	if limit == 0 {
+		return func() {}, 0, nil
+	}
+
+	g.mu.Lock()
+	if g.running < limit && len(g.queue) == 0 {
+		g.running++
+		g.mu.Unlock()
+		return g.release, 0, nil
+	}
+	switch g.policy.Mode {
+	case ExecutionDropWhenBusy:
+		g.mu.Unlock()
+		return nil, 0, ErrExecutionBusy
+	case ExecutionLatestWins:
+		for _, superseded := range g.queue {
+			superseded <- ErrExecutionSuperseded
+		}
+		g.queue = g.queue[:0]
+	default:
+		if g.policy.MaxQueue > 0 && len(g.queue) >= g.policy.MaxQueue {
+			g.mu.Unlock()
+			return nil, 0, ErrExecutionQueueFull
+		}
+	}
+	ready := make(chan error, 1)
+	g.queue = append(g.queue, ready)
+	depth := len(g.queue)
+	g.mu.Unlock()
+
+	if onQueued != nil {
+		onQueued(depth)
+	}
+	start := time.Now()
+	select {
+	case err := <-ready:
+		if err != nil {
+			return nil, time.Since(start), err
+		}
+		return g.release, time.Since(start), nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		for i, waiting := range g.queue {
+			if waiting == ready {
+				g.queue = append(g.queue[:i], g.queue[i+1:]...)
+				g.mu.Unlock()
+				return nil, time.Since(start), ctx.Err()
+			}
+		}
+		g.mu.Unlock()
+		// The slot was granted or superseded right as ctx ended.
+		if err := <-ready; err == nil {
+			g.release()
+		}
+		return nil, time.Since(start), ctx.Err()
+	}
+}
+
+// release hands the slot to the next waiter, or frees it.
+func (g *executionGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.queue) > 0 {
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		next <- nil
+		return
+	}
+	g.running--
+}
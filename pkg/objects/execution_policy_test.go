@@ -0,0 +1,134 @@
+package objects
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionPolicy_validate(t *testing.T) {
+	assert.NoError(t, ExecutionPolicy{}.validate())
+	assert.NoError(t, ExecutionPolicy{Mode: ExecutionSerial, MaxQueue: 3}.validate())
+	assert.Error(t, ExecutionPolicy{Mode: ExecutionBounded}.validate())
+	assert.Error(t, ExecutionPolicy{Mode: "fastest"}.validate())
+	assert.Error(t, ExecutionPolicy{Mode: ExecutionSerial, MaxQueue: -1}.validate())
+}
+
+func TestExecutionGate_serialKeepsArrivalOrder(t *testing.T) {
+	gate := newExecutionGate(ExecutionPolicy{Mode: ExecutionSerial})
+	release, _, err := gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	order := []int{}
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		queued := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next, _, err := gate.acquire(context.Background(), func(depth int) {
+				assert.Equal(t, i, depth)
+				close(queued)
+			})
+			require.NoError(t, err)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			next()
+		}()
+		<-queued
+	}
+
+	release()
+	wg.Wait()
+	assert.Equal(t, []int{1, 2, 3}, order)
+}
+
+func TestExecutionGate_bounded(t *testing.T) {
+	gate := newExecutionGate(ExecutionPolicy{Mode: ExecutionBounded, MaxConcurrent: 2, MaxQueue: 1})
+	r1, _, err := gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	r2, _, err := gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+
+	granted := make(chan time.Duration, 1)
+	queued := make(chan struct{})
+	go func() {
+		r3, waited, err := gate.acquire(context.Background(), func(int) { close(queued) })
+		require.NoError(t, err)
+		granted <- waited
+		r3()
+	}()
+	<-queued
+
+	_, _, err = gate.acquire(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrExecutionQueueFull)
+
+	time.Sleep(10 * time.Millisecond)
+	r1()
+	assert.GreaterOrEqual(t, <-granted, 10*time.Millisecond)
+	r2()
+}
+
+func TestExecutionGate_latestWins(t *testing.T) {
+	gate := newExecutionGate(ExecutionPolicy{Mode: ExecutionLatestWins})
+	release, _, err := gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+
+	first := make(chan error, 1)
+	queued := make(chan struct{})
+	go func() {
+		_, _, err := gate.acquire(context.Background(), func(int) { close(queued) })
+		first <- err
+	}()
+	<-queued
+
+	second := make(chan error, 1)
+	go func() {
+		next, _, err := gate.acquire(context.Background(), nil)
+		if err == nil {
+			next()
+		}
+		second <- err
+	}()
+
+	assert.ErrorIs(t, <-first, ErrExecutionSuperseded)
+	release()
+	assert.NoError(t, <-second)
+}
+
+func TestExecutionGate_dropWhenBusy(t *testing.T) {
+	gate := newExecutionGate(ExecutionPolicy{Mode: ExecutionDropWhenBusy})
+	release, _, err := gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+
+	_, _, err = gate.acquire(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrExecutionBusy)
+
+	release()
+	release, _, err = gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	release()
+}
+
+func TestExecutionGate_cancelledWhileQueued(t *testing.T) {
+	gate := newExecutionGate(ExecutionPolicy{Mode: ExecutionSerial})
+	release, _, err := gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err = gate.acquire(ctx, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The cancelled waiter left the queue: the slot is free again.
+	release()
+	release, _, err = gate.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	release()
+}
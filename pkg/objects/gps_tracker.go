@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 type GpsTrackerObject interface {
 	RegistrableObject
 	CustomActionRegistrar
@@ -31,7 +33,12 @@ func (g *gpsTrackerObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements GpsTrackerObject.
 func (g *gpsTrackerObject) RunAction(id string, action string, payload []byte) (map[string]string, error) {
-	return g.dispatchCustom(g, g.controller, id, action, payload)
+	return g.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (g *gpsTrackerObject) RunActionContext(ctx context.Context, id string, action string, payload []byte) (map[string]string, error) {
+	return g.dispatchCustom(ctx, g, g.controller, id, action, payload)
 }
 
 // SetState implements GpsTrackerObject.
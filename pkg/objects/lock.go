@@ -1,6 +1,9 @@
 package objects
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 const LOCK_STATE_JAMMED = "jammed"
 const LOCK_STATE_OPEN = "open"
@@ -70,25 +73,30 @@ func (d *lockObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements LockObject.
 func (d *lockObject) RunAction(id string, action string, payload []byte) (map[string]string, error) {
+	return d.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (d *lockObject) RunActionContext(ctx context.Context, id string, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case LOCK_ACTION_LOCK:
 		if d.lockMethod == nil {
 			return nil, errors.New("lock method is not set")
 		}
-		return d.lockMethod(d, d.controller)
+		return d.lockMethod(d, withExecutionContext(ctx, id, d.controller))
 	case LOCK_ACTION_UNLOCK:
 		if d.unlockMethod == nil {
 			return nil, errors.New("unlock method is not set")
 		}
-		return d.unlockMethod(d, d.controller)
+		return d.unlockMethod(d, withExecutionContext(ctx, id, d.controller))
 	case LOCK_ACTION_REBOOT:
 		if d.rebootMethod == nil {
 			return nil, errors.New("reboot method is not set")
 		}
-		return d.rebootMethod(d, d.controller)
+		return d.rebootMethod(d, withExecutionContext(ctx, id, d.controller))
 	}
 
-	return d.dispatchCustom(d, d.controller, id, action, payload)
+	return d.dispatchCustom(ctx, d, d.controller, id, action, payload)
 }
 
 // SetState implements LockObject.
@@ -0,0 +1,20 @@
+package objects
+
+import "github.com/Netsocs-Team/driver.sdk_go/internal/metrics"
+
+// Kinds of driver_audio_sessions_active.
+const (
+	audioSessionTalkback   = "talkback"
+	audioSessionMicrophone = "microphone"
+)
+
+var (
+	actionExecutions = metrics.NewCounterVec("driver_action_executions_total",
+		"Action executions, by domain, action and outcome (succeeded, failed, cancelled).",
+		"domain", "action", "outcome")
+	actionDuration = metrics.NewHistogramVec("driver_action_duration_seconds",
+		"Duration of the action executions, from the request to the result, queue wait included.",
+		nil, "domain", "action")
+	audioSessions = metrics.NewGaugeVec("driver_audio_sessions_active",
+		"Open talkback and microphone sessions.", "kind")
+)
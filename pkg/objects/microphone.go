@@ -8,6 +8,7 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
 )
 
@@ -26,6 +27,8 @@ type MicStream struct {
 	ws   *websocket.Conn
 	done chan struct{}
 	once sync.Once
+	// cancel cancels the context passed to the session handler.
+	cancel context.CancelFunc
 }
 
 // WriteRTP forwards raw RTP bytes to DriversHub over the WebSocket connection.
@@ -36,9 +39,13 @@ func (s *MicStream) WriteRTP(data []byte) error {
 // Done returns a channel that is closed when the stream ends.
 func (s *MicStream) Done() <-chan struct{} { return s.done }
 
-// Close terminates the stream and the underlying WebSocket connection.
+// Close terminates the stream and the underlying WebSocket connection, and
+// cancels the context passed to the session handler.
 func (s *MicStream) Close() {
 	s.once.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
 		s.ws.Close()
 		close(s.done)
 	})
@@ -73,6 +80,7 @@ type microphoneObject struct {
 	props         NewMicrophoneObjectProps
 	controller    ObjectController
 	activeStreams sync.Map // sessionID → *MicStream
+	sessions      sync.WaitGroup
 }
 
 func (m *microphoneObject) GetMetadata() ObjectMetadata {
@@ -124,13 +132,18 @@ func (m *microphoneObject) Setup(ctrl ObjectController) error {
 }
 
 func (m *microphoneObject) RunAction(executionID, action string, payload []byte) (map[string]string, error) {
+	return m.RunActionContext(context.Background(), executionID, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (m *microphoneObject) RunActionContext(ctx context.Context, executionID, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case MICROPHONE_ACTION_START_STREAM:
 		return m.startStream(payload)
 	case MICROPHONE_ACTION_STOP_STREAM:
 		return m.stopStream(payload)
 	}
-	return m.dispatchCustom(m, m.controller, executionID, action, payload)
+	return m.dispatchCustom(ctx, m, m.controller, executionID, action, payload)
 }
 
 func (m *microphoneObject) startStream(payload []byte) (map[string]string, error) {
@@ -147,12 +160,16 @@ func (m *microphoneObject) startStream(payload []byte) (map[string]string, error
 		return nil, fmt.Errorf("microphone: dial DriversHub audio stream: %w", err)
 	}
 
-	stream := &MicStream{ws: ws, done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &MicStream{ws: ws, done: make(chan struct{}), cancel: cancel}
 	m.activeStreams.Store(p.SessionID, stream)
 	_ = m.SetStateStreaming()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	m.sessions.Add(1)
+	audioSessions.Inc(audioSessionMicrophone)
 	go func() {
+		defer m.sessions.Done()
+		defer audioSessions.Dec(audioSessionMicrophone)
 		defer cancel()
 		defer func() {
 			m.activeStreams.Delete(p.SessionID)
@@ -160,7 +177,9 @@ func (m *microphoneObject) startStream(payload []byte) (map[string]string, error
 			_ = m.SetStateIdle()
 		}()
 		if m.props.StartStreamFn != nil {
-			m.props.StartStreamFn(ctx, p.SessionID, stream)
+			// The driver callback runs outside the action handler, so it
+			// gets its own recovery: a panic ends the session only.
+			recovery.Guard(recovery.BoundaryAction, func() { m.props.StartStreamFn(ctx, p.SessionID, stream) })
 		}
 	}()
 
@@ -178,6 +197,18 @@ func (m *microphoneObject) stopStream(payload []byte) (map[string]string, error)
 	return nil, nil
 }
 
+// Close ends every active stream and waits for their StartStreamFn calls to
+// return. The client calls it on shutdown.
+func (m *microphoneObject) Close() error {
+	m.activeStreams.Range(func(key, v any) bool {
+		m.activeStreams.Delete(key)
+		v.(*MicStream).Close()
+		return true
+	})
+	m.sessions.Wait()
+	return nil
+}
+
 // NewMicrophoneObject creates a MicrophoneObject ready to be registered with the SDK client.
 func NewMicrophoneObject(props NewMicrophoneObjectProps) MicrophoneObject {
 	return &microphoneObject{props: props}
@@ -315,3 +315,27 @@ func TestBuildAudioStreamURL(t *testing.T) {
 		assert.Equal(t, c.expected, got, "hub=%q session=%q", c.hub, c.session)
 	}
 }
+
+func TestMicrophoneObject_StartStream_recoversPanic(t *testing.T) {
+	connCh := make(chan *websocket.Conn, 1)
+	srv := newWSSServer(t, connCh)
+	defer srv.Close()
+
+	ctrl := newMockMicController(srv.URL)
+	mic := NewMicrophoneObject(NewMicrophoneObjectProps{
+		Metadata: ObjectMetadata{ObjectID: "mic.1", Domain: "d"},
+		StartStreamFn: func(ctx context.Context, sessionID string, stream *MicStream) error {
+			panic("driver bug")
+		},
+	})
+	require.NoError(t, mic.Setup(ctrl))
+
+	payload, _ := json.Marshal(map[string]string{"session_id": "s1"})
+	_, err := mic.RunAction("e1", MICROPHONE_ACTION_START_STREAM, payload)
+	require.NoError(t, err)
+	<-connCh
+
+	// The panic ends the session, not the process.
+	require.NoError(t, mic.(*microphoneObject).Close())
+	assert.Equal(t, MICROPHONE_STATE_IDLE, ctrl.getState("mic.1"))
+}
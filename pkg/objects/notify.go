@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"github.com/goccy/go-json"
 )
 
@@ -63,18 +64,23 @@ func (n *notifierObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements NotifierObject.
 func (n *notifierObject) RunAction(id string, action string, payload []byte) (map[string]string, error) {
+	return n.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (n *notifierObject) RunActionContext(ctx context.Context, id string, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case CREATE:
 		var p CreatePayload
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		if err := n.createFn(n, n.controller, p); err != nil {
+		if err := n.createFn(n, withExecutionContext(ctx, id, n.controller), p); err != nil {
 			return nil, err
 		}
 		return nil, nil
 	}
-	return n.dispatchCustom(n, n.controller, id, action, payload)
+	return n.dispatchCustom(ctx, n, n.controller, id, action, payload)
 }
 
 // SetState implements NotifierObject.
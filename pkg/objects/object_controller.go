@@ -1,19 +1,20 @@
 package objects
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"net/http"
-	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/Netsocs-Team/driver.sdk_go/internal/eventbus"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/tools"
 	"github.com/go-resty/resty/v2"
 	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
 )
 
 type ObjectController interface {
@@ -33,18 +34,67 @@ type ObjectController interface {
 	Decrement(objectId string) error
 }
 
+// ObjectControllerCtx is the context-aware counterpart of ObjectController.
+// Every call that reaches the DriverHub takes a context whose deadline and
+// cancellation are honoured by the underlying HTTP request, so a slow hub cannot
+// block a device goroutine forever and pending work can be abandoned during
+// shutdown. The controller returned by NewObjectController implements it; use
+// WithContext to obtain one from any ObjectController.
+type ObjectControllerCtx interface {
+	ObjectController
+	SetStateContext(ctx context.Context, objectId string, state string) error
+	UpdateStateAttributesContext(ctx context.Context, objectId string, attributes map[string]string) error
+	UpdateResultAttributesContext(ctx context.Context, ActionExecutionID string, attributes map[string]string) error
+	NewActionContext(ctx context.Context, action ObjectAction) error
+	CreateObjectContext(ctx context.Context, obj RegistrableObject) error
+	ListenActionRequestsContext(ctx context.Context) error
+	GetStateContext(ctx context.Context, objectId string) (state StateRecord, err error)
+	DisabledObjectContext(ctx context.Context, objectId string) error
+	EnabledObjectContext(ctx context.Context, objectId string) error
+	AddEventTypesContext(ctx context.Context, eventTypes []EventType) error
+	IncrementContext(ctx context.Context, objectId string) error
+	DecrementContext(ctx context.Context, objectId string) error
+}
+
 type objectController struct {
 	driverhub_host string
 	driver_key     string
 	token          string
 	httpClient     *resty.Client
+
+	// objects websocket supervision, see actions_websocket.go
+	wsMu              sync.Mutex
+	wsConn            *websocket.Conn
+	registeredDomains func() []string
+	stateListeners    []func(httpx.ConnectionStateChange)
+
+	// offline buffering, see object_controller_outbox.go
+	outboxMu sync.Mutex
+	outbox   *outbox.Outbox
+
+	// write-behind batching, see state_batcher.go
+	batcherMu sync.Mutex
+	batcher   *stateBatcher
+
+	// last known state per object, see state_cache.go
+	states stateCache
+
+	// event types accepted by the hub, see event_registry.go
+	eventTypes EventRegistry
+
+	// see EnableStructuredActionResults
+	structuredResults atomic.Bool
+
+	// set once the hub served the object listing, see
+	// object_controller_lifecycle.go
+	lifecycleSupported atomic.Bool
 }
 
-// GetState implements ObjectController.
-func (o *objectController) GetState(objectId string) (state StateRecord, err error) {
+// GetStateContext implements ObjectControllerCtx.
+func (o *objectController) GetStateContext(ctx context.Context, objectId string) (state StateRecord, err error) {
 	url := fmt.Sprintf("%s/objects/states/%s?limit=1", o.driverhub_host, objectId)
 	var paginated PaginatedStateRecord
-	resp, err := o.httpClient.R().
+	resp, err := o.httpClient.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
 		Get(url)
@@ -53,7 +103,7 @@ func (o *objectController) GetState(objectId string) (state StateRecord, err err
 	}
 
 	if resp.StatusCode() >= 400 {
-		return state, errors.New(resp.String())
+		return state, httpx.NewHubError(resp)
 	}
 
 	err = json.Unmarshal(resp.Body(), &paginated)
@@ -68,11 +118,11 @@ func (o *objectController) GetState(objectId string) (state StateRecord, err err
 	return state, nil
 }
 
-// UpdateResultAttributes implements ObjectController.
-func (o *objectController) UpdateResultAttributes(executionID string, attributes map[string]string) error {
+// UpdateResultAttributesContext implements ObjectControllerCtx.
+func (o *objectController) UpdateResultAttributesContext(ctx context.Context, executionID string, attributes map[string]string) error {
 	url := fmt.Sprintf("%s/objects/actions/executions/%s", o.driverhub_host, executionID)
 	body := map[string]map[string]string{"result": attributes}
-	_, err := o.httpClient.R().
+	_, err := o.httpClient.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
 		SetBody(body).
@@ -80,10 +130,45 @@ func (o *objectController) UpdateResultAttributes(executionID string, attributes
 	return err
 }
 
-// Increment implements ObjectController.
-func (o *objectController) Increment(objectId string) error {
+// EnableStructuredActionResults makes ReportActionResult send the status,
+// progress and typed data of each result. Until then it sends
+// {"result": attributes} with the data flattened to strings, as
+// UpdateResultAttributes does, for hubs that do not read structured results.
+func (o *objectController) EnableStructuredActionResults() {
+	o.structuredResults.Store(true)
+}
+
+func (o *objectController) structuredActionResults() bool {
+	return o.structuredResults.Load()
+}
+
+// ReportActionResultContext implements ActionResultReporter.
+func (o *objectController) ReportActionResultContext(ctx context.Context, executionID string, result ActionResult) error {
+	if !o.structuredResults.Load() {
+		return o.UpdateResultAttributesContext(ctx, executionID, result.attributes())
+	}
+	url := fmt.Sprintf("%s/objects/actions/executions/%s", o.driverhub_host, executionID)
+	resp, err := o.httpClient.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Auth-Token", o.token).
+		SetBody(result.body()).
+		Put(url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 400 {
+		return httpx.NewHubError(resp)
+	}
+	return nil
+}
+
+// IncrementContext implements ObjectControllerCtx.
+func (o *objectController) IncrementContext(ctx context.Context, objectId string) error {
+	o.states.forget(objectId)
 	url := fmt.Sprintf("%s/objects/states/%s/increment", o.driverhub_host, objectId)
-	resp, err := o.httpClient.R().
+	// Not idempotent despite the PUT: a retry after a lost response would
+	// count twice.
+	resp, err := o.httpClient.R().SetContext(httpx.WithoutRetry(ctx)).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
 		Put(url)
@@ -91,15 +176,18 @@ func (o *objectController) Increment(objectId string) error {
 		return err
 	}
 	if resp.StatusCode() >= 400 {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 	return nil
 }
 
-// Decrement implements ObjectController.
-func (o *objectController) Decrement(objectId string) error {
+// DecrementContext implements ObjectControllerCtx.
+func (o *objectController) DecrementContext(ctx context.Context, objectId string) error {
+	o.states.forget(objectId)
 	url := fmt.Sprintf("%s/objects/states/%s/decrement", o.driverhub_host, objectId)
-	resp, err := o.httpClient.R().
+	// Not idempotent despite the PUT: a retry after a lost response would
+	// count twice.
+	resp, err := o.httpClient.R().SetContext(httpx.WithoutRetry(ctx)).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
 		Put(url)
@@ -107,7 +195,7 @@ func (o *objectController) Decrement(objectId string) error {
 		return err
 	}
 	if resp.StatusCode() >= 400 {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 	return nil
 }
@@ -131,7 +219,10 @@ type EventTypesBatchResponse struct {
 	Failed     []EventTypeResponse `json:"failed"`
 }
 
-func (o *objectController) AddEventTypes(eventTypes []EventType) error {
+// AddEventTypesContext implements ObjectControllerCtx.
+func (o *objectController) AddEventTypesContext(ctx context.Context, eventTypes []EventType) error {
+	// An event type created twice is reported as existing, which is fine.
+	ctx = httpx.WithRetry(ctx)
 
 	url := fmt.Sprintf("%s/objects/events/types/batch", o.driverhub_host)
 
@@ -149,9 +240,10 @@ func (o *objectController) AddEventTypes(eventTypes []EventType) error {
 		}
 		batch := eventTypes[i:end]
 
-		resp, err := o.httpClient.R().
+		resp, err := o.httpClient.R().SetContext(ctx).
 			SetHeader("Content-Type", "application/json").
 			SetHeader("X-Auth-Token", o.token).
+			SetHeader(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey()).
 			SetBody(batch).
 			Post(url)
 
@@ -162,7 +254,7 @@ func (o *objectController) AddEventTypes(eventTypes []EventType) error {
 		if resp.StatusCode() == 404 {
 			// If the endpoint is not found, we assume that the driverhub does not support batch event type creation.
 			// We will fall back to the old method of creating event types one by one.
-			return o.AddEventTypesFallback(batch)
+			return o.addEventTypesFallback(ctx, batch)
 		}
 
 		if resp.StatusCode() == 201 || resp.StatusCode() == 207 {
@@ -173,25 +265,31 @@ func (o *objectController) AddEventTypes(eventTypes []EventType) error {
 			if err != nil {
 				return err
 			}
-			for _, failed := range eventTypesBatch.Failed {
-				logger.Logger().Error(fmt.Sprintf("failed to post event type: %s/%s", failed.Domain, failed.EventType))
+			failed := make(map[string]bool, len(eventTypesBatch.Failed))
+			for _, f := range eventTypesBatch.Failed {
+				logger.Logger().Error(fmt.Sprintf("failed to post event type: %s/%s", f.Domain, f.EventType))
+				failed[f.EventType] = true
+			}
+			for _, e := range batch {
+				if !failed[e.EventType] {
+					o.eventTypes.Add(e)
+				}
 			}
+			continue
 		}
 
 		if resp.StatusCode() >= 400 {
-			content := resp.String()
-			if strings.Contains(content, "all event types failed to create") {
-				return errors.New("all event types failed to create")
-			}
-			return errors.New(content)
+			return httpx.NewHubError(resp)
 		}
+		o.eventTypes.Add(batch...)
 
 	}
 	return nil
 }
 
-// AddEventTypes implements ObjectController.
-func (o *objectController) AddEventTypesFallback(eventTypes []EventType) error {
+// addEventTypesFallback creates the event types one by one, for hubs without
+// the batch endpoint.
+func (o *objectController) addEventTypesFallback(ctx context.Context, eventTypes []EventType) error {
 
 	if len(eventTypes) == 0 {
 		return errors.New("event types cannot be empty")
@@ -206,29 +304,29 @@ func (o *objectController) AddEventTypesFallback(eventTypes []EventType) error {
 		}
 
 		url := fmt.Sprintf("%s/objects/events/types/%s/%s", o.driverhub_host, e.Domain, e.EventType)
-		resp, err := o.httpClient.R().
+		resp, err := o.httpClient.R().SetContext(ctx).
 			SetHeader("Content-Type", "application/json").
 			SetHeader("X-Auth-Token", o.token).
+			SetHeader(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey()).
 			SetBody(e).
 			Post(url)
 		if err != nil {
 			return err
 		}
 		if resp.StatusCode() >= 400 {
-			content := resp.String()
-			if strings.Contains(content, "Duplicate entry") {
-				continue
+			if err := httpx.NewHubError(resp); !errors.Is(err, ErrObjectAlreadyExists) {
+				return err
 			}
-			return errors.New(content)
 		}
+		o.eventTypes.Add(e)
 	}
 	return nil
 }
 
-// DisabledObject implements ObjectController.
-func (o *objectController) DisabledObject(objectId string) error {
+// DisabledObjectContext implements ObjectControllerCtx.
+func (o *objectController) DisabledObjectContext(ctx context.Context, objectId string) error {
 	url := fmt.Sprintf("%s/objects/%s/disabled", o.driverhub_host, objectId)
-	resp, err := o.httpClient.R().
+	resp, err := o.httpClient.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
 		Put(url)
@@ -236,13 +334,15 @@ func (o *objectController) DisabledObject(objectId string) error {
 		return err
 	}
 	if resp.StatusCode() >= 400 {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 	return nil
 }
-func (o *objectController) EnabledObject(objectId string) error {
+
+// EnabledObjectContext implements ObjectControllerCtx.
+func (o *objectController) EnabledObjectContext(ctx context.Context, objectId string) error {
 	url := fmt.Sprintf("%s/objects/%s/enabled", o.driverhub_host, objectId)
-	resp, err := o.httpClient.R().
+	resp, err := o.httpClient.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
 		Put(url)
@@ -250,7 +350,7 @@ func (o *objectController) EnabledObject(objectId string) error {
 		return err
 	}
 	if resp.StatusCode() >= 400 {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 	return nil
 }
@@ -265,42 +365,66 @@ func (o *objectController) GetDriverhubHost() string {
 	return o.driverhub_host
 }
 
-// UpdateStateAttributes implements ObjectController.
-func (o *objectController) UpdateStateAttributes(objectId string, attributes map[string]string) error {
-	url := fmt.Sprintf("%s/objects/states/%s", o.driverhub_host, objectId)
+// UpdateStateAttributesContext implements ObjectControllerCtx.
+func (o *objectController) UpdateStateAttributesContext(ctx context.Context, objectId string, attributes map[string]string) error {
+	if o.states.redundant(objectId, "", attributes) {
+		return nil
+	}
+	applied, err := o.sendStateAttributes(ctx, objectId, attributes)
+	if err != nil {
+		return err
+	}
+	o.cacheState(objectId, "", attributes, applied)
+	return nil
+}
+
+// sendStateAttributes sends attributes, and reports whether the hub applied
+// them: they may only be queued in the outbox, or belong to a disabled object.
+func (o *objectController) sendStateAttributes(ctx context.Context, objectId string, attributes map[string]string) (applied bool, err error) {
+	if b := o.getBatcher(); b != nil {
+		return b.submit(ctx, objectId, nil, attributes)
+	}
 	body := map[string]map[string]string{"state_additional_properties": attributes}
-	_, err := o.httpClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("X-Auth-Token", o.token).
-		SetBody(body).
-		Put(url)
-	return err
+	resp, queued, err := o.putOrQueue(ctx, "/objects/states/"+objectId, body)
+	if err != nil || queued {
+		return false, err
+	}
+	if resp.StatusCode() >= 400 {
+		if err := httpx.NewHubError(resp); !errors.Is(err, ErrObjectDisabled) {
+			return false, fmt.Errorf("error updating state attributes: %w", err)
+		}
+		return false, nil
+	}
+	return true, nil
 }
 
 type UpdateStateAttributesBatchRequest struct {
 	Changes []ObjectStateChange `json:"changes"`
 }
 
-func (o *objectController) UpdateStateAttributesBatch(objectsStates []ObjectStateChange) error {
-	url := fmt.Sprintf("%s/objects/states_batch", o.driverhub_host)
-	body := UpdateStateAttributesBatchRequest{
-		Changes: objectsStates,
+// UpdateStateAttributesBatchContext sends several changes in one
+// /objects/states_batch request.
+func (o *objectController) UpdateStateAttributesBatchContext(ctx context.Context, objectsStates []ObjectStateChange) error {
+	resp, queued, err := o.putOrQueue(ctx, "/objects/states_batch", UpdateStateAttributesBatchRequest{Changes: objectsStates})
+	if err != nil || queued {
+		return err
 	}
-	_, err := o.httpClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("X-Auth-Token", o.token).
-		SetBody(body).
-		Put(url)
-	return err
+	if resp.StatusCode() >= 400 {
+		return httpx.NewHubError(resp)
+	}
+	return nil
 }
 
-// NewAction implements ObjectController.
-func (o *objectController) NewAction(action ObjectAction) error {
+// NewActionContext implements ObjectControllerCtx.
+func (o *objectController) NewActionContext(ctx context.Context, action ObjectAction) error {
+	// The runner takes an existing action as success, so a repeat is harmless.
+	ctx = httpx.WithRetry(ctx)
 	url := fmt.Sprintf("%s/objects/actions", o.driverhub_host)
 
-	resp, err := o.httpClient.R().
+	resp, err := o.httpClient.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
+		SetHeader(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey()).
 		SetBody(action).
 		Post(url)
 
@@ -309,103 +433,23 @@ func (o *objectController) NewAction(action ObjectAction) error {
 	}
 
 	if resp.StatusCode() >= 400 {
-		return errors.New(resp.String())
+		return httpx.NewHubError(resp)
 	}
 
 	return nil
 }
 
-type wsMessage struct {
-	EventType string `json:"event_type"`
-	Data      any    `json:"data"`
-	Domain    string `json:"domain"`
-}
-
-// ListenActionRequests implements ObjectController.
-func (o *objectController) ListenActionRequests() error {
-	_logger := logger.Logger()
-
-	// Convert the URL using the utility function
-	url := tools.ConvertToWebSocketURL(o.driverhub_host, "objects/ws")
-
-	// Shared dialer: honours the SDK TLS configuration (custom CA bundle or
-	// verification opt-out). See pkg/httpx.
-	dialer := httpx.WebsocketDialer()
-
-	c, _, err := dialer.Dial(url, http.Header{
-		"X-Auth-Token": []string{o.token},
-	})
-	if err != nil {
-		return err
-	}
-	defer c.Close()
-
-	subscribeHandler := func(data interface{}) {
-		domain := reflect.ValueOf(data).FieldByName("Domain")
-		if domain.IsValid() {
-			writeErr := c.WriteJSON(wsMessage{EventType: "REQUEST_SUBSCRIPTION_TO_DOMAIN", Domain: domain.String()})
-			if writeErr != nil {
-				_logger.Error(writeErr)
-			}
-		}
-	}
-	eventbus.Pubsub.Subscribe("SUBSCRIBE_OBJECTS_COMMANDS_LISTENING", subscribeHandler)
-	defer eventbus.Pubsub.Unsubscribe("SUBSCRIBE_OBJECTS_COMMANDS_LISTENING", subscribeHandler)
-
-	done := make(chan struct{})
-	defer close(done)
-	for {
-		_, message, err := c.ReadMessage()
-		if err != nil {
-			return nil
-		}
-		msg := wsMessage{}
-		json.Unmarshal(message, &msg)
-		if msg.EventType == "REQUEST_ACTION_EXECUTION" {
-			go eventbus.Pubsub.Publish("REQUEST_ACTION_EXECUTION", msg.Data)
-		}
-	}
-
-}
-
-type newObjectRequest struct {
-	ID               string   `json:"id"`
-	Domain           string   `json:"domain"`
-	Name             string   `json:"name"`
-	Tags             []string `json:"tags"`
-	Type             string   `json:"type"`
-	DeviceID         int      `json:"device_id"`
-	Enabled          bool     `json:"enabled"`
-	StatesAvailable  []string `json:"states_available"`
-	EventsAvailable  []string `json:"events_available"`
-	ActionsAvailable []string `json:"actions_available"`
-}
-
-// CreateObject implements ObjectController.
-func (o *objectController) CreateObject(obj RegistrableObject) error {
-	req := newObjectRequest{}
-
-	req.ID = obj.GetMetadata().ObjectID
-	req.Domain = obj.GetMetadata().Domain
-	req.Name = obj.GetMetadata().Name
-	req.Tags = obj.GetMetadata().Tags
-	req.Type = obj.GetMetadata().Type
-	req.Enabled = true
-	req.DeviceID, _ = strconv.Atoi(obj.GetMetadata().DeviceID)
-	req.EventsAvailable = []string{}
-	req.StatesAvailable = []string{}
-	req.ActionsAvailable = []string{}
-
-	req.StatesAvailable = append(req.StatesAvailable, obj.GetAvailableStates()...)
-
-	for _, action := range obj.GetAvailableActions() {
-		req.ActionsAvailable = append(req.ActionsAvailable, action.Action)
-	}
+// CreateObjectContext implements ObjectControllerCtx.
+func (o *objectController) CreateObjectContext(ctx context.Context, obj RegistrableObject) error {
+	// The runner takes an existing object as success, so a repeat is harmless.
+	ctx = httpx.WithRetry(ctx)
+	req := objectRecordOf(obj)
 
 	url := fmt.Sprintf("%s/objects", o.driverhub_host)
-	resp, err := o.httpClient.R().
+	resp, err := o.httpClient.R().SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Auth-Token", o.token).
+		SetHeader(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey()).
 		SetBody(req).
 		Post(url)
 	if err != nil {
@@ -413,7 +457,7 @@ func (o *objectController) CreateObject(obj RegistrableObject) error {
 	}
 
 	if resp.StatusCode() >= 400 {
-		return fmt.Errorf("error creating object: %s", resp.String())
+		return fmt.Errorf("error creating object: %w", httpx.NewHubError(resp))
 	}
 
 	if groupID := obj.GetMetadata().GroupID; groupID != "" {
@@ -424,16 +468,17 @@ func (o *objectController) CreateObject(obj RegistrableObject) error {
 			"parent_id": groupID,
 		}
 		groupURL := fmt.Sprintf("%s/groups", o.driverhub_host)
-		groupResp, groupErr := o.httpClient.R().
+		groupResp, groupErr := o.httpClient.R().SetContext(ctx).
 			SetHeader("Content-Type", "application/json").
 			SetHeader("X-Auth-Token", o.token).
+			SetHeader(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey()).
 			SetBody(groupRelBody).
 			Post(groupURL)
 		if groupErr != nil {
 			return groupErr
 		}
 		if groupResp.StatusCode() >= 400 {
-			return fmt.Errorf("error creating object-group relation: %s", groupResp.String())
+			return fmt.Errorf("error creating object-group relation: %w", httpx.NewHubError(groupResp))
 		}
 	}
 
@@ -441,26 +486,37 @@ func (o *objectController) CreateObject(obj RegistrableObject) error {
 
 }
 
-// SetState implements ObjectController.
-func (o *objectController) SetState(objectId, state string) error {
-	url := fmt.Sprintf("%s/objects/states/%s", o.driverhub_host, objectId)
-	body := map[string]string{"state": state}
-	resp, err := o.httpClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("X-Auth-Token", o.token).
-		SetBody(body).
-		Put(url)
-
+// SetStateContext implements ObjectControllerCtx.
+func (o *objectController) SetStateContext(ctx context.Context, objectId, state string) error {
+	if o.states.redundant(objectId, state, nil) {
+		return nil
+	}
+	applied, err := o.sendState(ctx, objectId, state)
 	if err != nil {
 		return err
 	}
+	o.cacheState(objectId, state, nil, applied)
+	return nil
+}
+
+// sendState sends state, and reports whether the hub applied it: it may only
+// be queued in the outbox, or belong to a disabled object.
+func (o *objectController) sendState(ctx context.Context, objectId, state string) (applied bool, err error) {
+	if b := o.getBatcher(); b != nil {
+		return b.submit(ctx, objectId, &state, nil)
+	}
+	body := map[string]string{"state": state}
+	resp, queued, err := o.putOrQueue(ctx, "/objects/states/"+objectId, body)
+	if err != nil || queued {
+		return false, err
+	}
 	if resp.StatusCode() >= 400 {
-		if strings.Contains(resp.String(), "object is disabled") {
-			return nil
+		if err := httpx.NewHubError(resp); !errors.Is(err, ErrObjectDisabled) {
+			return false, fmt.Errorf("error setting state: %w", err)
 		}
-		return fmt.Errorf("error setting state: %s", resp.String())
+		return false, nil
 	}
-	return nil
+	return true, nil
 }
 
 func NewObjectController(driverhubHost string, driverKey string) ObjectController {
@@ -468,7 +524,12 @@ func NewObjectController(driverhubHost string, driverKey string) ObjectControlle
 		panic("driverhub host cannot be empty")
 	}
 
-	fileData, _ := tools.GetDriverNetsocsDotJsonContent("driver.netsocs.json")
+	// The token is optional: drivers under test usually run without a
+	// driver.netsocs.json.
+	token := ""
+	if fileData, err := tools.GetDriverNetsocsDotJsonContent("driver.netsocs.json"); err == nil {
+		token = fileData.Token
+	}
 
 	if !strings.HasPrefix(driverhubHost, "http") && !strings.HasPrefix(driverhubHost, "https") {
 		driverhubHost = fmt.Sprintf("http://%s", driverhubHost)
@@ -478,6 +539,6 @@ func NewObjectController(driverhubHost string, driverKey string) ObjectControlle
 		driverhub_host: driverhubHost,
 		driver_key:     driverKey,
 		httpClient:     httpx.Resty(),
-		token:          fileData.Token,
+		token:          token,
 	}
 }
@@ -0,0 +1,181 @@
+package objects
+
+import "context"
+
+// The ObjectController methods are thin wrappers over their context-aware
+// variants, using context.Background().
+
+// SetState implements ObjectController.
+func (o *objectController) SetState(objectId, state string) error {
+	return o.SetStateContext(context.Background(), objectId, state)
+}
+
+// UpdateStateAttributes implements ObjectController.
+func (o *objectController) UpdateStateAttributes(objectId string, attributes map[string]string) error {
+	return o.UpdateStateAttributesContext(context.Background(), objectId, attributes)
+}
+
+// ReportActionResult implements ActionResultReporter.
+func (o *objectController) ReportActionResult(executionID string, result ActionResult) error {
+	return o.ReportActionResultContext(context.Background(), executionID, result)
+}
+
+// UpdateResultAttributes implements ObjectController.
+func (o *objectController) UpdateResultAttributes(executionID string, attributes map[string]string) error {
+	return o.UpdateResultAttributesContext(context.Background(), executionID, attributes)
+}
+
+// NewAction implements ObjectController.
+func (o *objectController) NewAction(action ObjectAction) error {
+	return o.NewActionContext(context.Background(), action)
+}
+
+// CreateObject implements ObjectController.
+func (o *objectController) CreateObject(obj RegistrableObject) error {
+	return o.CreateObjectContext(context.Background(), obj)
+}
+
+// ListenActionRequests implements ObjectController.
+func (o *objectController) ListenActionRequests() error {
+	return o.ListenActionRequestsContext(context.Background())
+}
+
+// GetState implements ObjectController.
+func (o *objectController) GetState(objectId string) (StateRecord, error) {
+	return o.GetStateContext(context.Background(), objectId)
+}
+
+// DisabledObject implements ObjectController.
+func (o *objectController) DisabledObject(objectId string) error {
+	return o.DisabledObjectContext(context.Background(), objectId)
+}
+
+// EnabledObject implements ObjectController.
+func (o *objectController) EnabledObject(objectId string) error {
+	return o.EnabledObjectContext(context.Background(), objectId)
+}
+
+// AddEventTypes implements ObjectController.
+func (o *objectController) AddEventTypes(eventTypes []EventType) error {
+	return o.AddEventTypesContext(context.Background(), eventTypes)
+}
+
+// AddEventTypesFallback creates the event types one by one, for hubs without
+// the batch endpoint.
+func (o *objectController) AddEventTypesFallback(eventTypes []EventType) error {
+	return o.addEventTypesFallback(context.Background(), eventTypes)
+}
+
+// Increment implements ObjectController.
+func (o *objectController) Increment(objectId string) error {
+	return o.IncrementContext(context.Background(), objectId)
+}
+
+// Decrement implements ObjectController.
+func (o *objectController) Decrement(objectId string) error {
+	return o.DecrementContext(context.Background(), objectId)
+}
+
+func (o *objectController) UpdateStateAttributesBatch(objectsStates []ObjectStateChange) error {
+	return o.UpdateStateAttributesBatchContext(context.Background(), objectsStates)
+}
+
+// WithContext returns oc as an ObjectControllerCtx. Controllers that do not
+// implement it natively (e.g. test doubles) are adapted: the context is checked
+// before each call, but cannot interrupt a call already in progress.
+func WithContext(oc ObjectController) ObjectControllerCtx {
+	if ctxController, ok := oc.(ObjectControllerCtx); ok {
+		return ctxController
+	}
+	return contextAdapter{oc}
+}
+
+type contextAdapter struct {
+	ObjectController
+}
+
+func (a contextAdapter) SetStateContext(ctx context.Context, objectId string, state string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.SetState(objectId, state)
+}
+
+func (a contextAdapter) UpdateStateAttributesContext(ctx context.Context, objectId string, attributes map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.UpdateStateAttributes(objectId, attributes)
+}
+
+func (a contextAdapter) UpdateResultAttributesContext(ctx context.Context, executionID string, attributes map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.UpdateResultAttributes(executionID, attributes)
+}
+
+func (a contextAdapter) NewActionContext(ctx context.Context, action ObjectAction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.NewAction(action)
+}
+
+func (a contextAdapter) CreateObjectContext(ctx context.Context, obj RegistrableObject) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.CreateObject(obj)
+}
+
+func (a contextAdapter) ListenActionRequestsContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.ListenActionRequests()
+}
+
+func (a contextAdapter) GetStateContext(ctx context.Context, objectId string) (StateRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return StateRecord{}, err
+	}
+	return a.GetState(objectId)
+}
+
+func (a contextAdapter) DisabledObjectContext(ctx context.Context, objectId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.DisabledObject(objectId)
+}
+
+func (a contextAdapter) EnabledObjectContext(ctx context.Context, objectId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.EnabledObject(objectId)
+}
+
+func (a contextAdapter) AddEventTypesContext(ctx context.Context, eventTypes []EventType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.AddEventTypes(eventTypes)
+}
+
+func (a contextAdapter) IncrementContext(ctx context.Context, objectId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Increment(objectId)
+}
+
+func (a contextAdapter) DecrementContext(ctx context.Context, objectId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Decrement(objectId)
+}
+
+var _ ObjectControllerCtx = (*objectController)(nil)
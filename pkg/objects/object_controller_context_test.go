@@ -0,0 +1,55 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetStateContext_deadlineReachesTheRequest(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := controller.SetStateContext(ctx, "obj-1", "on")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWithContext_adaptsPlainControllers(t *testing.T) {
+	mock := newMockMicController("http://localhost:9999")
+	ctrl := WithContext(mock)
+
+	require.NoError(t, ctrl.SetStateContext(context.Background(), "obj-1", "on"))
+	assert.Equal(t, "on", mock.getState("obj-1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, ctrl.SetStateContext(ctx, "obj-1", "off"), context.Canceled)
+	assert.Equal(t, "on", mock.getState("obj-1"), "a cancelled call must not reach the controller")
+}
+
+func TestWithContext_returnsNativeImplementation(t *testing.T) {
+	controller := &objectController{driverhub_host: "http://localhost", httpClient: resty.New()}
+	assert.Same(t, controller, WithContext(controller))
+}
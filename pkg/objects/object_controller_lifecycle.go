@@ -0,0 +1,212 @@
+package objects
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/goccy/go-json"
+)
+
+// ObjectLifecycleController is implemented by controllers that can list,
+// update and delete the objects of the DriverHub, on top of creating them.
+// The controller returned by NewObjectController implements it; the runner
+// relies on it for UnregisterObject, UpdateObject and Reconcile. Its methods
+// return ErrLifecycleUnsupported for a hub without the object listing.
+type ObjectLifecycleController interface {
+	// ListObjectsContext returns the objects the hub holds for a device.
+	ListObjectsContext(ctx context.Context, deviceID string) ([]ObjectRecord, error)
+	// UpdateObjectContext replaces the name, type, tags, states and actions
+	// of an existing object.
+	UpdateObjectContext(ctx context.Context, obj RegistrableObject) error
+	// DeleteObjectContext removes an object from the hub.
+	DeleteObjectContext(ctx context.Context, objectID string) error
+}
+
+// ObjectRecord is an object as the DriverHub stores it.
+type ObjectRecord struct {
+	ID               string   `json:"id"`
+	Domain           string   `json:"domain"`
+	Name             string   `json:"name"`
+	Tags             []string `json:"tags"`
+	Type             string   `json:"type"`
+	DeviceID         int      `json:"device_id"`
+	Enabled          bool     `json:"enabled"`
+	StatesAvailable  []string `json:"states_available"`
+	EventsAvailable  []string `json:"events_available"`
+	ActionsAvailable []string `json:"actions_available"`
+}
+
+type PaginatedObjectRecord struct {
+	Items    []ObjectRecord `json:"items"`
+	Metadata struct {
+		TotalItems int `json:"total_items"`
+		Limit      int `json:"limit"`
+		Offset     int `json:"offset"`
+	} `json:"_metadata"`
+}
+
+// objectRecordOf returns the record the hub should hold for obj.
+func objectRecordOf(obj RegistrableObject) ObjectRecord {
+	metadata := obj.GetMetadata()
+	record := ObjectRecord{
+		ID:               metadata.ObjectID,
+		Domain:           metadata.Domain,
+		Name:             metadata.Name,
+		Tags:             metadata.Tags,
+		Type:             metadata.Type,
+		Enabled:          true,
+		StatesAvailable:  []string{},
+		EventsAvailable:  []string{},
+		ActionsAvailable: []string{},
+	}
+	record.DeviceID, _ = strconv.Atoi(metadata.DeviceID)
+	record.StatesAvailable = append(record.StatesAvailable, obj.GetAvailableStates()...)
+	for _, action := range obj.GetAvailableActions() {
+		record.ActionsAvailable = append(record.ActionsAvailable, action.Action)
+	}
+	return record
+}
+
+// matches reports whether the hub record already describes obj, ignoring
+// the order of tags, states and actions.
+func (r ObjectRecord) matches(obj RegistrableObject) bool {
+	want := objectRecordOf(obj)
+	return r.Domain == want.Domain && r.Name == want.Name && r.Type == want.Type &&
+		sameSet(r.Tags, want.Tags) && sameSet(r.StatesAvailable, want.StatesAvailable) &&
+		sameSet(r.ActionsAvailable, want.ActionsAvailable)
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// listObjectsPageSize is the page size of ListObjectsContext.
+const listObjectsPageSize = 100
+
+// listObjectsPage reads a page of the object listing. A hub without the
+// listing answers 404, 405 or 501, or with something else than a page; that
+// is reported as ErrLifecycleUnsupported. Updating and deleting objects is
+// only attempted once the hub served a page.
+func (o *objectController) listObjectsPage(ctx context.Context, query url.Values) (PaginatedObjectRecord, error) {
+	var page PaginatedObjectRecord
+	resp, err := o.httpClient.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Auth-Token", o.token).
+		Get(fmt.Sprintf("%s/objects?%s", o.driverhub_host, query.Encode()))
+	if err != nil {
+		return page, err
+	}
+	switch code := resp.StatusCode(); {
+	case code == http.StatusNotFound || code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented:
+		return page, fmt.Errorf("%w: %w", ErrLifecycleUnsupported, httpx.NewHubError(resp))
+	case code >= 400:
+		return page, httpx.NewHubError(resp)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Body(), &fields); err != nil || fields["items"] == nil {
+		return page, fmt.Errorf("%w: the object listing is not a page of objects", ErrLifecycleUnsupported)
+	}
+	if err := json.Unmarshal(resp.Body(), &page); err != nil {
+		return page, err
+	}
+	o.lifecycleSupported.Store(true)
+	return page, nil
+}
+
+// checkLifecycle returns ErrLifecycleUnsupported unless the hub serves the
+// object listing, reading a page of it the first time.
+func (o *objectController) checkLifecycle(ctx context.Context) error {
+	if o.lifecycleSupported.Load() {
+		return nil
+	}
+	_, err := o.listObjectsPage(ctx, url.Values{"limit": {"1"}, "offset": {"0"}})
+	return err
+}
+
+// ListObjectsContext implements ObjectLifecycleController.
+func (o *objectController) ListObjectsContext(ctx context.Context, deviceID string) ([]ObjectRecord, error) {
+	records := []ObjectRecord{}
+	for offset := 0; ; offset += listObjectsPageSize {
+		query := url.Values{}
+		query.Set("device_id", deviceID)
+		query.Set("limit", strconv.Itoa(listObjectsPageSize))
+		query.Set("offset", strconv.Itoa(offset))
+		page, err := o.listObjectsPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page.Items...)
+		if len(page.Items) < listObjectsPageSize || len(records) >= page.Metadata.TotalItems {
+			return records, nil
+		}
+	}
+}
+
+type updateObjectRequest struct {
+	Domain           string   `json:"domain"`
+	Name             string   `json:"name"`
+	Tags             []string `json:"tags"`
+	Type             string   `json:"type"`
+	StatesAvailable  []string `json:"states_available"`
+	ActionsAvailable []string `json:"actions_available"`
+}
+
+// UpdateObjectContext implements ObjectLifecycleController. The enabled flag
+// of the object is left as it is.
+func (o *objectController) UpdateObjectContext(ctx context.Context, obj RegistrableObject) error {
+	if err := o.checkLifecycle(ctx); err != nil {
+		return err
+	}
+	record := objectRecordOf(obj)
+	url := fmt.Sprintf("%s/objects/%s", o.driverhub_host, record.ID)
+	resp, err := o.httpClient.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Auth-Token", o.token).
+		SetBody(updateObjectRequest{
+			Domain:           record.Domain,
+			Name:             record.Name,
+			Tags:             record.Tags,
+			Type:             record.Type,
+			StatesAvailable:  record.StatesAvailable,
+			ActionsAvailable: record.ActionsAvailable,
+		}).
+		Put(url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("error updating object: %w", httpx.NewHubError(resp))
+	}
+	return nil
+}
+
+// DeleteObjectContext implements ObjectLifecycleController.
+func (o *objectController) DeleteObjectContext(ctx context.Context, objectID string) error {
+	if err := o.checkLifecycle(ctx); err != nil {
+		return err
+	}
+	o.states.forget(objectID)
+	url := fmt.Sprintf("%s/objects/%s", o.driverhub_host, objectID)
+	resp, err := o.httpClient.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Auth-Token", o.token).
+		Delete(url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("error deleting object: %w", httpx.NewHubError(resp))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package objects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A hub without the object listing is never sent the update and delete
+// requests, and Reconcile falls back to the objects registered locally.
+func TestObjectRunner_lifecycleUnsupportedByHub(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.Method == http.MethodGet && r.URL.Path == "/objects" {
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	runner := NewObjectRunner(controller).(*objectRunner)
+	t.Cleanup(func() { runner.Shutdown(context.Background()) })
+
+	newSwitch := func(id string) RegistrableObject {
+		return NewSwitchObject(NewSwitchObjectParams{Metadata: ObjectMetadata{ObjectID: id, Domain: "lifecycle", DeviceID: "4"}})
+	}
+	result, err := runner.Reconcile("4", []RegistrableObject{newSwitch("lifecycle.1"), newSwitch("lifecycle.2")})
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileResult{Registered: []string{"lifecycle.1", "lifecycle.2"}}, result)
+
+	result, err = runner.Reconcile("4", []RegistrableObject{newSwitch("lifecycle.1")})
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileResult{Registered: []string{"lifecycle.1"}, Disabled: []string{"lifecycle.2"}}, result)
+
+	assert.ErrorIs(t, runner.UpdateObject(newSwitch("lifecycle.1")), ErrLifecycleUnsupported)
+	require.NoError(t, runner.UnregisterObject("lifecycle.1"), "the object is disabled instead")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, requests, "PUT /objects/lifecycle.1/disabled")
+	for _, request := range requests {
+		assert.NotEqual(t, "PUT /objects/lifecycle.1", request)
+		assert.NotContains(t, request, "DELETE")
+	}
+}
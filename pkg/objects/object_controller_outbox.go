@@ -0,0 +1,86 @@
+package objects
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
+	"github.com/go-resty/resty/v2"
+)
+
+// OutboxController is implemented by controllers that can buffer state
+// changes in an outbox while the DriverHub is unreachable. The controller
+// returned by NewObjectController implements it; NetsocsDriverClient.EnableOutbox
+// wires it up.
+type OutboxController interface {
+	// SetOutbox makes SetState and UpdateStateAttributes queue their writes
+	// in ob, instead of failing, while the hub is unreachable.
+	SetOutbox(ob *outbox.Outbox)
+	// ReplayOutboxEntry is the outbox.Sender delivering queued writes.
+	ReplayOutboxEntry(ctx context.Context, entry outbox.Entry) error
+}
+
+// SetOutbox implements OutboxController.
+func (o *objectController) SetOutbox(ob *outbox.Outbox) {
+	o.outboxMu.Lock()
+	defer o.outboxMu.Unlock()
+	o.outbox = ob
+}
+
+func (o *objectController) getOutbox() *outbox.Outbox {
+	o.outboxMu.Lock()
+	defer o.outboxMu.Unlock()
+	return o.outbox
+}
+
+// putOrQueue PUTs body to path on the hub. With an outbox enabled, the write
+// is queued instead when the hub is unreachable, and also while earlier writes
+// are still queued, so the hub receives them in order. queued reports whether
+// the write was queued; resp is nil then.
+func (o *objectController) putOrQueue(ctx context.Context, path string, body any) (resp *resty.Response, queued bool, err error) {
+	ob := o.getOutbox()
+	if ob != nil && ob.Len() > 0 {
+		return nil, true, ob.Append(http.MethodPut, path, body)
+	}
+	resp, err = o.httpClient.R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Auth-Token", o.token).
+		SetBody(body).
+		Put(o.driverhub_host + path)
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode()
+	}
+	if ob == nil || !httpx.Unreachable(statusCode, err) {
+		return resp, false, err
+	}
+	logger.Logger().Warnw("driverhub unreachable, write queued in the outbox", "path", path, "error", err, "status", statusCode)
+	return nil, true, ob.Append(http.MethodPut, path, body)
+}
+
+// ReplayOutboxEntry implements OutboxController. Entries the hub rejects with
+// a client error are dropped, since sending them again cannot succeed. The
+// outbox paces its own retries, so each replay is a single attempt.
+func (o *objectController) ReplayOutboxEntry(ctx context.Context, entry outbox.Entry) error {
+	request := o.httpClient.R().SetContext(httpx.WithoutRetry(ctx))
+	if entry.IdempotencyKey != "" {
+		request.SetHeader(httpx.IdempotencyKeyHeader, entry.IdempotencyKey)
+	}
+	resp, err := request.
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Auth-Token", o.token).
+		SetBody([]byte(entry.Body)).
+		Execute(entry.Method, o.driverhub_host+entry.Path)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 500 {
+		return httpx.NewHubError(resp)
+	}
+	if resp.StatusCode() >= 400 {
+		logger.Logger().Warnw("driverhub rejected a replayed write, dropping it", "path", entry.Path, "status", resp.StatusCode(), "response", resp.String())
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package objects
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectController_queuesWritesWhileHubIsDown(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r.URL.Path+" "+string(body))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	ob, err := outbox.Open(outbox.Options{})
+	require.NoError(t, err)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.SetOutbox(ob)
+
+	require.NoError(t, controller.SetState("door-1", "open"))
+	require.NoError(t, controller.UpdateStateAttributes("door-1", map[string]string{"battery": "80"}))
+	assert.Equal(t, 2, ob.Len())
+
+	// Queued writes keep the hub from seeing a newer write before them.
+	down.Store(false)
+	require.NoError(t, controller.SetState("door-1", "closed"))
+	assert.Equal(t, 3, ob.Len())
+	assert.Empty(t, received)
+
+	n, err := ob.Replay(context.Background(), controller.ReplayOutboxEntry)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{
+		`/objects/states/door-1 {"state":"open"}`,
+		`/objects/states/door-1 {"state_additional_properties":{"battery":"80"}}`,
+		`/objects/states/door-1 {"state":"closed"}`,
+	}, received)
+}
+
+func TestObjectController_withoutOutboxReturnsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	assert.Error(t, controller.SetState("door-1", "open"))
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -374,3 +377,64 @@ func TestAddEventTypesFallback(t *testing.T) {
 		})
 	}
 }
+
+func TestObjectController_hubErrors(t *testing.T) {
+	newController := func(status int, body string) *objectController {
+		client := resty.New()
+		client.SetTransport(&mockTransport{statusCode: status, body: []byte(body)})
+		return &objectController{driverhub_host: "http://test.com", httpClient: client}
+	}
+
+	err := newController(400, `{"code":"ERR_ITEM_ALREADY_EXIST","message":"item exists"}`).CreateObject(NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "relay.1", Domain: "switch"},
+	}))
+	assert.ErrorIs(t, err, ErrObjectAlreadyExists)
+	var hubErr *httpx.HubError
+	if assert.ErrorAs(t, err, &hubErr) {
+		assert.Equal(t, 400, hubErr.StatusCode)
+		assert.Equal(t, "ERR_ITEM_ALREADY_EXIST", hubErr.Code)
+		assert.Equal(t, "/objects", hubErr.Path)
+	}
+
+	// A disabled object is not an error for SetState.
+	assert.NoError(t, newController(400, "object is disabled").SetState("relay.1", "on"))
+
+	err = newController(404, "object not found").SetState("relay.1", "on")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	assert.NotErrorIs(t, err, ErrObjectDisabled)
+}
+
+func TestObjectController_countersAreNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	controller := &objectController{driverhub_host: srv.URL, httpClient: httpx.Resty()}
+
+	assert.Error(t, controller.Increment("counter.1"))
+	assert.Error(t, controller.Decrement("counter.1"))
+	assert.Equal(t, int32(2), calls.Load(), "a retry could count twice")
+}
+
+func TestObjectController_registrationCallsSurviveA502(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"successful":[],"failed":[]}`))
+	}))
+	defer srv.Close()
+	controller := &objectController{driverhub_host: srv.URL, httpClient: httpx.Resty()}
+
+	assert.NoError(t, controller.CreateObject(NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "relay.1", Domain: "switch"},
+	})))
+	assert.NoError(t, controller.NewAction(ObjectAction{Domain: "switch", Action: "turn_on"}))
+	assert.NoError(t, controller.AddEventTypes([]EventType{{Domain: "switch", EventType: "turned_on"}}))
+	assert.Equal(t, int32(6), calls.Load())
+}
@@ -0,0 +1,314 @@
+package objects
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+)
+
+// ReconcileOptions tunes Reconcile.
+type ReconcileOptions struct {
+	// DeleteStale deletes the hub objects of the device that are not desired
+	// any more. By default they are disabled, which keeps their history and
+	// can be undone by a later Reconcile.
+	DeleteStale bool
+}
+
+// ReconcileResult lists, by object ID, what Reconcile did.
+type ReconcileResult struct {
+	Created   []string
+	Updated   []string
+	Enabled   []string
+	Disabled  []string
+	Deleted   []string
+	Unchanged []string
+	// Registered are the objects created or enabled without comparing them
+	// with the hub, for a hub that cannot list them.
+	Registered []string
+}
+
+// track makes object the registered instance for its ID, replacing any
+// previous one, and returns the instance it replaced.
+func (o *objectRunner) track(object RegistrableObject) (previous RegistrableObject) {
+	o.objectsMu.Lock()
+	defer o.objectsMu.Unlock()
+	metadata := object.GetMetadata()
+	previous = o.untrackLocked(metadata.ObjectID)
+	existing, _ := o.objectsMap.Load(metadata.Domain)
+	list, _ := existing.([]RegistrableObject)
+	o.objectsMap.Store(metadata.Domain, append(list[:len(list):len(list)], object))
+	return previous
+}
+
+// untrack forgets the registered instance of objectID and returns it, or nil.
+func (o *objectRunner) untrack(objectID string) RegistrableObject {
+	o.objectsMu.Lock()
+	defer o.objectsMu.Unlock()
+	return o.untrackLocked(objectID)
+}
+
+func (o *objectRunner) untrackLocked(objectID string) (removed RegistrableObject) {
+	o.objectsMap.Range(func(key, value any) bool {
+		list, _ := value.([]RegistrableObject)
+		for i, obj := range list {
+			if obj.GetMetadata().ObjectID != objectID {
+				continue
+			}
+			removed = obj
+			// Readers may hold the current slice: store a copy.
+			rest := append(append([]RegistrableObject{}, list[:i]...), list[i+1:]...)
+			if len(rest) == 0 {
+				o.objectsMap.Delete(key)
+			} else {
+				o.objectsMap.Store(key, rest)
+			}
+			return false
+		}
+		return true
+	})
+	return removed
+}
+
+// registered returns the registered objects of a device.
+func (o *objectRunner) registered(deviceID string) []RegistrableObject {
+	var found []RegistrableObject
+	o.objectsMap.Range(func(_, value any) bool {
+		list, _ := value.([]RegistrableObject)
+		for _, obj := range list {
+			if obj.GetMetadata().DeviceID == deviceID {
+				found = append(found, obj)
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// DeviceObjects implements ManagedObjectRunner.
+func (o *objectRunner) DeviceObjects(deviceID string) []RegistrableObject {
+	found := o.registered(deviceID)
+	sort.Slice(found, func(i, j int) bool { return found[i].GetMetadata().ObjectID < found[j].GetMetadata().ObjectID })
+	return found
+}
+
+// sameObject reports whether a and b are the same instance. Objects of
+// non-comparable types are never the same.
+func sameObject(a, b RegistrableObject) bool {
+	return a != nil && reflect.TypeOf(a).Comparable() && a == b
+}
+
+// closeObject closes object if it holds sessions open.
+func closeObject(object RegistrableObject) {
+	closer, ok := object.(io.Closer)
+	if !ok {
+		return
+	}
+	var err error
+	if panicErr := recovery.Guard(recovery.BoundaryClose, func() { err = closer.Close() }); panicErr != nil {
+		err = panicErr
+	}
+	if err != nil {
+		logger.Logger().Warnw("failed to close an unregistered object", "object_id", object.GetMetadata().ObjectID, "error", err)
+	}
+}
+
+func (o *objectRunner) lifecycleController() (ObjectLifecycleController, error) {
+	lc, ok := o.controller.(ObjectLifecycleController)
+	if !ok {
+		return nil, fmt.Errorf("the objects controller cannot list, update or delete objects: %w", ErrMethodNotImplemented)
+	}
+	return lc, nil
+}
+
+// UnregisterObject implements ManagedObjectRunner.
+func (o *objectRunner) UnregisterObject(objectID string) error {
+	if object := o.untrack(objectID); object != nil {
+		closeObject(object)
+	}
+	lc, ok := o.controller.(ObjectLifecycleController)
+	if !ok {
+		// Disabling is the closest the controller can get.
+		return o.controller.DisabledObject(objectID)
+	}
+	err := lc.DeleteObjectContext(o.ctx, objectID)
+	switch {
+	case errors.Is(err, ErrLifecycleUnsupported):
+		return o.controller.DisabledObject(objectID)
+	case err != nil && !errors.Is(err, ErrObjectNotFound):
+		return err
+	}
+	return nil
+}
+
+// UpdateObject implements ManagedObjectRunner.
+func (o *objectRunner) UpdateObject(object RegistrableObject) error {
+	lc, err := o.lifecycleController()
+	if err != nil {
+		return err
+	}
+	if err := lc.UpdateObjectContext(o.ctx, object); err != nil {
+		return err
+	}
+	for _, action := range object.GetAvailableActions() {
+		if err := o.controller.NewAction(action); err != nil && !errors.Is(err, ErrObjectAlreadyExists) {
+			return err
+		}
+	}
+	return o.adopt(object)
+}
+
+// adopt registers object locally, for an object the hub already holds.
+func (o *objectRunner) adopt(object RegistrableObject) error {
+	previous := o.track(object)
+	if sameObject(previous, object) {
+		return nil
+	}
+	if previous != nil {
+		closeObject(previous)
+	}
+	o.subscribeDomain(object.GetMetadata().Domain)
+	return setupObject(object, o.controller)
+}
+
+// Reconcile implements ManagedObjectRunner.
+func (o *objectRunner) Reconcile(deviceID string, desired []RegistrableObject, opts ...ReconcileOptions) (ReconcileResult, error) {
+	var result ReconcileResult
+	var options ReconcileOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	lc, err := o.lifecycleController()
+	if err != nil {
+		return result, err
+	}
+	records, err := lc.ListObjectsContext(o.ctx, deviceID)
+	if errors.Is(err, ErrLifecycleUnsupported) {
+		logger.Logger().Warnw("the driverhub cannot list objects, reconciling the registered objects only", "device_id", deviceID, "error", err)
+		return o.reconcileRegistered(deviceID, desired)
+	}
+	if err != nil {
+		return result, err
+	}
+	onHub := make(map[string]ObjectRecord, len(records))
+	for _, record := range records {
+		onHub[record.ID] = record
+	}
+
+	var errs []error
+	wanted := make(map[string]bool, len(desired))
+	for _, object := range desired {
+		metadata := object.GetMetadata()
+		if metadata.DeviceID != deviceID {
+			errs = append(errs, fmt.Errorf("%s: belongs to device %q, not %q", metadata.ObjectID, metadata.DeviceID, deviceID))
+			continue
+		}
+		wanted[metadata.ObjectID] = true
+		record, exists := onHub[metadata.ObjectID]
+		var err error
+		switch {
+		case !exists:
+			if err = o.RegisterObject(object); err == nil {
+				result.Created = append(result.Created, metadata.ObjectID)
+			}
+		case !record.matches(object):
+			if err = o.UpdateObject(object); err == nil {
+				result.Updated = append(result.Updated, metadata.ObjectID)
+			}
+		default:
+			if err = o.adopt(object); err == nil {
+				result.Unchanged = append(result.Unchanged, metadata.ObjectID)
+			}
+		}
+		if err == nil && exists && !record.Enabled {
+			if err = o.controller.EnabledObject(metadata.ObjectID); err == nil {
+				result.Enabled = append(result.Enabled, metadata.ObjectID)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", metadata.ObjectID, err))
+		}
+	}
+
+	for _, record := range records {
+		if wanted[record.ID] {
+			continue
+		}
+		var err error
+		switch {
+		case options.DeleteStale:
+			if err = o.UnregisterObject(record.ID); err == nil {
+				result.Deleted = append(result.Deleted, record.ID)
+			}
+		case record.Enabled:
+			if object := o.untrack(record.ID); object != nil {
+				closeObject(object)
+			}
+			if err = o.controller.DisabledObject(record.ID); err == nil {
+				result.Disabled = append(result.Disabled, record.ID)
+			}
+		default:
+			if object := o.untrack(record.ID); object != nil {
+				closeObject(object)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", record.ID, err))
+		}
+	}
+
+	// Objects registered locally that the hub no longer knows.
+	for _, object := range o.registered(deviceID) {
+		id := object.GetMetadata().ObjectID
+		if _, ok := onHub[id]; !ok && !wanted[id] {
+			o.untrack(id)
+			closeObject(object)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// reconcileRegistered is Reconcile for a hub that cannot list objects: the
+// desired objects are registered and enabled, and the objects registered
+// locally for the device that are no longer desired are disabled. Objects
+// the hub holds but this process never registered are left alone.
+func (o *objectRunner) reconcileRegistered(deviceID string, desired []RegistrableObject) (ReconcileResult, error) {
+	var result ReconcileResult
+	var errs []error
+	wanted := make(map[string]bool, len(desired))
+	for _, object := range desired {
+		metadata := object.GetMetadata()
+		if metadata.DeviceID != deviceID {
+			errs = append(errs, fmt.Errorf("%s: belongs to device %q, not %q", metadata.ObjectID, metadata.DeviceID, deviceID))
+			continue
+		}
+		wanted[metadata.ObjectID] = true
+		err := o.RegisterObject(object)
+		if err == nil {
+			err = o.controller.EnabledObject(metadata.ObjectID)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", metadata.ObjectID, err))
+			continue
+		}
+		result.Registered = append(result.Registered, metadata.ObjectID)
+	}
+	for _, object := range o.registered(deviceID) {
+		id := object.GetMetadata().ObjectID
+		if wanted[id] {
+			continue
+		}
+		o.untrack(id)
+		closeObject(object)
+		if err := o.controller.DisabledObject(id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		result.Disabled = append(result.Disabled, id)
+	}
+	return result, errors.Join(errs...)
+}
@@ -1,19 +1,62 @@
 package objects
 
 import (
+	"context"
 	"errors"
-	"strings"
+	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Netsocs-Team/driver.sdk_go/internal/eventbus"
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
 	"github.com/goccy/go-json"
-	"go.uber.org/zap"
 )
 
 type objectRunner struct {
 	controller ObjectController
 	// objectsMap map[string][]RegistrableObject
 	objectsMap sync.Map
+	// objectsMu serialises the changes of objectsMap, see track and untrack.
+	objectsMu sync.Mutex
+
+	// ctx is the parent of every action execution; cancel aborts them all.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+
+	gatesMu     sync.Mutex
+	objectGates map[string]*executionGate
+	domainGates map[string]*executionGate
+
+	timeoutsMu     sync.Mutex
+	actionTimeouts map[string]time.Duration
+}
+
+// The event bus unsubscribes handlers by code pointer, so the closures of two
+// runners cannot be told apart. A single subscription fans the requests out to
+// the live runners instead, which lets several clients share a process.
+var (
+	runnersMu            sync.Mutex
+	runners              = map[*objectRunner]struct{}{}
+	subscribeRunnersOnce sync.Once
+)
+
+func dispatchActionRequest(data interface{}) {
+	runnersMu.Lock()
+	live := make([]*objectRunner, 0, len(runners))
+	for runner := range runners {
+		live = append(live, runner)
+	}
+	runnersMu.Unlock()
+	for _, runner := range live {
+		recovery.Guard(recovery.BoundaryEventBus, func() { runner.handleActionRequest(data) })
+	}
 }
 
 // GetController implements ObjectRunner.
@@ -31,112 +74,423 @@ type requestActionExecutionEventData struct {
 
 // SubscribeToActionsRequest implements objects.ObjectRunner.
 func (o *objectRunner) listenActions() {
-	eventbus.Pubsub.Subscribe("REQUEST_ACTION_EXECUTION", func(data interface{}) {
-		logger, err := zap.NewProduction()
-		if err != nil {
-			panic(err)
-		}
-		defer logger.Sync() // flushes buffer, if any
-		sugar := logger.Sugar()
-
-		req := requestActionExecutionEventData{}
-		jsoncontent, _ := json.Marshal(data)
-		err = json.Unmarshal(jsoncontent, &req)
-		if err != nil {
-			sugar.Error("failed to unmarshal request action execution data", zap.Error(err))
-			return
-		}
-		payloadBytes, err := json.Marshal(req.Payload)
-		if err != nil {
-			sugar.Error("failed to marshal payload", zap.Error(err))
-			return
-		}
+	subscribeRunnersOnce.Do(func() {
+		eventbus.Pubsub.Subscribe("REQUEST_ACTION_EXECUTION", dispatchActionRequest)
+	})
+	runnersMu.Lock()
+	runners[o] = struct{}{}
+	runnersMu.Unlock()
+}
 
-		objectsRaw, ok := o.objectsMap.Load(req.Domain)
-		if !ok {
-			sugar.Info("no objects found for domain", zap.String("domain", req.Domain))
-			return
-		}
+func (o *objectRunner) handleActionRequest(data interface{}) {
+	req := requestActionExecutionEventData{}
+	jsoncontent, _ := json.Marshal(data)
+	if err := json.Unmarshal(jsoncontent, &req); err != nil {
+		logger.Logger().Errorw("failed to unmarshal request action execution data", "error", err)
+		return
+	}
+	log := logger.Logger().With("action_execution_id", req.ActionExecutionID, "domain", req.Domain, "action", req.Action)
+	payloadBytes, err := json.Marshal(req.Payload)
+	if err != nil {
+		log.Errorw("failed to marshal payload", "error", err)
+		return
+	}
 
-		objects, ok := objectsRaw.([]RegistrableObject)
-		if !ok {
-			sugar.Info("invalid objects type", zap.String("domain", req.Domain))
-			return
-		}
+	objectsRaw, ok := o.objectsMap.Load(req.Domain)
+	if !ok {
+		log.Info("no objects found for domain")
+		return
+	}
 
-		if len(objects) == 0 {
-			sugar.Info("no objects found for domain", zap.String("domain", req.Domain))
-			return
-		}
+	objects, ok := objectsRaw.([]RegistrableObject)
+	if !ok {
+		log.Info("invalid objects type")
+		return
+	}
 
-		sugar.Info("running action", zap.String("action", req.Action), zap.String("domain", req.Domain), zap.Strings("object_id", req.ObjectID), zap.String("payload", string(payloadBytes)))
+	if len(objects) == 0 {
+		log.Info("no objects found for domain")
+		return
+	}
 
-		RunActionRoutine := func(obj RegistrableObject) {
-			resp, err := obj.RunAction(req.ActionExecutionID, req.Action, payloadBytes)
+	// One span per execution request, with a child span per object.
+	requestCtx, requestSpan := tracing.Start(o.ctx, "action.execution")
+	requestSpan.SetAttribute("action_execution_id", req.ActionExecutionID)
+	requestSpan.SetAttribute("domain", req.Domain)
+	requestSpan.SetAttribute("action", req.Action)
+	if requestSpan != nil {
+		log = log.With("trace_id", requestSpan.SpanContext().TraceID)
+	}
 
-			if err != nil {
-				sugar.Info("action execution error", zap.Error(err))
-				o.GetController().UpdateResultAttributes(req.ActionExecutionID, map[string]string{"error": err.Error()})
-			} else {
-				sugar.Info("action executed", zap.Any("response", resp))
-				o.GetController().UpdateResultAttributes(req.ActionExecutionID, resp)
+	log.Infow("running action", "object_ids", req.ObjectID, "payload", string(payloadBytes))
+
+	timeout := o.executionTimeout(req.Action, req.Payload)
+	var running atomic.Int32
+
+	RunActionRoutine := func(obj RegistrableObject) {
+		defer o.inflight.Done()
+		defer func() {
+			if running.Add(-1) == 0 {
+				requestSpan.End()
 			}
+		}()
+		start := time.Now()
+		log := log.With("object_id", obj.GetMetadata().ObjectID)
+		spanCtx, span := tracing.Start(requestCtx, "action.run")
+		defer span.End()
+		span.SetAttribute("object_id", obj.GetMetadata().ObjectID)
+		ctx, cancel := context.WithCancel(spanCtx)
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(spanCtx, timeout)
 		}
+		defer cancel()
 
-		if len(req.ObjectID) > 0 {
-			for _, obj := range objects {
-				for _, objID := range req.ObjectID {
-					if obj.GetMetadata().ObjectID == objID {
-						go RunActionRoutine(obj)
+		ctx = logger.WithLogger(ctx, log)
+		ctx, state := withExecutionState(ctx, req.ActionExecutionID, reportsStructuredResults(o.GetController()))
+		var queueAttributes map[string]any
+		var reportOnce sync.Once
+		report := func(resp map[string]string, err error) {
+			reportOnce.Do(func() {
+				var result ActionResult
+				if err != nil {
+					log.Infow("action execution error", "error", err)
+					result = errorResult(req.Action, err, timeout)
+				} else {
+					log.Infow("action executed", "response", resp)
+					result = ActionResult{Status: ActionStatusSucceeded, Data: state.resultData()}
+					if result.Data == nil {
+						result.Data = stringsToData(resp)
 					}
 				}
+				span.SetAttribute("status", string(result.Status))
+				if err != nil {
+					span.RecordError(err)
+					requestSpan.RecordError(err)
+				} else {
+					span.SetStatus(tracing.StatusOK, "")
+				}
+				actionExecutions.Inc(req.Domain, req.Action, string(result.Status))
+				actionDuration.ObserveSince(start, req.Domain, req.Action)
+				if queueAttributes != nil {
+					for k, v := range result.Data {
+						queueAttributes[k] = v
+					}
+					result.Data = queueAttributes
+				}
+				// The execution ctx may be done already; the result must still
+				// be sent, within the span.
+				if err := reportActionResult(context.WithoutCancel(ctx), o.GetController(), req.ActionExecutionID, result); err != nil {
+					log.Errorw("failed to report action result", "error", err)
+				}
+			})
+		}
+
+		if gate := o.gateFor(obj.GetMetadata()); gate != nil {
+			queueDepth := 0
+			release, waited, err := gate.acquire(ctx, func(depth int) {
+				queueDepth = depth
+				reportActionResult(ctx, o.GetController(), req.ActionExecutionID, ActionResult{
+					Status: ActionStatusQueued,
+					Data:   map[string]any{"queue_depth": depth},
+				})
+			})
+			if err != nil {
+				log.Infow("action execution rejected", "error", err)
+				report(nil, err)
+				return
 			}
-		} else {
-			for _, obj := range objects {
-				go RunActionRoutine(obj)
+			defer release()
+			if queueDepth > 0 {
+				queueAttributes = map[string]any{
+					"queue_depth":   queueDepth,
+					"queue_wait_ms": waited.Milliseconds(),
+				}
+			}
+		}
+
+		// Report a timeout or cancellation as soon as it happens, even if the
+		// action ignores ctx; its late result is then dropped.
+		finished := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				report(nil, ctx.Err())
+			case <-finished:
+			}
+		}()
+
+		resp, err := runObjectAction(ctx, obj, req.ActionExecutionID, req.Action, payloadBytes)
+		close(finished)
+		report(resp, err)
+	}
+
+	targets := objects
+	if len(req.ObjectID) > 0 {
+		targets = nil
+		for _, obj := range objects {
+			for _, objID := range req.ObjectID {
+				if obj.GetMetadata().ObjectID == objID {
+					targets = append(targets, obj)
+				}
+			}
+		}
+	}
+
+	// Count the executions before starting them so Shutdown cannot miss
+	// one that is about to start.
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		log.Info("runner is shutting down, action ignored")
+		requestSpan.SetStatus(tracing.StatusError, "runner is shutting down")
+		requestSpan.End()
+		return
+	}
+	o.inflight.Add(len(targets))
+	o.mu.Unlock()
+
+	if len(targets) == 0 {
+		requestSpan.End()
+		return
+	}
+	running.Store(int32(len(targets)))
+
+	for _, obj := range targets {
+		go RunActionRoutine(obj)
+	}
+}
+
+// SetActionTimeout implements ManagedObjectRunner.
+func (o *objectRunner) SetActionTimeout(action string, timeout time.Duration) {
+	o.timeoutsMu.Lock()
+	defer o.timeoutsMu.Unlock()
+	if o.actionTimeouts == nil {
+		o.actionTimeouts = make(map[string]time.Duration)
+	}
+	if timeout <= 0 {
+		delete(o.actionTimeouts, action)
+		return
+	}
+	o.actionTimeouts[action] = timeout
+}
+
+// executionTimeout returns the deadline of an execution: the payload
+// "timeout" (seconds) when present, else the action default. 0 means none.
+func (o *objectRunner) executionTimeout(action string, payload map[string]interface{}) time.Duration {
+	if timeout := payloadTimeout(payload); timeout > 0 {
+		return timeout
+	}
+	o.timeoutsMu.Lock()
+	defer o.timeoutsMu.Unlock()
+	return o.actionTimeouts[action]
+}
+
+// SetObjectExecutionPolicy implements ManagedObjectRunner.
+func (o *objectRunner) SetObjectExecutionPolicy(objectID string, policy ExecutionPolicy) error {
+	return o.setExecutionPolicy(&o.objectGates, objectID, policy)
+}
+
+// SetDomainExecutionPolicy implements ManagedObjectRunner.
+func (o *objectRunner) SetDomainExecutionPolicy(domain string, policy ExecutionPolicy) error {
+	return o.setExecutionPolicy(&o.domainGates, domain, policy)
+}
+
+func (o *objectRunner) setExecutionPolicy(gates *map[string]*executionGate, key string, policy ExecutionPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	o.gatesMu.Lock()
+	defer o.gatesMu.Unlock()
+	if *gates == nil {
+		*gates = make(map[string]*executionGate)
+	}
+	if policy.Mode == "" || policy.Mode == ExecutionParallel {
+		delete(*gates, key)
+		return nil
+	}
+	// Executions already holding the previous gate finish against it.
+	(*gates)[key] = newExecutionGate(policy)
+	return nil
+}
+
+// gateFor returns the gate governing an object: its own policy first, then
+// the one shared by its domain. nil means no limit.
+func (o *objectRunner) gateFor(metadata ObjectMetadata) *executionGate {
+	o.gatesMu.Lock()
+	defer o.gatesMu.Unlock()
+	if gate, ok := o.objectGates[metadata.ObjectID]; ok {
+		return gate
+	}
+	return o.domainGates[metadata.Domain]
+}
+
+// runObjectAction runs the action through RunActionContext when the object
+// supports cancellation and falls back to RunAction otherwise. A panic in the
+// action is recovered and returned as a *recovery.PanicError.
+func runObjectAction(ctx context.Context, obj RegistrableObject, id, action string, payload []byte) (resp map[string]string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			resp, err = nil, recovery.Error(recovery.BoundaryAction, r)
+		}
+	}()
+	if runner, ok := obj.(ContextActionRunner); ok {
+		return runner.RunActionContext(ctx, id, action, payload)
+	}
+	return obj.RunAction(id, action, payload)
+}
+
+// Shutdown implements ManagedObjectRunner. It stops accepting action requests and
+// waits for the executions in flight to finish. If ctx expires first, their
+// contexts are cancelled and Shutdown returns ctx.Err() without waiting any
+// longer. Finally every registered object implementing io.Closer (speakers and
+// microphones with active sessions) is closed.
+func (o *objectRunner) Shutdown(ctx context.Context) error {
+	runnersMu.Lock()
+	delete(runners, o)
+	runnersMu.Unlock()
+	o.mu.Lock()
+	o.closed = true
+	o.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		o.inflight.Wait()
+		close(drained)
+	}()
+
+	var drainErr error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		drainErr = ctx.Err()
+	}
+	o.cancel()
+
+	return errors.Join(drainErr, o.closeObjects(ctx))
+}
+
+// closeObjects closes every registered io.Closer concurrently and waits for
+// them, or for ctx to expire.
+func (o *objectRunner) closeObjects(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	o.objectsMap.Range(func(_, value any) bool {
+		objects, _ := value.([]RegistrableObject)
+		for _, obj := range objects {
+			closer, ok := obj.(io.Closer)
+			if !ok {
+				continue
 			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var err error
+				if panicErr := recovery.Guard(recovery.BoundaryClose, func() { err = closer.Close() }); panicErr != nil {
+					err = panicErr
+				}
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}()
 		}
+		return true
 	})
 
+	closed := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
 }
 
 // RegisterObject implements objects.ObjectRunner.
 func (o *objectRunner) RegisterObject(object RegistrableObject) error {
 
 	if err := o.controller.CreateObject(object); err != nil {
-		if !strings.Contains(err.Error(), "ERR_ITEM_ALREADY_EXIST") {
+		if !errors.Is(err, ErrObjectAlreadyExists) {
 			return err
 		}
 	}
 
+	if seeder, ok := o.controller.(stateCacheSeeder); ok {
+		seeder.seedStateCache(o.ctx, object.GetMetadata().ObjectID)
+	}
+
 	for _, action := range object.GetAvailableActions() {
 		if err := o.controller.NewAction(action); err != nil {
-			if !strings.Contains(err.Error(), "ERR_ITEM_ALREADY_EXIST") {
+			if !errors.Is(err, ErrObjectAlreadyExists) {
 				return err
 			}
 		}
 	}
 
-	existingObjectsRaw, ok := o.objectsMap.Load(object.GetMetadata().Domain)
-	if !ok {
-		o.objectsMap.Store(object.GetMetadata().Domain, []RegistrableObject{object})
-	} else {
-		existingObjects, validType := existingObjectsRaw.([]RegistrableObject)
-		if !validType {
-			// sugar.Info("invalid objects type", zap.String("domain", object.GetMetadata().Domain))
-			return errors.New("invalid objects type")
+	if previous := o.track(object); previous != nil && !sameObject(previous, object) {
+		closeObject(previous)
+	}
+	o.subscribeDomain(object.GetMetadata().Domain)
+
+	return setupObject(object, o.controller)
+}
+
+// subscribeDomain asks the hub for the action requests of domain through the
+// runner's own controller. Other controllers get the request on the event bus.
+func (o *objectRunner) subscribeDomain(domain string) {
+	if subscriber, ok := UnwrapController(o.controller).(domainResubscriber); ok {
+		if err := subscriber.subscribeToDomain(domain); err != nil {
+			logger.Logger().Error(err)
 		}
-		o.objectsMap.Store(object.GetMetadata().Domain, append(existingObjects, object))
+		return
 	}
-	eventbus.Pubsub.Publish("SUBSCRIBE_OBJECTS_COMMANDS_LISTENING", struct{ Domain string }{Domain: object.GetMetadata().Domain})
+	eventbus.Pubsub.Publish("SUBSCRIBE_OBJECTS_COMMANDS_LISTENING", struct{ Domain string }{Domain: domain})
+}
 
-	return object.Setup(o.controller)
+// setupObject runs the object's Setup, where driver callbacks run, turning a
+// panic into the returned error.
+func setupObject(object RegistrableObject, oc ObjectController) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovery.Error(recovery.BoundarySetup, r)
+		}
+	}()
+	return object.Setup(oc)
+}
+
+// registeredDomains lists the domains that have at least one registered
+// object, so the controller can subscribe them again after a reconnection.
+func (o *objectRunner) registeredDomains() []string {
+	domains := []string{}
+	o.objectsMap.Range(func(key, _ any) bool {
+		if domain, ok := key.(string); ok {
+			domains = append(domains, domain)
+		}
+		return true
+	})
+	return domains
 }
 
-func NewObjectRunner(controller ObjectController) ObjectRunner {
+func NewObjectRunner(controller ObjectController) ManagedObjectRunner {
+	ctx, cancel := context.WithCancel(context.Background())
 	runner := &objectRunner{
 		controller: controller,
 		// objectsMap: make(map[string][]RegistrableObject),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if resubscriber, ok := controller.(domainResubscriber); ok {
+		resubscriber.setRegisteredDomains(runner.registeredDomains)
 	}
 
 	runner.listenActions()
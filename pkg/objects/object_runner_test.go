@@ -0,0 +1,422 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/internal/eventbus"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/logger"
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/tracing"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newRunnerWithSwitch registers a switch exposing a "<domain>.action.wait"
+// custom action backed by handler.
+func newRunnerWithSwitch(t *testing.T, domain string, handler CustomActionHandler) ManagedObjectRunner {
+	t.Helper()
+	runner := NewObjectRunner(newMockMicController(""))
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: domain + ".1", Domain: domain},
+	})
+	require.NoError(t, sw.RegisterCustomAction(domain+".action.wait", handler))
+	require.NoError(t, runner.RegisterObject(sw))
+	return runner
+}
+
+func publishAction(domain string) {
+	eventbus.Pubsub.Publish("REQUEST_ACTION_EXECUTION", map[string]interface{}{
+		"id":      "exec-" + domain,
+		"domain":  domain,
+		"action":  domain + ".action.wait",
+		"payload": map[string]interface{}{},
+	})
+}
+
+func TestObjectRunner_Shutdown_drainsInFlightActions(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	runner := newRunnerWithSwitch(t, "test.drain", func(CustomActionContext) (map[string]string, error) {
+		close(started)
+		<-release
+		finished.Store(true)
+		return nil, nil
+	})
+
+	publishAction("test.drain")
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- runner.Shutdown(context.Background()) }()
+
+	select {
+	case <-shutdownErr:
+		t.Fatal("Shutdown returned while an action was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-shutdownErr:
+		require.NoError(t, err)
+		assert.True(t, finished.Load())
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown did not return after the action finished")
+	}
+}
+
+func TestObjectRunner_Shutdown_cancelsActionsOnDeadline(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	runner := newRunnerWithSwitch(t, "test.cancel", func(ctx CustomActionContext) (map[string]string, error) {
+		close(started)
+		<-ctx.Context.Done()
+		close(cancelled)
+		return nil, ctx.Context.Err()
+	})
+
+	publishAction("test.cancel")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, runner.Shutdown(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("custom action handler was not cancelled")
+	}
+}
+
+func TestObjectRunner_Shutdown_ignoresLaterRequests(t *testing.T) {
+	var calls atomic.Int32
+	runner := newRunnerWithSwitch(t, "test.closed", func(CustomActionContext) (map[string]string, error) {
+		calls.Add(1)
+		return nil, nil
+	})
+	// A second runner keeps listening after the first one shuts down.
+	other := make(chan struct{}, 1)
+	otherRunner := newRunnerWithSwitch(t, "test.open", func(CustomActionContext) (map[string]string, error) {
+		other <- struct{}{}
+		return nil, nil
+	})
+	defer otherRunner.Shutdown(context.Background())
+
+	require.NoError(t, runner.Shutdown(context.Background()))
+	publishAction("test.closed")
+	publishAction("test.open")
+
+	select {
+	case <-other:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the runner still open did not receive its action")
+	}
+	assert.Zero(t, calls.Load())
+}
+
+// resultRecorder records every UpdateResultAttributes call.
+type resultRecorder struct {
+	*mockMicController
+	results chan map[string]string
+}
+
+func (r *resultRecorder) UpdateResultAttributes(execID string, a map[string]string) error {
+	r.results <- a
+	return nil
+}
+
+func TestObjectRunner_serialPolicyReportsQueue(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	sw := NewSwitchObject(NewSwitchObjectParams{Metadata: ObjectMetadata{ObjectID: "serial.1", Domain: "test.serial"}})
+	require.NoError(t, sw.RegisterCustomAction("test.serial.action.wait", func(CustomActionContext) (map[string]string, error) {
+		started <- struct{}{}
+		<-release
+		return map[string]string{"done": "true"}, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+	require.NoError(t, runner.SetDomainExecutionPolicy("test.serial", ExecutionPolicy{Mode: ExecutionSerial}))
+
+	publishAction("test.serial")
+	<-started
+	publishAction("test.serial")
+
+	assert.Equal(t, map[string]string{"queue_depth": "1"}, <-controller.results)
+	select {
+	case <-started:
+		t.Fatal("the second execution started while the first was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	assert.Equal(t, map[string]string{"done": "true"}, <-controller.results)
+	<-started
+	release <- struct{}{}
+	final := <-controller.results
+	assert.Equal(t, "true", final["done"])
+	assert.Equal(t, "1", final["queue_depth"])
+	assert.NotEmpty(t, final["queue_wait_ms"])
+}
+
+func TestObjectRunner_timeoutFromPayload(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	var deadlineSet atomic.Bool
+	sw := NewSwitchObject(NewSwitchObjectParams{Metadata: ObjectMetadata{ObjectID: "timeout.1", Domain: "test.timeout"}})
+	require.NoError(t, sw.RegisterCustomAction("test.timeout.action.wait", func(ctx CustomActionContext) (map[string]string, error) {
+		_, ok := ctx.Context.Deadline()
+		deadlineSet.Store(ok)
+		// Ignore ctx on purpose: the runner must report the timeout anyway.
+		time.Sleep(300 * time.Millisecond)
+		return map[string]string{"late": "true"}, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	eventbus.Pubsub.Publish("REQUEST_ACTION_EXECUTION", map[string]interface{}{
+		"id":      "exec-timeout",
+		"domain":  "test.timeout",
+		"action":  "test.timeout.action.wait",
+		"payload": map[string]interface{}{"timeout": 0.05},
+	})
+
+	select {
+	case result := <-controller.results:
+		assert.Equal(t, ActionErrorCodeTimeout, result["error_code"])
+		assert.Contains(t, result["error"], "timed out after 50ms")
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("the timeout was not reported before the action returned")
+	}
+	assert.True(t, deadlineSet.Load())
+
+	// The late result is dropped.
+	select {
+	case result := <-controller.results:
+		t.Fatalf("unexpected second result %v", result)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestObjectRunner_defaultActionTimeoutReachesCallbacks(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+	runner.SetActionTimeout(DOOR_ACTION_OPEN, 50*time.Millisecond)
+
+	door := NewDoorObject(NewDoorObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "door.1", Domain: "test.door"},
+		OpenDoorMethod: func(_ DoorObject, oc ObjectController) error {
+			<-ExecutionContext(oc).Done()
+			return ExecutionContext(oc).Err()
+		},
+	})
+	require.NoError(t, runner.RegisterObject(door))
+
+	eventbus.Pubsub.Publish("REQUEST_ACTION_EXECUTION", map[string]interface{}{
+		"id":     "exec-door",
+		"domain": "test.door",
+		"action": DOOR_ACTION_OPEN,
+	})
+
+	select {
+	case result := <-controller.results:
+		assert.Equal(t, ActionErrorCodeTimeout, result["error_code"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("the default timeout was not applied")
+	}
+}
+
+func TestExecutionContext_outsideExecution(t *testing.T) {
+	assert.Equal(t, context.Background(), ExecutionContext(newMockMicController("")))
+}
+
+func TestExecutionController_keepsInterfacesAndContext(t *testing.T) {
+	srv, puts := newStatesServer(t, "")
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	oc := withExecutionContext(ctx, "exec-1", controller)
+
+	_, isCtx := oc.(ObjectControllerCtx)
+	_, isCache := oc.(StateCacheController)
+	_, isNotifier := oc.(ConnectionStateNotifier)
+	assert.True(t, isCtx && isCache && isNotifier, "the execution controller hides the controller interfaces")
+	assert.ErrorIs(t, oc.SetState("sensor-1", "on"), context.Canceled)
+	assert.Zero(t, puts.Load(), "SetState ignored the execution context")
+
+	mock := newMockMicController("")
+	oc = withExecutionContext(ctx, "exec-2", mock)
+	_, isCtx = oc.(ObjectControllerCtx)
+	_, isCache = oc.(StateCacheController)
+	assert.True(t, isCtx)
+	assert.False(t, isCache, "a controller without a cache must not look like one")
+	assert.Same(t, mock, UnwrapController(oc))
+	assert.ErrorIs(t, oc.SetState("sensor-1", "on"), context.Canceled)
+}
+
+func TestObjectRunner_recoversHandlerPanics(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.panic.1", Domain: "test.panic"},
+	})
+	var calls atomic.Int32
+	require.NoError(t, sw.RegisterCustomAction("test.panic.action.wait", func(CustomActionContext) (map[string]string, error) {
+		if calls.Add(1) == 1 {
+			panic("nil device session")
+		}
+		return map[string]string{"done": "true"}, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	publishAction("test.panic")
+	result := <-controller.results
+	assert.Equal(t, ActionErrorCodePanic, result["error_code"])
+	assert.Contains(t, result["error"], "nil device session")
+
+	// The runner keeps serving actions.
+	publishAction("test.panic")
+	assert.Equal(t, map[string]string{"done": "true"}, <-controller.results)
+}
+
+func TestObjectRunner_RegisterObject_recoversSetupPanics(t *testing.T) {
+	runner := NewObjectRunner(newMockMicController(""))
+	defer runner.Shutdown(context.Background())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.setup_panic.1", Domain: "test.setup_panic"},
+		SetupMethod: func(RegistrableObject, ObjectController) error {
+			panic("setup failed")
+		},
+	})
+	assert.ErrorContains(t, runner.RegisterObject(sw), "setup failed")
+}
+
+func TestObjectRunner_recordsActionMetrics(t *testing.T) {
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.metrics.1", Domain: "test.metrics"},
+	})
+	var calls atomic.Int32
+	require.NoError(t, sw.RegisterCustomAction("test.metrics.action.wait", func(CustomActionContext) (map[string]string, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("device offline")
+		}
+		return nil, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	publishAction("test.metrics")
+	<-controller.results
+	publishAction("test.metrics")
+	<-controller.results
+
+	assert.Equal(t, float64(1), actionExecutions.Value("test.metrics", "test.metrics.action.wait", string(ActionStatusFailed)))
+	assert.Equal(t, float64(1), actionExecutions.Value("test.metrics", "test.metrics.action.wait", string(ActionStatusSucceeded)))
+	assert.Equal(t, uint64(2), actionDuration.Count("test.metrics", "test.metrics.action.wait"))
+}
+
+func TestObjectRunner_logsWithExecutionFields(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.Set(zap.New(core))
+	defer logger.Set(nil)
+
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.logs.1", Domain: "test.logs"},
+	})
+	require.NoError(t, sw.RegisterCustomAction("test.logs.action.wait", func(ctx CustomActionContext) (map[string]string, error) {
+		logger.FromContext(ctx.Context).Info("from handler")
+		return nil, nil
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	publishAction("test.logs")
+	<-controller.results
+
+	entries := logs.FilterMessage("from handler").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "exec-test.logs", fields["action_execution_id"])
+	assert.Equal(t, "test.logs.1", fields["object_id"])
+	assert.Equal(t, "test.logs.action.wait", fields["action"])
+}
+
+func TestObjectRunner_recordsSpans(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	controller := &resultRecorder{mockMicController: newMockMicController(""), results: make(chan map[string]string, 8)}
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "test.trace.1", Domain: "test.trace"},
+	})
+	var handlerSpan tracing.SpanContext
+	require.NoError(t, sw.RegisterCustomAction("test.trace.action.wait", func(ctx CustomActionContext) (map[string]string, error) {
+		handlerSpan = tracing.SpanContextFromContext(ctx.Context)
+		return nil, errors.New("device offline")
+	}))
+	require.NoError(t, runner.RegisterObject(sw))
+
+	publishAction("test.trace")
+	<-controller.results
+
+	require.Eventually(t, func() bool { return len(exporter.Spans()) == 2 }, time.Second, 5*time.Millisecond)
+	run, execution := exporter.Spans()[0], exporter.Spans()[1]
+	assert.Equal(t, "action.execution", execution.Name)
+	assert.Equal(t, "exec-test.trace", execution.Attributes["action_execution_id"])
+	assert.Equal(t, tracing.StatusError, execution.Status)
+
+	assert.Equal(t, "action.run", run.Name)
+	assert.Equal(t, execution.TraceID, run.TraceID)
+	assert.Equal(t, execution.SpanID, run.ParentSpanID)
+	assert.Equal(t, "test.trace.1", run.Attributes["object_id"])
+	assert.Equal(t, string(ActionStatusFailed), run.Attributes["status"])
+	assert.Equal(t, tracing.SpanContext{TraceID: run.TraceID, SpanID: run.SpanID}, handlerSpan)
+}
+
+func TestObjectRunner_RegisterObject_replacesSameID(t *testing.T) {
+	runner := NewObjectRunner(newMockMicController("")).(*objectRunner)
+	t.Cleanup(func() { runner.Shutdown(context.Background()) })
+	for i := 0; i < 2; i++ {
+		require.NoError(t, runner.RegisterObject(NewSwitchObject(NewSwitchObjectParams{
+			Metadata: ObjectMetadata{ObjectID: "replace.1", Domain: "replace", DeviceID: "3"},
+		})))
+	}
+	assert.Len(t, runner.registered("3"), 1)
+
+	// Without a lifecycle controller, objects are disabled instead of deleted.
+	require.NoError(t, runner.UnregisterObject("replace.1"))
+	assert.Empty(t, runner.registered("3"))
+	assert.Empty(t, runner.registeredDomains())
+
+	sw := NewSwitchObject(NewSwitchObjectParams{Metadata: ObjectMetadata{ObjectID: "replace.2", Domain: "replace"}})
+	assert.ErrorIs(t, runner.UpdateObject(sw), ErrMethodNotImplemented)
+	_, err := runner.Reconcile("3", nil)
+	assert.ErrorIs(t, err, ErrMethodNotImplemented)
+}
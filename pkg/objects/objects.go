@@ -1,10 +1,48 @@
 package objects
 
+import (
+	"context"
+	"time"
+)
+
 type ObjectRunner interface {
 	RegisterObject(object RegistrableObject) error
 	GetController() ObjectController
 }
 
+// ManagedObjectRunner is the ObjectRunner returned by NewObjectRunner. Its
+// methods live outside ObjectRunner so implementations of ObjectRunner written
+// against earlier releases keep compiling.
+type ManagedObjectRunner interface {
+	ObjectRunner
+	// UnregisterObject stops routing actions to an object, closes it if it
+	// holds sessions open and deletes it from the DriverHub (disables it when
+	// the controller cannot delete objects).
+	UnregisterObject(objectID string) error
+	// UpdateObject pushes the name, type, tags, states and actions of an
+	// object the DriverHub already holds, and routes its actions to object.
+	UpdateObject(object RegistrableObject) error
+	// Reconcile makes the DriverHub objects of a device match desired:
+	// missing objects are created, changed ones updated, disabled ones
+	// enabled, and the others disabled, or deleted with DeleteStale. It goes
+	// through every object and returns the errors joined.
+	Reconcile(deviceID string, desired []RegistrableObject, opts ...ReconcileOptions) (ReconcileResult, error)
+	// DeviceObjects returns the registered objects of a device, by ID.
+	DeviceObjects(deviceID string) []RegistrableObject
+	// Shutdown stops accepting action requests, drains the executions in
+	// flight and closes the objects that hold sessions open.
+	Shutdown(ctx context.Context) error
+	// SetObjectExecutionPolicy limits the concurrent action executions on one
+	// object. It takes precedence over the policy of the object's domain.
+	SetObjectExecutionPolicy(objectID string, policy ExecutionPolicy) error
+	// SetDomainExecutionPolicy limits the concurrent action executions across
+	// all objects of a domain, e.g. every relay behind the same panel.
+	SetDomainExecutionPolicy(domain string, policy ExecutionPolicy) error
+	// SetActionTimeout sets the deadline of every execution of action whose
+	// payload carries no "timeout". 0 removes it.
+	SetActionTimeout(action string, timeout time.Duration)
+}
+
 type SetupFunction func(RegistrableObject, ObjectController) error
 
 type ObjectMetadata struct {
@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"errors"
 	"strconv"
 
@@ -92,6 +93,11 @@ func (s *octopusObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements RegistrableObject.
 func (s *octopusObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return s.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (s *octopusObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case OCTOPUS_ACTION_RELAY_ON, OCTOPUS_ACTION_RELAY_OFF:
 		payloadMap := make(map[string]string)
@@ -99,11 +105,11 @@ func (s *octopusObject) RunAction(id, action string, payload []byte) (map[string
 			return nil, err
 		}
 		if action == OCTOPUS_ACTION_RELAY_ON {
-			return s.relayOnFn(s, s.controller, RelayOnPayload{RelayID: payloadMap["relay_id"]})
+			return s.relayOnFn(s, withExecutionContext(ctx, id, s.controller), RelayOnPayload{RelayID: payloadMap["relay_id"]})
 		}
-		return s.relayOffFn(s, s.controller, RelayOffPayload{RelayID: payloadMap["relay_id"]})
+		return s.relayOffFn(s, withExecutionContext(ctx, id, s.controller), RelayOffPayload{RelayID: payloadMap["relay_id"]})
 	}
-	return s.dispatchCustom(s, s.controller, id, action, payload)
+	return s.dispatchCustom(ctx, s, s.controller, id, action, payload)
 }
 
 // Setup implements RegistrableObject.
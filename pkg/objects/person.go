@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 type personObject struct {
 	customActions
 	metadata   ObjectMetadata
@@ -26,7 +28,12 @@ func (p *personObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements RegistrableObject.
 func (p *personObject) RunAction(id string, action string, payload []byte) (map[string]string, error) {
-	return p.dispatchCustom(p, p.controller, id, action, payload)
+	return p.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (p *personObject) RunActionContext(ctx context.Context, id string, action string, payload []byte) (map[string]string, error) {
+	return p.dispatchCustom(ctx, p, p.controller, id, action, payload)
 }
 
 // SetState implements RegistrableObject.
@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
@@ -236,34 +237,39 @@ func (r *readerObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements ReaderObject.
 func (r *readerObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return r.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (r *readerObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 	switch action {
 
 	case READER_ACTION_RESTART:
-		return nil, r.restart(r, r.controller)
+		return nil, r.restart(r, withExecutionContext(ctx, id, r.controller))
 
 	case READER_ACTION_STORE_QRS:
 		storeQrsPayload := QRPayload{}
 		if err := json.Unmarshal(payload, &storeQrsPayload); err != nil {
 			return nil, err
 		}
-		return nil, r.storeQRCredentials(r, r.controller, storeQrsPayload)
+		return nil, r.storeQRCredentials(r, withExecutionContext(ctx, id, r.controller), storeQrsPayload)
 
 	case READER_ACTION_DELETE_QRS:
 		deleteQrsPayload := QRPayload{}
 		if err := json.Unmarshal(payload, &deleteQrsPayload); err != nil {
 			return nil, err
 		}
-		return nil, r.deleteQRCredentials(r, r.controller, deleteQrsPayload)
+		return nil, r.deleteQRCredentials(r, withExecutionContext(ctx, id, r.controller), deleteQrsPayload)
 
 	case READER_ACTION_DELETE_PERSON:
 		deletePersonPayload := DeletePersonPayload{}
 		if err := json.Unmarshal(payload, &deletePersonPayload); err != nil {
 			return nil, err
 		}
-		return nil, r.deletePersonCredentials(r, r.controller, deletePersonPayload)
+		return nil, r.deletePersonCredentials(r, withExecutionContext(ctx, id, r.controller), deletePersonPayload)
 
 	case READER_ACTION_GET_PEOPLE:
-		people, err := r.getPeopleCredentials(r, r.controller)
+		people, err := r.getPeopleCredentials(r, withExecutionContext(ctx, id, r.controller))
 		if err != nil {
 			return nil, err
 		}
@@ -278,7 +284,7 @@ func (r *readerObject) RunAction(id, action string, payload []byte) (map[string]
 		if err := json.Unmarshal(payload, &people); err != nil {
 			return nil, err
 		}
-		return nil, r.setPeopleCredentials(r, r.controller, people)
+		return nil, r.setPeopleCredentials(r, withExecutionContext(ctx, id, r.controller), people)
 	case READER_ACTION_READ:
 		if r.readCredential == nil {
 			return nil, fmt.Errorf("read credential method not implemented")
@@ -290,7 +296,7 @@ func (r *readerObject) RunAction(id, action string, payload []byte) (map[string]
 		if !slices.Contains(r.supportedCredentialTypes, readCredentialPayload.Type) {
 			return nil, fmt.Errorf("credential type '%s' not supported", readCredentialPayload.Type)
 		}
-		response, err := r.readCredential(r, r.controller, readCredentialPayload)
+		response, err := r.readCredential(r, withExecutionContext(ctx, id, r.controller), readCredentialPayload)
 		if err != nil {
 			return nil, err
 		}
@@ -315,13 +321,13 @@ func (r *readerObject) RunAction(id, action string, payload []byte) (map[string]
 		if err := json.Unmarshal([]byte(wrapper.Data), &syncPayload); err != nil {
 			return nil, fmt.Errorf("sync_access_database: unmarshal payload: %w", err)
 		}
-		if err := r.syncAccessDatabase(r, r.controller, syncPayload); err != nil {
+		if err := r.syncAccessDatabase(r, withExecutionContext(ctx, id, r.controller), syncPayload); err != nil {
 			return nil, err
 		}
 		return map[string]string{"success": "true"}, nil
 	}
 
-	return r.dispatchCustom(r, r.controller, id, action, payload)
+	return r.dispatchCustom(ctx, r, r.controller, id, action, payload)
 }
 
 // SetState implements ReaderObject.
@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 type EventType struct {
 	Domain             string `json:"domain"`
 	DisplayName        string `json:"display_name"`
@@ -21,3 +23,10 @@ type RegistrableObject interface {
 	SetState(state string) error
 	UpdateStateAttributes(attributes map[string]string) error
 }
+
+// ContextActionRunner is implemented by objects whose actions can be cancelled.
+// The runner prefers RunActionContext over RunAction and cancels ctx when the
+// client shuts down. All built-in objects implement it.
+type ContextActionRunner interface {
+	RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error)
+}
@@ -1,6 +1,9 @@
 package objects
 
-import "strconv"
+import (
+	"context"
+	"strconv"
+)
 
 const RELATIVE_TRACKER_STATE_MOVING = "relative_tracker.state.moving"
 const RELATIVE_TRACKER_STATE_NO_SIGNAL = "relative_tracker.state.no_signal"
@@ -59,7 +62,12 @@ func (r *relativeTrackerObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements RelativeTrackerObject.
 func (r *relativeTrackerObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
-	return r.dispatchCustom(r, r.controller, id, action, payload)
+	return r.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (r *relativeTrackerObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
+	return r.dispatchCustom(ctx, r, r.controller, id, action, payload)
 }
 
 // SetAcceleration implements RelativeTrackerObject.
@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/goccy/go-json"
@@ -52,7 +53,12 @@ func (r *relativeZoneObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements RelativeZoneObject.
 func (r *relativeZoneObject) RunAction(id string, action string, payload []byte) (map[string]string, error) {
-	return r.dispatchCustom(r, r.controller, id, action, payload)
+	return r.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (r *relativeZoneObject) RunActionContext(ctx context.Context, id string, action string, payload []byte) (map[string]string, error) {
+	return r.dispatchCustom(ctx, r, r.controller, id, action, payload)
 }
 
 // SetState implements RelativeZoneObject.
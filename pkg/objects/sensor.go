@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -133,24 +134,29 @@ func (s *sensorObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements RegistrableObject.
 func (s *sensorObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return s.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (s *sensorObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case SENSOR_ACTION_BYPASS:
 		if s.alarmDetectorBypass == nil {
 			return nil, fmt.Errorf("alarm detector bypass not set")
 		}
-		return s.alarmDetectorBypass(s, s.controller, AlarmDetectorBypassPayload{})
+		return s.alarmDetectorBypass(s, withExecutionContext(ctx, id, s.controller), AlarmDetectorBypassPayload{})
 	case SENSOR_ACTION_UNBYPASS:
 		if s.alarmDetectorUnbypass == nil {
 			return nil, fmt.Errorf("alarm detector unbypass not set")
 		}
-		return s.alarmDetectorUnbypass(s, s.controller, AlarmDetectorUnbypassPayload{})
+		return s.alarmDetectorUnbypass(s, withExecutionContext(ctx, id, s.controller), AlarmDetectorUnbypassPayload{})
 	case SENSOR_CUSTOM_ACTION:
 		if s.customAction == nil {
 			return nil, fmt.Errorf("custom action not set")
 		}
-		return s.customAction(s, s.controller, CustomActionPayload{})
+		return s.customAction(s, withExecutionContext(ctx, id, s.controller), CustomActionPayload{})
 	}
-	return s.dispatchCustom(s, s.controller, id, action, payload)
+	return s.dispatchCustom(ctx, s, s.controller, id, action, payload)
 }
 
 // Setup implements RegistrableObject.
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"github.com/Netsocs-Team/driver.sdk_go/internal/recovery"
 	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
 )
 
@@ -22,7 +23,7 @@ const (
 )
 
 // PlayAudioClipActionPayload is sent by DriversHub when triggering SPEAKER_ACTION_PLAY_AUDIO_CLIP.
-// Timeout is a deadline hint for the driver; 0 means no deadline.
+// Timeout becomes the deadline of the ctx passed to PlayAudioClipFn; 0 means no deadline.
 type PlayAudioClipActionPayload struct {
 	URL     string `json:"url"`
 	Timeout int    `json:"timeout"` // seconds; 0 = no deadline
@@ -34,6 +35,8 @@ type TalkbackStream struct {
 	ws   *websocket.Conn
 	done chan struct{}
 	once sync.Once
+	// cancel cancels the context passed to the session handler.
+	cancel context.CancelFunc
 }
 
 // ReadRTP blocks until a raw RTP packet is received from DriversHub.
@@ -46,9 +49,13 @@ func (s *TalkbackStream) ReadRTP() ([]byte, error) {
 // Done returns a channel that is closed when the stream ends.
 func (s *TalkbackStream) Done() <-chan struct{} { return s.done }
 
-// Close terminates the stream and the underlying WebSocket connection.
+// Close terminates the stream and the underlying WebSocket connection, and
+// cancels the context passed to the session handler.
 func (s *TalkbackStream) Close() {
 	s.once.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
 		s.ws.Close()
 		close(s.done)
 	})
@@ -89,6 +96,7 @@ type speakerObject struct {
 	props          NewSpeakerObjectProps
 	controller     ObjectController
 	activeSessions sync.Map // sessionID → *TalkbackStream
+	sessions       sync.WaitGroup
 }
 
 func (s *speakerObject) GetMetadata() ObjectMetadata {
@@ -146,15 +154,20 @@ func (s *speakerObject) Setup(ctrl ObjectController) error {
 }
 
 func (s *speakerObject) RunAction(executionID, action string, payload []byte) (map[string]string, error) {
+	return s.RunActionContext(context.Background(), executionID, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (s *speakerObject) RunActionContext(ctx context.Context, executionID, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case SPEAKER_ACTION_START_TALKBACK:
 		return s.startTalkback(payload)
 	case SPEAKER_ACTION_STOP_TALKBACK:
 		return s.stopTalkback(payload)
 	case SPEAKER_ACTION_PLAY_AUDIO_CLIP:
-		return s.playAudioClip(payload)
+		return s.playAudioClip(ctx, payload)
 	}
-	return s.dispatchCustom(s, s.controller, executionID, action, payload)
+	return s.dispatchCustom(ctx, s, s.controller, executionID, action, payload)
 }
 
 func (s *speakerObject) startTalkback(payload []byte) (map[string]string, error) {
@@ -171,12 +184,16 @@ func (s *speakerObject) startTalkback(payload []byte) (map[string]string, error)
 		return nil, fmt.Errorf("speaker: dial DriversHub audio stream: %w", err)
 	}
 
-	stream := &TalkbackStream{ws: ws, done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &TalkbackStream{ws: ws, done: make(chan struct{}), cancel: cancel}
 	s.activeSessions.Store(p.SessionID, stream)
 	_ = s.SetStateTalkback()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	s.sessions.Add(1)
+	audioSessions.Inc(audioSessionTalkback)
 	go func() {
+		defer s.sessions.Done()
+		defer audioSessions.Dec(audioSessionTalkback)
 		defer cancel()
 		defer func() {
 			s.activeSessions.Delete(p.SessionID)
@@ -184,7 +201,9 @@ func (s *speakerObject) startTalkback(payload []byte) (map[string]string, error)
 			_ = s.SetStateIdle()
 		}()
 		if s.props.StartTalkbackFn != nil {
-			s.props.StartTalkbackFn(ctx, p.SessionID, stream)
+			// The driver callback runs outside the action handler, so it
+			// gets its own recovery: a panic ends the session only.
+			recovery.Guard(recovery.BoundaryAction, func() { s.props.StartTalkbackFn(ctx, p.SessionID, stream) })
 		}
 	}()
 
@@ -202,7 +221,7 @@ func (s *speakerObject) stopTalkback(payload []byte) (map[string]string, error)
 	return nil, nil
 }
 
-func (s *speakerObject) playAudioClip(payload []byte) (map[string]string, error) {
+func (s *speakerObject) playAudioClip(ctx context.Context, payload []byte) (map[string]string, error) {
 	var p PlayAudioClipActionPayload
 	if err := json.Unmarshal(payload, &p); err != nil {
 		return nil, fmt.Errorf("speaker: unmarshal play_audio_clip payload: %w", err)
@@ -214,13 +233,25 @@ func (s *speakerObject) playAudioClip(payload []byte) (map[string]string, error)
 	_ = s.controller.SetState(s.props.Metadata.ObjectID, SPEAKER_STATE_PLAYING)
 	defer func() { _ = s.SetStateIdle() }()
 
-	if err := s.props.PlayAudioClipFn(context.Background(), p.URL); err != nil {
+	if err := s.props.PlayAudioClipFn(ctx, p.URL); err != nil {
 		return nil, fmt.Errorf("speaker: play_audio_clip: %w", err)
 	}
 
 	return map[string]string{"status": "played"}, nil
 }
 
+// Close ends every active talkback session and waits for their
+// StartTalkbackFn calls to return. The client calls it on shutdown.
+func (s *speakerObject) Close() error {
+	s.activeSessions.Range(func(key, v any) bool {
+		s.activeSessions.Delete(key)
+		v.(*TalkbackStream).Close()
+		return true
+	})
+	s.sessions.Wait()
+	return nil
+}
+
 // NewSpeakerObject creates a SpeakerObject ready to be registered with the SDK client.
 func NewSpeakerObject(props NewSpeakerObjectProps) SpeakerObject {
 	return &speakerObject{props: props}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -353,3 +355,74 @@ func TestSpeakerObject_StateTransitionsAfterStop(t *testing.T) {
 		return ctrl.getState("spk.1") == SPEAKER_STATE_IDLE
 	}, time.Second, 10*time.Millisecond)
 }
+
+func TestSpeakerObject_Close_endsActiveSessions(t *testing.T) {
+	connCh := make(chan *websocket.Conn, 1)
+	srv := newWSSServer(t, connCh)
+	defer srv.Close()
+
+	ctrl := newMockMicController(srv.URL)
+	var returned atomic.Bool
+	spk := NewSpeakerObject(NewSpeakerObjectProps{
+		Metadata: ObjectMetadata{ObjectID: "spk.close", Domain: "test.speaker"},
+		StartTalkbackFn: func(ctx context.Context, sessionID string, stream *TalkbackStream) error {
+			<-ctx.Done()
+			returned.Store(true)
+			return nil
+		},
+	})
+	require.NoError(t, spk.Setup(ctrl))
+
+	payload, _ := json.Marshal(map[string]string{"session_id": "sess-close"})
+	_, err := spk.RunAction("exec1", SPEAKER_ACTION_START_TALKBACK, payload)
+	require.NoError(t, err)
+
+	closer, ok := spk.(io.Closer)
+	require.True(t, ok)
+	require.NoError(t, closer.Close())
+	assert.True(t, returned.Load(), "Close must wait for StartTalkbackFn to return")
+	assert.Equal(t, SPEAKER_STATE_IDLE, ctrl.getState("spk.close"))
+}
+
+func TestSpeakerObject_PlayAudioClip_receivesExecutionContext(t *testing.T) {
+	ctrl := newMockMicController("")
+	spk := NewSpeakerObject(NewSpeakerObjectProps{
+		Metadata: ObjectMetadata{ObjectID: "spk.ctx", Domain: "test.speaker"},
+		PlayAudioClipFn: func(ctx context.Context, url string) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	require.NoError(t, spk.Setup(ctrl))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	payload, _ := json.Marshal(PlayAudioClipActionPayload{URL: "http://clip"})
+	_, err := spk.(ContextActionRunner).RunActionContext(ctx, "exec1", SPEAKER_ACTION_PLAY_AUDIO_CLIP, payload)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, SPEAKER_STATE_IDLE, ctrl.getState("spk.ctx"))
+}
+
+func TestSpeakerObject_StartTalkback_recoversPanic(t *testing.T) {
+	connCh := make(chan *websocket.Conn, 1)
+	srv := newWSSServer(t, connCh)
+	defer srv.Close()
+
+	ctrl := newMockMicController(srv.URL)
+	spk := NewSpeakerObject(NewSpeakerObjectProps{
+		Metadata: ObjectMetadata{ObjectID: "spk.1", Domain: "d"},
+		StartTalkbackFn: func(ctx context.Context, sessionID string, stream *TalkbackStream) error {
+			panic("driver bug")
+		},
+	})
+	require.NoError(t, spk.Setup(ctrl))
+
+	payload, _ := json.Marshal(map[string]string{"session_id": "s1"})
+	_, err := spk.RunAction("e1", SPEAKER_ACTION_START_TALKBACK, payload)
+	require.NoError(t, err)
+	<-connCh
+
+	// The panic ends the session, not the process.
+	require.NoError(t, spk.(*speakerObject).Close())
+	assert.Equal(t, SPEAKER_STATE_IDLE, ctrl.getState("spk.1"))
+}
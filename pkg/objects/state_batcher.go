@@ -0,0 +1,235 @@
+package objects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/goccy/go-json"
+)
+
+// Defaults of StateBatchOptions.
+const (
+	DefaultStateBatchWindow = 100 * time.Millisecond
+	DefaultStateBatchSize   = 500
+)
+
+// StateBatchOptions configures the write-behind state batcher.
+type StateBatchOptions struct {
+	// Window is how long changes are collected before being sent; 0 means
+	// DefaultStateBatchWindow.
+	Window time.Duration
+	// MaxBatch sends the batch as soon as it holds that many objects; 0 means
+	// DefaultStateBatchSize.
+	MaxBatch int
+}
+
+// StateBatchController is implemented by controllers that can batch state
+// changes through the states-batch endpoint. The controller returned by
+// NewObjectController implements it.
+type StateBatchController interface {
+	// EnableStateBatching makes SetState and UpdateStateAttributes collect
+	// their changes over opts.Window, coalesce the ones for the same object
+	// and send them in a single request. Each caller still waits for the
+	// batch and gets the error the hub reported for its object.
+	EnableStateBatching(opts StateBatchOptions) error
+	// FlushStateBatch sends the changes collected so far right away.
+	FlushStateBatch(ctx context.Context) error
+}
+
+// stateBatcher collects state changes for the states-batch endpoint.
+type stateBatcher struct {
+	controller *objectController
+	opts       StateBatchOptions
+
+	mu      sync.Mutex
+	pending map[string]*pendingStateChange
+	order   []string
+	timer   *time.Timer
+
+	// flushMu serializes the requests, so two batches touching the same
+	// object reach the hub in the order they were collected.
+	flushMu sync.Mutex
+}
+
+// pendingStateChange is the coalesced change of one object, shared by every
+// caller that contributed to it.
+type pendingStateChange struct {
+	change  batchedStateChange
+	done    chan struct{}
+	applied bool
+	err     error
+}
+
+// batchedStateChange is the change of one object in a states-batch request.
+// Unlike ObjectStateChange it leaves out the fields nobody changed, as the
+// unbatched SetState and UpdateStateAttributes requests do: an empty state or
+// null attributes would clear those of the object. State is a pointer so that
+// SetState(id, "") still sends its empty state, as the unbatched request does.
+type batchedStateChange struct {
+	ObjectID                  string            `json:"object_id"`
+	State                     *string           `json:"state,omitempty"`
+	StateAdditionalProperties map[string]string `json:"state_additional_properties,omitempty"`
+}
+
+// EnableStateBatching implements StateBatchController.
+func (o *objectController) EnableStateBatching(opts StateBatchOptions) error {
+	if opts.Window < 0 || opts.MaxBatch < 0 {
+		return errors.New("state batch window and size cannot be negative")
+	}
+	if opts.Window == 0 {
+		opts.Window = DefaultStateBatchWindow
+	}
+	if opts.MaxBatch == 0 {
+		opts.MaxBatch = DefaultStateBatchSize
+	}
+	o.batcherMu.Lock()
+	defer o.batcherMu.Unlock()
+	if o.batcher != nil {
+		return errors.New("state batching already enabled")
+	}
+	o.batcher = &stateBatcher{controller: o, opts: opts, pending: make(map[string]*pendingStateChange)}
+	return nil
+}
+
+// FlushStateBatch implements StateBatchController. It returns the errors of
+// the flushed changes, joined.
+func (o *objectController) FlushStateBatch(ctx context.Context) error {
+	if b := o.getBatcher(); b != nil {
+		return b.flush(ctx)
+	}
+	return nil
+}
+
+func (o *objectController) getBatcher() *stateBatcher {
+	o.batcherMu.Lock()
+	defer o.batcherMu.Unlock()
+	return o.batcher
+}
+
+// submit adds a change to the current batch and waits until it is sent, or
+// queued in the outbox, and reports whether the hub applied it. A nil state
+// leaves the object's state untouched; attributes are merged into those
+// already pending for the object.
+func (b *stateBatcher) submit(ctx context.Context, objectID string, state *string, attributes map[string]string) (applied bool, err error) {
+	b.mu.Lock()
+	p, ok := b.pending[objectID]
+	if !ok {
+		p = &pendingStateChange{change: batchedStateChange{ObjectID: objectID}, done: make(chan struct{})}
+		b.pending[objectID] = p
+		b.order = append(b.order, objectID)
+	}
+	if state != nil {
+		p.change.State = state
+	}
+	if attributes != nil {
+		if p.change.StateAdditionalProperties == nil {
+			p.change.StateAdditionalProperties = make(map[string]string, len(attributes))
+		}
+		for k, v := range attributes {
+			p.change.StateAdditionalProperties[k] = v
+		}
+	}
+	switch {
+	case len(b.order) >= b.opts.MaxBatch:
+		if b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+		}
+		go b.flush(context.Background())
+	case b.timer == nil:
+		b.timer = time.AfterFunc(b.opts.Window, func() { b.flush(context.Background()) })
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return p.applied, p.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// take detaches the pending changes from the batcher.
+func (b *stateBatcher) take() []*pendingStateChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := make([]*pendingStateChange, 0, len(b.order))
+	for _, objectID := range b.order {
+		batch = append(batch, b.pending[objectID])
+	}
+	b.pending = make(map[string]*pendingStateChange)
+	b.order = nil
+	return batch
+}
+
+// flush sends the pending changes, wakes their callers and returns their
+// errors, joined.
+func (b *stateBatcher) flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	batch := b.take()
+	if len(batch) == 0 {
+		return nil
+	}
+	changes := make([]batchedStateChange, len(batch))
+	for i, p := range batch {
+		changes[i] = p.change
+	}
+	errs, queued, err := b.controller.changeStatesBatch(ctx, changes)
+	var failed []error
+	for _, p := range batch {
+		switch objectErr := errs[p.change.ObjectID]; {
+		case err != nil:
+			p.err = err
+		case errors.Is(objectErr, ErrObjectDisabled):
+			// Ignored, as SetState does, but not applied either.
+		case objectErr != nil:
+			p.err = fmt.Errorf("error setting state: %w", objectErr)
+			failed = append(failed, fmt.Errorf("%s: %w", p.change.ObjectID, p.err))
+		default:
+			p.applied = !queued
+		}
+		close(p.done)
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(failed...)
+}
+
+// changeStatesBatch sends changes to the states-batch endpoint and returns
+// the error the hub reported for each object, ErrObjectDisabled included.
+// With an outbox enabled, the request is queued while the hub is unreachable.
+func (o *objectController) changeStatesBatch(ctx context.Context, changes []batchedStateChange) (errs map[string]error, queued bool, err error) {
+	body := struct {
+		Changes []batchedStateChange `json:"changes"`
+	}{changes}
+	resp, queued, err := o.putOrQueue(ctx, "/objects/states-batch", body)
+	if err != nil || queued {
+		return nil, queued, err
+	}
+	if resp.IsError() {
+		return nil, false, httpx.NewHubError(resp)
+	}
+	var responses []ChangeStateBatchResponse
+	if err := json.Unmarshal(resp.Body(), &responses); err != nil {
+		return nil, false, err
+	}
+	errs = make(map[string]error)
+	for _, r := range responses {
+		if r.Error == "" {
+			continue
+		}
+		errs[r.ID] = httpx.ParseHubError(resp.StatusCode(), http.MethodPut, "/objects/states/"+r.ID, []byte(r.Error))
+	}
+	return errs, false, nil
+}
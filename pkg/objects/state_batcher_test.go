@@ -0,0 +1,169 @@
+package objects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchServer answers states-batch requests, failing the objects listed in
+// failures, and records the requests it receives.
+func newBatchServer(t *testing.T, failures map[string]string) (*httptest.Server, chan ChangeStateBatchRequest) {
+	t.Helper()
+	requests := make(chan ChangeStateBatchRequest, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/objects/states-batch", r.URL.Path)
+		var req ChangeStateBatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests <- req
+		resp := make([]ChangeStateBatchResponse, 0, len(req.Changes))
+		for _, change := range req.Changes {
+			resp = append(resp, ChangeStateBatchResponse{ID: change.ObjectID, Error: failures[change.ObjectID], Changed: failures[change.ObjectID] == ""})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func TestStateBatcher_coalescesAndReportsPerObjectErrors(t *testing.T) {
+	srv, requests := newBatchServer(t, map[string]string{"sensor-2": "object not found"})
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	require.NoError(t, controller.EnableStateBatching(StateBatchOptions{Window: 50 * time.Millisecond}))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	calls := []func() error{
+		func() error { return controller.SetState("sensor-1", "on") },
+		func() error { return controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "12"}) },
+		func() error { return controller.SetState("sensor-2", "on") },
+		func() error {
+			return controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "13", "unit": "C"})
+		},
+	}
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = call()
+		}()
+		time.Sleep(5 * time.Millisecond) // keep the arrival order
+	}
+	wg.Wait()
+
+	req := <-requests
+	require.Len(t, req.Changes, 2)
+	assert.Equal(t, ObjectStateChange{
+		ObjectID:                  "sensor-1",
+		State:                     "on",
+		StateAdditionalProperties: map[string]string{"value": "13", "unit": "C"},
+	}, req.Changes[0])
+	assert.Equal(t, "sensor-2", req.Changes[1].ObjectID)
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.ErrorContains(t, errs[2], "object not found")
+	assert.NoError(t, errs[3])
+	assert.Len(t, requests, 0, "a single request was expected")
+}
+
+func TestStateBatcher_sendsFullBatchRightAway(t *testing.T) {
+	srv, requests := newBatchServer(t, nil)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	require.NoError(t, controller.EnableStateBatching(StateBatchOptions{Window: time.Hour, MaxBatch: 2}))
+
+	var wg sync.WaitGroup
+	for _, id := range []string{"a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, controller.SetState(id, "on"))
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, (<-requests).Changes, 2)
+}
+
+func TestStateBatcher_FlushStateBatch(t *testing.T) {
+	srv, requests := newBatchServer(t, nil)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	require.NoError(t, controller.EnableStateBatching(StateBatchOptions{Window: time.Hour}))
+
+	done := make(chan error, 1)
+	go func() { done <- controller.SetState("a", "on") }()
+	require.Eventually(t, func() bool {
+		controller.batcher.mu.Lock()
+		defer controller.batcher.mu.Unlock()
+		return len(controller.batcher.order) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, controller.FlushStateBatch(context.Background()))
+	assert.NoError(t, <-done)
+	assert.Equal(t, "a", (<-requests).Changes[0].ObjectID)
+}
+
+func TestStateBatcher_leavesOutUnchangedFields(t *testing.T) {
+	bodies := make(chan map[string][]map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string][]map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies <- body
+		json.NewEncoder(w).Encode([]ChangeStateBatchResponse{{ID: "sensor-1", Changed: true}, {ID: "sensor-2", Error: "object not found"}})
+	}))
+	t.Cleanup(srv.Close)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	require.NoError(t, controller.EnableStateBatching(StateBatchOptions{Window: time.Hour}))
+
+	errs := make(chan error, 2)
+	go func() { errs <- controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "12"}) }()
+	go func() { errs <- controller.SetState("sensor-2", "on") }()
+	require.Eventually(t, func() bool {
+		controller.batcher.mu.Lock()
+		defer controller.batcher.mu.Unlock()
+		return len(controller.batcher.order) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	assert.ErrorContains(t, controller.FlushStateBatch(context.Background()), "object not found")
+	<-errs
+	<-errs
+	changes := (<-bodies)["changes"]
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		switch change["object_id"] {
+		case "sensor-1":
+			assert.NotContains(t, change, "state", "an attributes-only change must not clear the state")
+			assert.Equal(t, map[string]any{"value": "12"}, change["state_additional_properties"])
+		case "sensor-2":
+			assert.NotContains(t, change, "state_additional_properties", "a state-only change must not clear the attributes")
+			assert.Equal(t, "on", change["state"])
+		}
+	}
+}
+
+func TestStateBatcher_sendsEmptyState(t *testing.T) {
+	bodies := make(chan map[string][]map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string][]map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies <- body
+		json.NewEncoder(w).Encode([]ChangeStateBatchResponse{{ID: "sensor-1", Changed: true}})
+	}))
+	t.Cleanup(srv.Close)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	require.NoError(t, controller.EnableStateBatching(StateBatchOptions{Window: time.Millisecond}))
+
+	// As without batching, an empty state is sent rather than dropped.
+	require.NoError(t, controller.SetState("sensor-1", ""))
+	changes := (<-bodies)["changes"]
+	require.Len(t, changes, 1)
+	assert.Contains(t, changes[0], "state")
+	assert.Equal(t, "", changes[0]["state"])
+}
@@ -0,0 +1,191 @@
+package objects
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StateCacheController is implemented by controllers that remember the last
+// state and attributes they pushed for each object. The controller returned
+// by NewObjectController implements it. With state suppression enabled, the
+// runner seeds the cache from GetState when an object is registered;
+// otherwise the cache only knows the updates the hub accepted.
+type StateCacheController interface {
+	// CachedState returns the last known state of an object without asking
+	// the hub. ok is false when nothing is known about it yet.
+	CachedState(objectID string) (state State, ok bool)
+	// EnableStateSuppression makes SetState and UpdateStateAttributes skip
+	// updates identical to the cached ones, unless heartbeat has passed
+	// since the last update was sent. A zero heartbeat never resends an
+	// identical update.
+	EnableStateSuppression(heartbeat time.Duration)
+}
+
+type cachedState struct {
+	state  State
+	sentAt time.Time
+}
+
+// stateCache is the last state pushed to, or read from, the hub per object.
+type stateCache struct {
+	mu        sync.Mutex
+	entries   map[string]*cachedState
+	suppress  bool
+	heartbeat time.Duration
+}
+
+func (c *stateCache) get(objectID string) (State, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[objectID]
+	if !ok {
+		return State{}, false
+	}
+	return State{State: entry.state.State, StateAdditionalProperties: copyAttributes(entry.state.StateAdditionalProperties)}, true
+}
+
+// redundant reports whether the update would not change what the hub already
+// has, and may therefore be skipped.
+func (c *stateCache) redundant(objectID, state string, attributes map[string]string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.suppress {
+		return false
+	}
+	entry, ok := c.entries[objectID]
+	if !ok {
+		return false
+	}
+	if c.heartbeat > 0 && time.Since(entry.sentAt) >= c.heartbeat {
+		return false
+	}
+	if state != "" && state != entry.state.State {
+		return false
+	}
+	for k, v := range attributes {
+		if current, ok := entry.state.StateAdditionalProperties[k]; !ok || current != v {
+			return false
+		}
+	}
+	return true
+}
+
+// store records an update the hub accepted.
+func (c *stateCache) store(objectID, state string, attributes map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedState)
+	}
+	entry, ok := c.entries[objectID]
+	if !ok {
+		entry = &cachedState{}
+		c.entries[objectID] = entry
+	}
+	if state != "" {
+		entry.state.State = state
+	}
+	if len(attributes) > 0 && entry.state.StateAdditionalProperties == nil {
+		entry.state.StateAdditionalProperties = make(map[string]string, len(attributes))
+	}
+	for k, v := range attributes {
+		entry.state.StateAdditionalProperties[k] = v
+	}
+	entry.sentAt = time.Now()
+}
+
+// seed records the state read from the hub, unless an update was pushed in
+// the meantime.
+func (c *stateCache) seed(objectID string, state State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[objectID]; ok {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedState)
+	}
+	c.entries[objectID] = &cachedState{
+		state:  State{State: state.State, StateAdditionalProperties: copyAttributes(state.StateAdditionalProperties)},
+		sentAt: time.Now(),
+	}
+}
+
+// forget drops an object whose state changed on the hub side, e.g. through
+// Increment.
+func (c *stateCache) forget(objectID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, objectID)
+}
+
+func copyAttributes(attributes map[string]string) map[string]string {
+	if attributes == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		copied[k] = v
+	}
+	return copied
+}
+
+// cacheState records an update the hub applied. An update only queued in the
+// outbox is not known to the hub yet, and a disabled object ignores it, so the
+// object is forgotten instead: comparing later updates with it could skip one
+// the hub still needs.
+func (o *objectController) cacheState(objectID, state string, attributes map[string]string, applied bool) {
+	if !applied {
+		o.states.forget(objectID)
+		return
+	}
+	o.states.store(objectID, state, attributes)
+}
+
+// CachedState implements StateCacheController.
+func (o *objectController) CachedState(objectID string) (State, bool) {
+	return o.states.get(objectID)
+}
+
+// EnableStateSuppression implements StateCacheController.
+func (o *objectController) EnableStateSuppression(heartbeat time.Duration) {
+	o.states.mu.Lock()
+	defer o.states.mu.Unlock()
+	o.states.suppress = true
+	o.states.heartbeat = heartbeat
+}
+
+// CachedState returns the last known state of an object from oc's cache, for
+// handlers that receive a controller. ok is false when oc keeps no cache or
+// knows nothing about the object yet.
+func CachedState(oc ObjectController, objectID string) (state State, ok bool) {
+	cache, isCache := UnwrapController(oc).(StateCacheController)
+	if !isCache {
+		return State{}, false
+	}
+	return cache.CachedState(objectID)
+}
+
+// stateCacheSeeder is implemented by controllers whose state cache the runner
+// seeds on registration.
+type stateCacheSeeder interface {
+	seedStateCache(ctx context.Context, objectID string)
+}
+
+// seedStateCache reads the current state of a newly registered object from
+// the hub, when state suppression is enabled. Failures are ignored: the cache
+// then fills on the first update.
+func (o *objectController) seedStateCache(ctx context.Context, objectID string) {
+	o.states.mu.Lock()
+	suppress := o.states.suppress
+	o.states.mu.Unlock()
+	if !suppress {
+		return
+	}
+	record, err := o.GetStateContext(ctx, objectID)
+	if err != nil || record.ObjectID == "" {
+		return
+	}
+	o.states.seed(objectID, record.State)
+}
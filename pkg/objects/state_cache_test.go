@@ -0,0 +1,182 @@
+package objects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/outbox"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatesServer counts the state updates it receives and answers GET with
+// the given state.
+func newStatesServer(t *testing.T, current string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var puts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"items":[{"object_id":"sensor-1","state":{"state":"` + current + `","state_additional_properties":{"value":"10"}}}]}`))
+			return
+		}
+		if r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/objects/states/") {
+			puts.Add(1)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &puts
+}
+
+func TestStateCache_suppressesIdenticalUpdates(t *testing.T) {
+	srv, puts := newStatesServer(t, "")
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.EnableStateSuppression(0)
+
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	require.NoError(t, controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "1"}))
+	require.NoError(t, controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "1"}))
+	require.NoError(t, controller.SetState("sensor-1", "off"))
+	assert.Equal(t, int32(3), puts.Load())
+
+	state, ok := controller.CachedState("sensor-1")
+	require.True(t, ok)
+	assert.Equal(t, State{State: "off", StateAdditionalProperties: map[string]string{"value": "1"}}, state)
+}
+
+func TestStateCache_heartbeatResendsIdenticalUpdates(t *testing.T) {
+	srv, puts := newStatesServer(t, "")
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.EnableStateSuppression(20 * time.Millisecond)
+
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	assert.Equal(t, int32(2), puts.Load())
+}
+
+func TestStateCache_withoutSuppressionEveryUpdateIsSent(t *testing.T) {
+	srv, puts := newStatesServer(t, "")
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	assert.Equal(t, int32(2), puts.Load())
+}
+
+func TestStateCache_seededOnRegistration(t *testing.T) {
+	srv, puts := newStatesServer(t, "alarm")
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.EnableStateSuppression(0)
+
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+	require.NoError(t, runner.RegisterObject(NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "sensor-1", Domain: "test.cache"},
+	})))
+
+	state, ok := CachedState(withExecutionContext(context.Background(), "exec-1", controller), "sensor-1")
+	require.True(t, ok)
+	assert.Equal(t, "alarm", state.State)
+
+	require.NoError(t, controller.SetState("sensor-1", "alarm"))
+	assert.Zero(t, puts.Load(), "the state read at registration must suppress the identical update")
+
+	_, ok = CachedState(newMockMicController(""), "sensor-1")
+	assert.False(t, ok)
+}
+
+func TestStateCache_notSeededWithoutSuppression(t *testing.T) {
+	var gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+
+	runner := NewObjectRunner(controller)
+	defer runner.Shutdown(context.Background())
+	require.NoError(t, runner.RegisterObject(NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "sensor-1", Domain: "test.cache.unseeded"},
+	})))
+	assert.Zero(t, gets.Load(), "registration must not read the state")
+}
+
+func TestStateCache_forgetsQueuedUpdates(t *testing.T) {
+	var down atomic.Bool
+	var puts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		puts.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+	ob, err := outbox.Open(outbox.Options{})
+	require.NoError(t, err)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.SetOutbox(ob)
+	controller.EnableStateSuppression(0)
+
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	down.Store(true)
+	require.NoError(t, controller.SetState("sensor-1", "off"))
+	_, ok := controller.CachedState("sensor-1")
+	assert.False(t, ok, "a queued update is not known to the hub")
+
+	down.Store(false)
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	assert.Equal(t, 2, ob.Len(), "the update after the queued one must not be suppressed")
+	_, err = ob.Replay(context.Background(), controller.ReplayOutboxEntry)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), puts.Load())
+}
+
+func TestStateCache_skipsUpdatesTheHubDidNotApply(t *testing.T) {
+	var status atomic.Int32
+	var body atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte(body.Load().(string)))
+	}))
+	t.Cleanup(srv.Close)
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.EnableStateSuppression(0)
+
+	status.Store(http.StatusInternalServerError)
+	body.Store("database is down")
+	assert.ErrorContains(t, controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "1"}), "database is down")
+	_, ok := controller.CachedState("sensor-1")
+	assert.False(t, ok, "refused attributes must not be cached")
+
+	status.Store(http.StatusBadRequest)
+	body.Store("object is disabled")
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	require.NoError(t, controller.UpdateStateAttributes("sensor-1", map[string]string{"value": "1"}))
+	_, ok = controller.CachedState("sensor-1")
+	assert.False(t, ok, "a disabled object ignores the update")
+}
+
+func TestStateCache_skipsBatchedUpdatesOfDisabledObjects(t *testing.T) {
+	srv, _ := newBatchServer(t, map[string]string{"sensor-2": "object is disabled"})
+	controller := &objectController{driverhub_host: srv.URL, httpClient: resty.New()}
+	controller.EnableStateSuppression(0)
+	require.NoError(t, controller.EnableStateBatching(StateBatchOptions{Window: time.Millisecond}))
+
+	require.NoError(t, controller.SetState("sensor-1", "on"))
+	require.NoError(t, controller.SetState("sensor-2", "on"))
+	_, ok := controller.CachedState("sensor-1")
+	assert.True(t, ok)
+	_, ok = controller.CachedState("sensor-2")
+	assert.False(t, ok, "a disabled object ignores the update")
+}
@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 const SWITCH_STATE_OFF = "switch.state.off"
 const SWITCH_STATE_ON = "switch.state.on"
 
@@ -21,6 +23,7 @@ type switchObject struct {
 	metadata      ObjectMetadata
 	switchActions SwitchActions
 	controller    ObjectController
+	eventTypes    []EventType
 }
 
 // UpdateStateAttributes implements SwitchObject.
@@ -35,8 +38,19 @@ func (s *switchObject) SetState(state string) error {
 }
 
 // AddEventTypes implements SwitchObject.
+// Event types added before Setup are registered once the controller is set.
 func (s *switchObject) AddEventTypes(eventTypes []EventType) error {
-	panic("unimplemented")
+	if s.controller == nil {
+		s.eventTypes = append(s.eventTypes, eventTypes...)
+		return nil
+	}
+	for i := range eventTypes {
+		e := eventTypes[i]
+		e.Domain = s.metadata.Domain
+		e.Origin = "driver"
+		eventTypes[i] = e
+	}
+	return s.controller.AddEventTypes(eventTypes)
 }
 
 // TurnOff implements SwitchObject.
@@ -57,13 +71,18 @@ func (s *switchObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements RegistrableObject.
 func (s *switchObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return s.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (s *switchObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 	switch action {
 	case SWITCH_ACTION_TURN_ON:
-		return nil, s.switchActions.TurnOn(s, s.controller)
+		return nil, s.switchActions.TurnOn(s, withExecutionContext(ctx, id, s.controller))
 	case SWITCH_ACTION_TURN_OFF:
-		return nil, s.switchActions.TurnOff(s, s.controller)
+		return nil, s.switchActions.TurnOff(s, withExecutionContext(ctx, id, s.controller))
 	}
-	return s.dispatchCustom(s, s.controller, id, action, payload)
+	return s.dispatchCustom(ctx, s, s.controller, id, action, payload)
 
 }
 
@@ -90,6 +109,12 @@ func (s *switchObject) GetAvailableStates() []string {
 // New implements RegistrableObject.
 func (s *switchObject) Setup(oc ObjectController) error {
 	s.controller = oc
+	if pending := s.eventTypes; len(pending) > 0 {
+		s.eventTypes = nil
+		if err := s.AddEventTypes(pending); err != nil {
+			return err
+		}
+	}
 	if s.switchActions.Setup == nil {
 		return nil
 	}
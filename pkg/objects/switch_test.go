@@ -1 +1,33 @@
 package objects
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTypesRecorder records the event types registered through it.
+type eventTypesRecorder struct {
+	*mockMicController
+	eventTypes []EventType
+}
+
+func (r *eventTypesRecorder) AddEventTypes(et []EventType) error {
+	r.eventTypes = append(r.eventTypes, et...)
+	return nil
+}
+
+func TestSwitchObject_AddEventTypes_beforeSetup(t *testing.T) {
+	sw := NewSwitchObject(NewSwitchObjectParams{
+		Metadata: ObjectMetadata{ObjectID: "relay.1", Domain: "relay"},
+	})
+	require.NoError(t, sw.(*switchObject).AddEventTypes([]EventType{{EventType: "relay.event.opened"}}))
+
+	controller := &eventTypesRecorder{mockMicController: newMockMicController("")}
+	require.NoError(t, sw.Setup(controller))
+
+	require.Len(t, controller.eventTypes, 1)
+	assert.Equal(t, "relay", controller.eventTypes[0].Domain)
+	assert.Equal(t, "driver", controller.eventTypes[0].Origin)
+}
@@ -1,6 +1,7 @@
 package objects
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -44,7 +45,8 @@ type PTZCommand string
 
 // DownloadVideoClipActionPayload is the payload DriversHub sends when triggering
 // VIDEO_CHANNEL_ACTION_DOWNLOAD_VIDEO_CLIP. Times are RFC3339 strings.
-// Timeout is the maximum seconds the driver should spend fetching; 0 means no timeout.
+// Timeout is the maximum seconds the driver may spend fetching; the runner
+// cancels ExecutionContext(controller) once it elapses. 0 means no timeout.
 type DownloadVideoClipActionPayload struct {
 	ObjectID   string `json:"object_id"`
 	ChannelIdx int    `json:"channel_idx"`
@@ -114,7 +116,7 @@ type VideoClipActionPayload struct {
 	StartTimestamp string `json:"start_timestamp"`
 	EndTimestamp   string `json:"end_timestamp"`
 	Resolution     string `json:"resolution"` //"1920x1080"
-	Timeout        int    `json:"timeout"`    // This is to stop trying to make the video clip after certain seconds
+	Timeout        int    `json:"timeout"`    // seconds; the runner cancels ExecutionContext(controller) once it elapses
 }
 
 type SnapshotActionPayload struct {
@@ -259,6 +261,7 @@ type videoChannelObject struct {
 	setupFn    func(VideoChannelObject, ObjectController) error
 	controller ObjectController
 	metadata   ObjectMetadata
+	eventTypes []EventType
 
 	streamId      string
 	subStreamId   string
@@ -302,8 +305,19 @@ func (v *videoChannelObject) SetState(state string) error {
 }
 
 // AddEventTypes implements VideoChannelObject.
+// Event types added before Setup are registered once the controller is set.
 func (v *videoChannelObject) AddEventTypes(eventTypes []EventType) error {
-	panic("unimplemented")
+	if v.controller == nil {
+		v.eventTypes = append(v.eventTypes, eventTypes...)
+		return nil
+	}
+	for i := range eventTypes {
+		e := eventTypes[i]
+		e.Domain = v.metadata.Domain
+		e.Origin = "driver"
+		eventTypes[i] = e
+	}
+	return v.controller.AddEventTypes(eventTypes)
 }
 
 // SetModeIdle implements VideoChannelObject.
@@ -372,6 +386,11 @@ func (v *videoChannelObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements VideoChannelObject.
 func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return v.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (v *videoChannelObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 
 	switch action {
 	case VIDEO_CHANNEL_ACTION_SNAPSHOT:
@@ -382,7 +401,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		r, err := v.snapshotFn(v, v.controller, p)
+		r, err := v.snapshotFn(v, withExecutionContext(ctx, id, v.controller), p)
 		if err != nil {
 			return nil, err
 		}
@@ -396,7 +415,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		r, err := v.videoclipFn(v, v.controller, p)
+		r, err := v.videoclipFn(v, withExecutionContext(ctx, id, v.controller), p)
 		if err != nil {
 			return nil, err
 		}
@@ -410,7 +429,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		return nil, v.ptzFn(v, v.controller, p)
+		return nil, v.ptzFn(v, withExecutionContext(ctx, id, v.controller), p)
 
 	case VIDEO_CHANNEL_ACTION_PTZ_GOTO_PRESET:
 		if v.gotoPresetFn == nil {
@@ -420,7 +439,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		return nil, v.gotoPresetFn(v, v.controller, p)
+		return nil, v.gotoPresetFn(v, withExecutionContext(ctx, id, v.controller), p)
 
 	case VIDEO_CHANNEL_ACTION_SEEK:
 		if v.seekFn == nil {
@@ -430,7 +449,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		result, err := v.seekFn(v, v.controller, p)
+		result, err := v.seekFn(v, withExecutionContext(ctx, id, v.controller), p)
 		return map[string]string{"error": strconv.FormatBool(result.Error), "message": result.Message}, err
 
 	case VIDEO_CHANNEL_ACTION_REQUEST_DOLYNK_STREAM_URL:
@@ -441,7 +460,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		response, err := v.requestDolynkStreamURLFn(v, v.controller, p)
+		response, err := v.requestDolynkStreamURLFn(v, withExecutionContext(ctx, id, v.controller), p)
 		if err != nil {
 			return nil, err
 		}
@@ -464,11 +483,14 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		response, err := v.requestDahuaPlaybackMediaFilesFn(v, v.controller, p)
+		response, err := v.requestDahuaPlaybackMediaFilesFn(v, withExecutionContext(ctx, id, v.controller), p)
 		if err != nil {
 			return nil, err
 		}
 
+		// The runner reports the typed list; RunAction callers keep the
+		// index-keyed form.
+		setResultData(ctx, map[string]any{"media_files": response.MediaFiles})
 		mapJson := map[string]string{}
 		for i, mediaFile := range response.MediaFiles {
 			mediaFileJson, err := json.Marshal(mediaFile)
@@ -487,7 +509,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		response, err := v.getRecordingSegmentsFn(v, v.controller, p)
+		response, err := v.getRecordingSegmentsFn(v, withExecutionContext(ctx, id, v.controller), p)
 		if err != nil {
 			return nil, err
 		}
@@ -501,7 +523,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if v.getPtzStatusFn == nil {
 			return nil, fmt.Errorf("action %s not supported by this object", action)
 		}
-		response, err := v.getPtzStatusFn(v, v.controller)
+		response, err := v.getPtzStatusFn(v, withExecutionContext(ctx, id, v.controller))
 		if err != nil {
 			return nil, err
 		}
@@ -523,7 +545,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		return nil, v.publishStreamStartFn(v, v.controller, p)
+		return nil, v.publishStreamStartFn(v, withExecutionContext(ctx, id, v.controller), p)
 
 	case VIDEO_CHANNEL_ACTION_PUBLISH_STREAM_STOP:
 		if v.publishStreamStopFn == nil {
@@ -533,7 +555,7 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, err
 		}
-		return nil, v.publishStreamStopFn(v, v.controller, p)
+		return nil, v.publishStreamStopFn(v, withExecutionContext(ctx, id, v.controller), p)
 
 	case VIDEO_CHANNEL_ACTION_DOWNLOAD_VIDEO_CLIP:
 		if v.downloadVideoClipFn == nil {
@@ -543,19 +565,25 @@ func (v *videoChannelObject) RunAction(id, action string, payload []byte) (map[s
 		if err := json.Unmarshal(payload, &p); err != nil {
 			return nil, fmt.Errorf("unmarshal download payload: %w", err)
 		}
-		if err := v.downloadVideoClipFn(v, v.controller, p); err != nil {
+		if err := v.downloadVideoClipFn(v, withExecutionContext(ctx, id, v.controller), p); err != nil {
 			return nil, err
 		}
 		return map[string]string{"status": "complete", "job_id": p.JobID}, nil
 	}
 
-	return v.dispatchCustom(v, v.controller, id, action, payload)
+	return v.dispatchCustom(ctx, v, v.controller, id, action, payload)
 
 }
 
 // Setup implements VideoChannelObject.
 func (v *videoChannelObject) Setup(oc ObjectController) error {
 	v.controller = oc
+	if pending := v.eventTypes; len(pending) > 0 {
+		v.eventTypes = nil
+		if err := v.AddEventTypes(pending); err != nil {
+			return err
+		}
+	}
 
 	v.UpdateStateAttributes(map[string]string{
 		"video_engine_id": v.videoEngineId,
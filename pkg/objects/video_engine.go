@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 type VideoEngineObject interface {
 	RegistrableObject
 	CustomActionRegistrar
@@ -42,8 +44,13 @@ func (v *videoEngineObject) GetMetadata() ObjectMetadata {
 
 // RunAction implements VideoEngineObject.
 func (v *videoEngineObject) RunAction(id, action string, payload []byte) (map[string]string, error) {
+	return v.RunActionContext(context.Background(), id, action, payload)
+}
+
+// RunActionContext implements ContextActionRunner.
+func (v *videoEngineObject) RunActionContext(ctx context.Context, id, action string, payload []byte) (map[string]string, error) {
 
-	return v.dispatchCustom(v, v.controller, id, action, payload)
+	return v.dispatchCustom(ctx, v, v.controller, id, action, payload)
 }
 
 // Setup implements VideoEngineObject.
@@ -0,0 +1,367 @@
+// Package outbox buffers writes to the DriverHub while it is unreachable and
+// replays them, in order, once it is reachable again.
+//
+// State changes and events produced by the field during a hub outage (a
+// restart, a network cut between the site and the hub) used to be lost: the
+// SDK returned an error and the driver had nowhere to keep them. With an
+// outbox enabled (see NetsocsDriverClient.EnableOutbox), those writes are
+// appended to a local log instead and replayed by Run. Entries are delivered
+// at least once: a crash during a replay may send the last entries again.
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/goccy/go-json"
+)
+
+// DropPolicy decides what happens when an append would exceed the limits.
+type DropPolicy string
+
+const (
+	// DropOldest discards the oldest queued entries to make room. It is the
+	// default: the latest state of the field is usually what matters.
+	DropOldest DropPolicy = "drop_oldest"
+	// DropNewest rejects the new entry with ErrFull, keeping the history that
+	// is already queued.
+	DropNewest DropPolicy = "drop_newest"
+)
+
+// DefaultMaxEntries is the MaxEntries used when Options leaves it unset.
+const DefaultMaxEntries = 10000
+
+// ErrFull is returned by Append under DropNewest when the outbox is full.
+var ErrFull = errors.New("outbox is full")
+
+// ErrQueued is returned by writes that were queued instead of sent, and whose
+// result the hub has yet to give, such as the ID of a dispatched event. It is
+// not a failure: the write is delivered once the hub is reachable again.
+var ErrQueued = errors.New("queued in the outbox")
+
+// Options configures an Outbox.
+type Options struct {
+	// Path is the file the queue is persisted to, so it survives a driver
+	// restart. Empty keeps the queue in memory only.
+	Path string
+	// MaxEntries caps the number of queued entries; 0 means DefaultMaxEntries.
+	MaxEntries int
+	// MaxBytes caps the total size of the queued bodies; 0 means no limit.
+	MaxBytes int64
+	// DropPolicy applies when a limit is reached; empty means DropOldest.
+	DropPolicy DropPolicy
+	// Backoff paces the replay attempts while the hub stays unreachable; the
+	// zero value means httpx.DefaultBackoff().
+	Backoff httpx.Backoff
+}
+
+// Entry is one queued write. Path is relative to the DriverHub host, e.g.
+// "/objects/states/door-1".
+type Entry struct {
+	Seq      uint64          `json:"seq"`
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Body     json.RawMessage `json:"body"`
+	QueuedAt time.Time       `json:"queued_at"`
+	// IdempotencyKey, when set, is sent with the replayed write so the hub
+	// applies it once even if the original attempt reached it.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// Sender delivers one entry to the hub. It returns an error only when the
+// entry should be retried later; entries the hub rejects for good must be
+// reported as delivered, or they would block the queue forever.
+type Sender func(ctx context.Context, entry Entry) error
+
+// Outbox is a bounded FIFO of writes, optionally persisted to a file. It is
+// safe for concurrent use.
+type Outbox struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries []Entry
+	size    int64
+	nextSeq uint64
+	dropped uint64
+	file    *os.File
+
+	replayMu sync.Mutex
+	kick     chan struct{}
+}
+
+// Open creates an outbox. When opts.Path names an existing file, the entries
+// it holds are loaded and will be replayed first.
+func Open(opts Options) (*Outbox, error) {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+	switch opts.DropPolicy {
+	case "":
+		opts.DropPolicy = DropOldest
+	case DropOldest, DropNewest:
+	default:
+		return nil, fmt.Errorf("unknown outbox drop policy %q", opts.DropPolicy)
+	}
+	if opts.Backoff == (httpx.Backoff{}) {
+		opts.Backoff = httpx.DefaultBackoff()
+	}
+
+	o := &Outbox{opts: opts, nextSeq: 1, kick: make(chan struct{}, 1)}
+	if opts.Path == "" {
+		return o, nil
+	}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	o.file = file
+	return o, nil
+}
+
+// load reads the entries persisted by a previous run. A truncated last line,
+// left by a crash in the middle of a write, is ignored.
+func (o *Outbox) load() error {
+	file, err := os.Open(o.opts.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		o.entries = append(o.entries, entry)
+		o.size += int64(len(entry.Body))
+		if entry.Seq >= o.nextSeq {
+			o.nextSeq = entry.Seq + 1
+		}
+	}
+	return scanner.Err()
+}
+
+// Append queues a write. body is encoded as JSON. With DropOldest the oldest
+// entries are discarded when a limit is reached; with DropNewest ErrFull is
+// returned instead.
+func (o *Outbox) Append(method, path string, body any) error {
+	return o.AppendIdempotent(method, path, body, "")
+}
+
+// AppendIdempotent is Append for a write that carries an idempotency key,
+// such as an event dispatch; the key is stored with the entry.
+func (o *Outbox) AppendIdempotent(method, path string, body any, idempotencyKey string) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry := Entry{Seq: o.nextSeq, Method: method, Path: path, Body: raw, QueuedAt: time.Now(), IdempotencyKey: idempotencyKey}
+	if o.opts.MaxBytes > 0 && int64(len(raw)) > o.opts.MaxBytes {
+		return ErrFull
+	}
+	compact := false
+	for o.full(int64(len(raw))) {
+		if o.opts.DropPolicy == DropNewest {
+			return ErrFull
+		}
+		o.size -= int64(len(o.entries[0].Body))
+		o.entries = o.entries[1:]
+		o.dropped++
+		compact = true
+	}
+	o.nextSeq++
+	o.entries = append(o.entries, entry)
+	o.size += int64(len(raw))
+
+	if compact {
+		err = o.rewrite()
+	} else {
+		err = o.persist(entry)
+	}
+	o.signal()
+	return err
+}
+
+func (o *Outbox) full(incoming int64) bool {
+	if len(o.entries) == 0 {
+		return false
+	}
+	if len(o.entries) >= o.opts.MaxEntries {
+		return true
+	}
+	return o.opts.MaxBytes > 0 && o.size+incoming > o.opts.MaxBytes
+}
+
+func (o *Outbox) persist(entry Entry) error {
+	if o.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := o.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return o.file.Sync()
+}
+
+// rewrite replaces the file with the entries still queued.
+func (o *Outbox) rewrite() error {
+	if o.file == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(o.opts.Path), filepath.Base(o.opts.Path)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, entry := range o.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), o.opts.Path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	o.file.Close()
+	o.file, err = os.OpenFile(o.opts.Path, os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+func (o *Outbox) signal() {
+	select {
+	case o.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of queued entries.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// Dropped returns how many entries the DropOldest policy discarded.
+func (o *Outbox) Dropped() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.dropped
+}
+
+// Kick asks Run to attempt a replay now, e.g. right after the hub websocket
+// reconnects, instead of waiting for the next backoff delay.
+func (o *Outbox) Kick() {
+	o.signal()
+}
+
+// Replay sends the queued entries in order until the queue is empty or send
+// fails. It returns how many entries were delivered and the send error.
+func (o *Outbox) Replay(ctx context.Context, send Sender) (int, error) {
+	o.replayMu.Lock()
+	defer o.replayMu.Unlock()
+
+	delivered := 0
+	defer func() {
+		if delivered > 0 {
+			o.mu.Lock()
+			o.rewrite()
+			o.mu.Unlock()
+		}
+	}()
+	for {
+		o.mu.Lock()
+		if len(o.entries) == 0 {
+			o.mu.Unlock()
+			return delivered, nil
+		}
+		entry := o.entries[0]
+		o.mu.Unlock()
+
+		if err := send(ctx, entry); err != nil {
+			return delivered, err
+		}
+
+		o.mu.Lock()
+		// DropOldest may have discarded the entry while it was being sent.
+		if len(o.entries) > 0 && o.entries[0].Seq == entry.Seq {
+			o.size -= int64(len(entry.Body))
+			o.entries = o.entries[1:]
+		}
+		o.mu.Unlock()
+		delivered++
+	}
+}
+
+// Run replays the queue every time entries are appended or Kick is called,
+// retrying with the configured backoff while send fails. It returns when ctx
+// is done.
+func (o *Outbox) Run(ctx context.Context, send Sender) {
+	attempt := 0
+	var retry <-chan time.Time
+	if o.Len() > 0 {
+		o.signal()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.kick:
+		case <-retry:
+		}
+		retry = nil
+		if _, err := o.Replay(ctx, send); err != nil && ctx.Err() == nil {
+			retry = time.After(o.opts.Backoff.Delay(attempt))
+			attempt++
+			continue
+		}
+		attempt = 0
+	}
+}
+
+// Close releases the file. Queued entries stay in it for the next Open.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Netsocs-Team/driver.sdk_go/pkg/httpx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(delivered *[]string) Sender {
+	return func(ctx context.Context, entry Entry) error {
+		*delivered = append(*delivered, entry.Path)
+		return nil
+	}
+}
+
+func TestOutbox_replaysInOrder(t *testing.T) {
+	ob, err := Open(Options{})
+	require.NoError(t, err)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		require.NoError(t, ob.Append("PUT", path, map[string]string{"state": "on"}))
+	}
+
+	var delivered []string
+	n, err := ob.Replay(context.Background(), collect(&delivered))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"/a", "/b", "/c"}, delivered)
+	assert.Zero(t, ob.Len())
+}
+
+func TestOutbox_stopsAtFirstFailure(t *testing.T) {
+	ob, err := Open(Options{})
+	require.NoError(t, err)
+	require.NoError(t, ob.Append("PUT", "/a", nil))
+	require.NoError(t, ob.Append("PUT", "/b", nil))
+
+	down := errors.New("connection refused")
+	n, err := ob.Replay(context.Background(), func(context.Context, Entry) error { return down })
+	assert.ErrorIs(t, err, down)
+	assert.Zero(t, n)
+	assert.Equal(t, 2, ob.Len())
+}
+
+func TestOutbox_survivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	ob, err := Open(Options{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, ob.Append("PUT", "/a", nil))
+	require.NoError(t, ob.Append("POST", "/b", map[string]string{"timestamp": "2026-01-02T03:04:05Z"}))
+	require.NoError(t, ob.Close())
+
+	reopened, err := Open(Options{Path: path})
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.Equal(t, 2, reopened.Len())
+
+	var entries []Entry
+	_, err = reopened.Replay(context.Background(), func(_ context.Context, entry Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "POST", entries[1].Method)
+	assert.JSONEq(t, `{"timestamp":"2026-01-02T03:04:05Z"}`, string(entries[1].Body))
+
+	// Delivered entries are gone from the file, and sequence numbers go on.
+	require.NoError(t, reopened.Append("PUT", "/c", nil))
+	require.NoError(t, reopened.Close())
+	again, err := Open(Options{Path: path})
+	require.NoError(t, err)
+	defer again.Close()
+	var delivered []string
+	_, err = again.Replay(context.Background(), collect(&delivered))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/c"}, delivered)
+}
+
+func TestOutbox_dropPolicies(t *testing.T) {
+	oldest, err := Open(Options{MaxEntries: 2})
+	require.NoError(t, err)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		require.NoError(t, oldest.Append("PUT", path, nil))
+	}
+	var delivered []string
+	_, err = oldest.Replay(context.Background(), collect(&delivered))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/b", "/c"}, delivered)
+	assert.Equal(t, uint64(1), oldest.Dropped())
+
+	newest, err := Open(Options{MaxEntries: 2, DropPolicy: DropNewest})
+	require.NoError(t, err)
+	require.NoError(t, newest.Append("PUT", "/a", nil))
+	require.NoError(t, newest.Append("PUT", "/b", nil))
+	assert.ErrorIs(t, newest.Append("PUT", "/c", nil), ErrFull)
+
+	bytes, err := Open(Options{MaxBytes: 20, DropPolicy: DropNewest})
+	require.NoError(t, err)
+	require.NoError(t, bytes.Append("PUT", "/a", "0123456789"))
+	assert.ErrorIs(t, bytes.Append("PUT", "/b", "0123456789"), ErrFull)
+
+	_, err = Open(Options{DropPolicy: "random"})
+	assert.Error(t, err)
+}
+
+func TestOutbox_RunRetriesUntilDelivered(t *testing.T) {
+	ob, err := Open(Options{Backoff: httpx.Backoff{Initial: 5 * time.Millisecond, Multiplier: 1}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	attempts := 0
+	delivered := make(chan string, 1)
+	go ob.Run(ctx, func(_ context.Context, entry Entry) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("hub down")
+		}
+		delivered <- entry.Path
+		return nil
+	})
+
+	require.NoError(t, ob.Append("PUT", "/a", nil))
+	select {
+	case path := <-delivered:
+		assert.Equal(t, "/a", path)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the entry was not replayed")
+	}
+}
@@ -17,6 +17,15 @@ type FileSchema struct {
 	SiteHost             string `json:"site_host"`
 	Token                string `json:"token"`
 	DriverID             string `json:"driver_id"`
+	LogLevel             string `json:"log_level"`
+
+	// TLS trust of the DriverHub; see httpx.TLSOptions. The NETSOCS_TLS_*
+	// environment variables override them.
+	TLSCAFile     string   `json:"tls_ca_file"`
+	TLSCertFile   string   `json:"tls_cert_file"`
+	TLSKeyFile    string   `json:"tls_key_file"`
+	TLSPinnedSPKI []string `json:"tls_pinned_spki"`
+	TLSStrict     bool     `json:"tls_strict"`
 }
 
 func GetDriverNetsocsDotJsonContent(path string) (*FileSchema, error) {
@@ -50,6 +50,7 @@ func UploadFileAndGetURL(driverHubHost string, driverKey string, file *os.File)
 	// Add authentication headers
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", driverKey)
+	req.Header.Set(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey())
 
 	// Make the request
 	client := httpx.Client()
@@ -143,6 +144,7 @@ func UploadFileAndGetURLWithReset(driverHubHost string, driverKey string, file *
 	// Add authentication headers
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", driverKey)
+	req.Header.Set(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey())
 
 	// Make the request
 	client := httpx.Client()
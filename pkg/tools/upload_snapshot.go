@@ -65,6 +65,7 @@ func UploadSnapshot(driverHubHost, driverKey string, r io.Reader, filename strin
 	}
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", driverKey)
+	req.Header.Set(httpx.IdempotencyKeyHeader, httpx.NewIdempotencyKey())
 
 	client := httpx.Client()
 	res, err := client.Do(req)
@@ -0,0 +1,231 @@
+// Package tracing records spans for what the SDK does on behalf of the
+// DriverHub: one per action execution, one per config message and one per
+// outbound HTTP call. Spans follow the OpenTelemetry model (trace and span
+// IDs, parent, attributes, status) and propagate over HTTP with the W3C
+// traceparent header, so a hub that traces its requests sees the driver's
+// calls in the same trace.
+//
+// Tracing is off until an Exporter is set. Exporters receive each span when
+// it ends; MemoryExporter and WriterExporter cover tests and local debugging,
+// and any other backend can be plugged in by implementing Exporter.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter receives ended spans. ExportSpan is called on the goroutine that
+// ended the span and must not block.
+type Exporter interface {
+	ExportSpan(SpanData)
+}
+
+// ExporterFunc adapts a function to Exporter.
+type ExporterFunc func(SpanData)
+
+// ExportSpan implements Exporter.
+func (f ExporterFunc) ExportSpan(s SpanData) { f(s) }
+
+type exporterHolder struct{ Exporter }
+
+var exporter atomic.Pointer[exporterHolder]
+
+// SetExporter enables tracing, sending every ended span to e. A nil e
+// disables it. The exporter is process-wide.
+func SetExporter(e Exporter) {
+	if e == nil {
+		exporter.Store(nil)
+		return
+	}
+	exporter.Store(&exporterHolder{e})
+}
+
+// Enabled reports whether an exporter is set.
+func Enabled() bool {
+	return exporter.Load() != nil
+}
+
+// Status is the outcome of a span.
+type Status string
+
+const (
+	StatusUnset Status = "unset"
+	StatusOK    Status = "ok"
+	StatusError Status = "error"
+)
+
+// SpanData is a finished span, as handed to the exporter.
+type SpanData struct {
+	Name         string         `json:"name"`
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Status       Status         `json:"status"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// Duration returns how long the span lasted.
+func (s SpanData) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// SpanContext identifies a span within its trace.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Span is a span being recorded. A nil *Span, returned when tracing is off,
+// is valid and ignores every call.
+type Span struct {
+	mu       sync.Mutex
+	data     SpanData
+	ended    bool
+	exporter Exporter
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// Start starts a span named name, child of the span in ctx (or of the remote
+// span extracted into ctx), and returns a ctx carrying it. When tracing is
+// off it returns ctx unchanged and a nil span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	holder := exporter.Load()
+	if holder == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	data := SpanData{
+		Name:   name,
+		SpanID: newID(8),
+		Start:  time.Now(),
+		Status: StatusUnset,
+	}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		data.TraceID = parent.TraceID
+		data.ParentSpanID = parent.SpanID
+	} else {
+		data.TraceID = newID(16)
+	}
+	span := &Span{data: data, exporter: holder.Exporter}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span carried by ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the span context of the span carried by
+// ctx, or of the remote parent extracted into it.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span := FromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx whose next span
+// continues the trace of sc, typically received from another service.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContext returns the IDs of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}
+}
+
+// SetAttribute records a key/value pair on the span.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = map[string]any{}
+	}
+	s.data.Attributes[key] = value
+}
+
+// SetStatus sets the outcome of the span. A later call overrides it.
+func (s *Span) SetStatus(status Status, description string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = status
+	s.data.Error = description
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and exports it. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	s.exporter.ExportSpan(data)
+}
+
+func newID(bytes int) string {
+	b := make([]byte, bytes)
+	for {
+		for i := 0; i < bytes; i += 8 {
+			v := rand.Uint64()
+			for j := 0; j < 8 && i+j < bytes; j++ {
+				b[i+j] = byte(v >> (8 * j))
+			}
+		}
+		// All-zero IDs are invalid in W3C trace context.
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
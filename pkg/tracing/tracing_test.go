@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStart_disabled(t *testing.T) {
+	SetExporter(nil)
+	ctx, span := Start(context.Background(), "noop")
+	assert.Nil(t, span)
+	assert.Equal(t, context.Background(), ctx)
+
+	// A nil span ignores every call.
+	span.SetAttribute("k", "v")
+	span.RecordError(errors.New("boom"))
+	span.End()
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+func TestStart_childSpans(t *testing.T) {
+	exporter := NewMemoryExporter()
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	child.SetAttribute("object_id", "relay.1")
+	child.RecordError(errors.New("device offline"))
+	child.End()
+	child.End()
+	parent.SetStatus(StatusOK, "")
+	parent.End()
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, map[string]any{"object_id": "relay.1"}, spans[0].Attributes)
+	assert.Equal(t, StatusError, spans[0].Status)
+	assert.Equal(t, "device offline", spans[0].Error)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Equal(t, StatusOK, spans[1].Status)
+	assert.Len(t, spans[1].TraceID, 32)
+	assert.Len(t, spans[1].SpanID, 16)
+	assert.Same(t, parent, FromContext(ctx))
+}
+
+func TestInjectExtract(t *testing.T) {
+	exporter := NewMemoryExporter()
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	h := http.Header{}
+	Inject(context.Background(), h)
+	assert.Empty(t, h.Get(TraceparentHeader))
+
+	ctx, span := Start(context.Background(), "client")
+	Inject(ctx, h)
+	assert.Equal(t, "00-"+span.SpanContext().TraceID+"-"+span.SpanContext().SpanID+"-01", h.Get(TraceparentHeader))
+
+	_, server := Start(Extract(context.Background(), h), "server")
+	server.End()
+	assert.Equal(t, span.SpanContext().TraceID, exporter.Spans()[0].TraceID)
+	assert.Equal(t, span.SpanContext().SpanID, exporter.Spans()[0].ParentSpanID)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	assert.Equal(t, SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}, sc)
+
+	for _, bad := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00F067AA0BA902B7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+	} {
+		_, ok := ParseTraceparent(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	SetExporter(NewWriterExporter(&buf))
+	defer SetExporter(nil)
+
+	_, span := Start(context.Background(), "config.message")
+	span.SetAttribute("request_id", "r1")
+	span.End()
+
+	var data SpanData
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, "config.message", data.Name)
+	assert.Equal(t, "r1", data.Attributes["request_id"])
+	assert.Equal(t, StatusUnset, data.Status)
+}